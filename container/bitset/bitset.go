@@ -0,0 +1,425 @@
+// Package bitset defines a Set type that holds a set of small non-negative
+// integers as a bit vector.
+package bitset
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+// Integer is a constraint that permits any integer type.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// A Set is a set of non-negative integers.
+// It has the same methods as set.Set, but it is represented as a bit vector
+// in which bit i is set if i is in the set. This uses much less memory than
+// a map when the elements are small and densely packed, and operations that
+// combine two sets, such as Union and ContainsAll, work on 64 elements at a
+// time. The memory used by a Set is proportional to its largest element,
+// so it is not suitable for sets containing large values.
+//
+// Copies of a Set share its words but not its length, so once a Set has
+// held elements, changing one copy can leave another with elements it
+// should not have or without ones it should. Use Clone to copy a Set.
+// The zero value of a Set is an empty set ready to use.
+// As with maps, concurrent calls to functions and methods that read values
+// are fine; concurrent calls to functions and methods that write values are
+// racy.
+type Set[E Integer] struct {
+	// w holds the bits of the set, 64 per word, least significant first.
+	// The last word, if any, is nonzero, so that two Sets with the same
+	// elements have equal slices.
+	w []uint64
+}
+
+const wordBits = 64
+
+// Of returns a new set containing the listed elements.
+// Like Add, it panics if any element is negative.
+func Of[E Integer](v ...E) *Set[E] {
+	s := new(Set[E])
+	s.Add(v...)
+	return s
+}
+
+// String returns a human-readable representation of the set,
+// listing the elements in ascending order.
+func (s *Set[E]) String() string {
+	var b strings.Builder
+	b.WriteString("bitset[")
+	sep := ""
+	for v := range s.All() {
+		fmt.Fprint(&b, sep, v)
+		sep = " "
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// GoString returns a Go syntax representation of the set.
+func (s *Set[E]) GoString() string {
+	var vals []string
+	for v := range s.All() {
+		vals = append(vals, fmt.Sprintf("%#v", v))
+	}
+	var v E
+	return fmt.Sprintf("bitset.Of[%T](%s)", v, strings.Join(vals, ", "))
+}
+
+// Add adds elements to a set.
+// It panics if any element is negative.
+func (s *Set[E]) Add(v ...E) {
+	for _, vv := range v {
+		if vv < 0 {
+			panic(fmt.Sprintf("bitset: negative element %d", vv))
+		}
+		i := uint64(vv)
+		s.grow(i/wordBits + 1)
+		s.w[i/wordBits] |= 1 << (i % wordBits)
+	}
+}
+
+// AddRange adds the elements lo, lo+1, ..., hi-1 to a set.
+// It panics if lo is negative. If hi <= lo, AddRange does nothing.
+func (s *Set[E]) AddRange(lo, hi E) {
+	if lo < 0 {
+		panic(fmt.Sprintf("bitset: negative element %d", lo))
+	}
+	if hi <= lo {
+		return
+	}
+	i, j := uint64(lo), uint64(hi)
+	s.grow((j-1)/wordBits + 1)
+	for i < j {
+		wi := i / wordBits
+		// Set the bits from i to the end of the word or to j,
+		// whichever comes first.
+		n := min(wordBits-i%wordBits, j-i)
+		s.w[wi] |= (1<<n - 1) << (i % wordBits)
+		i += n
+	}
+}
+
+// AddSet adds the elements of set s2 to s.
+func (s *Set[E]) AddSet(s2 *Set[E]) {
+	s.grow(uint64(len(s2.w)))
+	for i, w := range s2.w {
+		s.w[i] |= w
+	}
+}
+
+// Remove removes elements from a set.
+// Elements that are not present, including negative values, are ignored.
+func (s *Set[E]) Remove(v ...E) {
+	for _, vv := range v {
+		if vv < 0 {
+			continue
+		}
+		i := uint64(vv)
+		if i/wordBits < uint64(len(s.w)) {
+			s.w[i/wordBits] &^= 1 << (i % wordBits)
+		}
+	}
+	s.trim()
+}
+
+// RemoveRange removes the elements lo, lo+1, ..., hi-1 from a set.
+// Elements that are not present, including negative values, are ignored.
+func (s *Set[E]) RemoveRange(lo, hi E) {
+	lo = max(lo, 0)
+	if hi <= lo {
+		return
+	}
+	i, j := uint64(lo), min(uint64(hi), uint64(len(s.w))*wordBits)
+	for i < j {
+		wi := i / wordBits
+		n := min(wordBits-i%wordBits, j-i)
+		s.w[wi] &^= (1<<n - 1) << (i % wordBits)
+		i += n
+	}
+	s.trim()
+}
+
+// RemoveSet removes the elements of set s2 from s.
+// Elements present in s2 but not s are ignored.
+func (s *Set[E]) RemoveSet(s2 *Set[E]) {
+	for i := range min(len(s.w), len(s2.w)) {
+		s.w[i] &^= s2.w[i]
+	}
+	s.trim()
+}
+
+// RetainSet removes the elements of s that are not present in s2,
+// leaving s as the intersection of s and s2.
+func (s *Set[E]) RetainSet(s2 *Set[E]) {
+	s.w = s.w[:min(len(s.w), len(s2.w))]
+	for i := range s.w {
+		s.w[i] &= s2.w[i]
+	}
+	s.trim()
+}
+
+// XorSet removes the elements of s that are present in s2 and adds the
+// elements of s2 that are not present in s, leaving s as the symmetric
+// difference of s and s2.
+func (s *Set[E]) XorSet(s2 *Set[E]) {
+	s.grow(uint64(len(s2.w)))
+	for i, w := range s2.w {
+		s.w[i] ^= w
+	}
+	s.trim()
+}
+
+// Contains reports whether v is in the set.
+func (s *Set[E]) Contains(v E) bool {
+	if v < 0 {
+		return false
+	}
+	i := uint64(v)
+	return i/wordBits < uint64(len(s.w)) && s.w[i/wordBits]&(1<<(i%wordBits)) != 0
+}
+
+// ContainsAny reports whether any of the elements in s2 are in s.
+func (s *Set[E]) ContainsAny(s2 *Set[E]) bool {
+	for i := range min(len(s.w), len(s2.w)) {
+		if s.w[i]&s2.w[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAll reports whether all of the elements in s2 are in s.
+func (s *Set[E]) ContainsAll(s2 *Set[E]) bool {
+	if len(s2.w) > len(s.w) {
+		return false
+	}
+	for i, w := range s2.w {
+		if w&^s.w[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether s and s2 contain the same elements.
+func (s *Set[E]) Equal(s2 *Set[E]) bool {
+	return slices.Equal(s.w, s2.w)
+}
+
+// IsSubset reports whether every element of s is in s2.
+func (s *Set[E]) IsSubset(s2 *Set[E]) bool {
+	return s2.ContainsAll(s)
+}
+
+// IsSuperset reports whether every element of s2 is in s.
+// It is the same as ContainsAll.
+func (s *Set[E]) IsSuperset(s2 *Set[E]) bool {
+	return s.ContainsAll(s2)
+}
+
+// IsProperSubset reports whether s is a subset of s2 and s2 has elements
+// that are not in s.
+func (s *Set[E]) IsProperSubset(s2 *Set[E]) bool {
+	return s2.ContainsAll(s) && !s.Equal(s2)
+}
+
+// IsDisjoint reports whether s and s2 have no elements in common.
+func (s *Set[E]) IsDisjoint(s2 *Set[E]) bool {
+	return !s.ContainsAny(s2)
+}
+
+// Clear removes all elements from s, leaving it empty.
+// It retains the memory used by s for reuse.
+func (s *Set[E]) Clear() {
+	clear(s.w)
+	s.w = s.w[:0]
+}
+
+// Clone returns a copy of s.
+func (s *Set[E]) Clone() *Set[E] {
+	return &Set[E]{w: slices.Clone(s.w)}
+}
+
+// RemoveIf deletes any elements from s for which remove returns true.
+func (s *Set[E]) RemoveIf(remove func(E) bool) {
+	for i, w := range s.w {
+		for w != 0 {
+			b := bits.TrailingZeros64(w)
+			w &= w - 1
+			if remove(E(i*wordBits + b)) {
+				s.w[i] &^= 1 << b
+			}
+		}
+	}
+	s.trim()
+}
+
+// Len returns the number of elements in s.
+// It takes time proportional to the largest element.
+func (s *Set[E]) Len() int {
+	var n int
+	for _, w := range s.w {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// All returns an iterator over the elements in the set in ascending order.
+// If the set is modified during iteration, the iterator produces each
+// element at most once, and elements greater than the most recently
+// produced one are produced if they are present when they are reached.
+func (s *Set[E]) All() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for i := 0; i < len(s.w); i++ {
+			w := s.w[i]
+			for w != 0 {
+				b := bits.TrailingZeros64(w)
+				if !yield(E(i*wordBits + b)) {
+					return
+				}
+				if i >= len(s.w) {
+					break
+				}
+				// Pick up changes made by yield to the rest of this word.
+				w = s.w[i] & (^uint64(0) << b << 1)
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the elements in the set in descending
+// order. Its behavior under modification mirrors that of All.
+func (s *Set[E]) Backward() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for i := len(s.w) - 1; i >= 0; i-- {
+			if i >= len(s.w) {
+				continue
+			}
+			w := s.w[i]
+			for w != 0 {
+				b := wordBits - 1 - bits.LeadingZeros64(w)
+				if !yield(E(i*wordBits + b)) {
+					return
+				}
+				if i >= len(s.w) {
+					break
+				}
+				w = s.w[i] & (1<<b - 1)
+			}
+		}
+	}
+}
+
+// NextSet returns the smallest element of s that is greater than or equal
+// to v. If there is no such element, it returns 0, false.
+func (s *Set[E]) NextSet(v E) (E, bool) {
+	v = max(v, 0)
+	i := uint64(v)
+	wi := i / wordBits
+	if wi >= uint64(len(s.w)) {
+		return 0, false
+	}
+	if w := s.w[wi] >> (i % wordBits); w != 0 {
+		return v + E(bits.TrailingZeros64(w)), true
+	}
+	for wi++; wi < uint64(len(s.w)); wi++ {
+		if w := s.w[wi]; w != 0 {
+			return E(wi*wordBits + uint64(bits.TrailingZeros64(w))), true
+		}
+	}
+	return 0, false
+}
+
+// PrevSet returns the largest element of s that is less than or equal
+// to v. If there is no such element, it returns 0, false.
+func (s *Set[E]) PrevSet(v E) (E, bool) {
+	if v < 0 || len(s.w) == 0 {
+		return 0, false
+	}
+	i := uint64(v)
+	wi := i / wordBits
+	if wi >= uint64(len(s.w)) {
+		// The last word is nonzero.
+		wi = uint64(len(s.w) - 1)
+		return E(wi*wordBits + wordBits - 1 - uint64(bits.LeadingZeros64(s.w[wi]))), true
+	}
+	if w := s.w[wi] << (wordBits - 1 - i%wordBits); w != 0 {
+		return v - E(bits.LeadingZeros64(w)), true
+	}
+	for wi > 0 {
+		wi--
+		if w := s.w[wi]; w != 0 {
+			return E(wi*wordBits + wordBits - 1 - uint64(bits.LeadingZeros64(w))), true
+		}
+	}
+	return 0, false
+}
+
+// Union constructs a new set containing the union of s1 and s2.
+func Union[E Integer](s1, s2 *Set[E]) *Set[E] {
+	if len(s1.w) < len(s2.w) {
+		s1, s2 = s2, s1
+	}
+	s := s1.Clone()
+	for i, w := range s2.w {
+		s.w[i] |= w
+	}
+	return s
+}
+
+// Intersection constructs a new set containing the intersection of s1 and s2.
+func Intersection[E Integer](s1, s2 *Set[E]) *Set[E] {
+	n := min(len(s1.w), len(s2.w))
+	if n == 0 {
+		return new(Set[E])
+	}
+	s := &Set[E]{w: make([]uint64, n)}
+	for i := range s.w {
+		s.w[i] = s1.w[i] & s2.w[i]
+	}
+	s.trim()
+	return s
+}
+
+// Difference constructs a new set containing the elements of s1 that
+// are not present in s2.
+func Difference[E Integer](s1, s2 *Set[E]) *Set[E] {
+	s := s1.Clone()
+	s.RemoveSet(s2)
+	return s
+}
+
+// SymmetricDifference constructs a new set containing the elements that are
+// present in exactly one of s1 and s2.
+func SymmetricDifference[E Integer](s1, s2 *Set[E]) *Set[E] {
+	if len(s1.w) < len(s2.w) {
+		s1, s2 = s2, s1
+	}
+	s := s1.Clone()
+	s.XorSet(s2)
+	return s
+}
+
+// grow extends s.w to have at least n words.
+func (s *Set[E]) grow(n uint64) {
+	if old := len(s.w); n > uint64(old) {
+		s.w = slices.Grow(s.w, int(n)-old)[:n]
+		clear(s.w[old:])
+	}
+}
+
+// trim removes the zero words from the end of s.w.
+func (s *Set[E]) trim() {
+	n := len(s.w)
+	for n > 0 && s.w[n-1] == 0 {
+		n--
+	}
+	s.w = s.w[:n]
+}
@@ -0,0 +1,408 @@
+package bitset
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/cespare/next/container/set"
+)
+
+func TestString(t *testing.T) {
+	for _, tt := range []struct {
+		s    *Set[int]
+		want string
+		gs   string
+	}{
+		{Of[int](), "bitset[]", "bitset.Of[int]()"},
+		{Of(3), "bitset[3]", "bitset.Of[int](3)"},
+		{Of(64, 1, 3), "bitset[1 3 64]", "bitset.Of[int](1, 3, 64)"},
+	} {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("String: got %q; want %q", got, tt.want)
+		}
+		if got := fmt.Sprintf("%#v", tt.s); got != tt.gs {
+			t.Errorf("GoString: got %q; want %q", got, tt.gs)
+		}
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	check(t, Of(5, 1, 3, 1, 200), 1, 3, 5, 200)
+	check(t, Of[uint8](255, 0), 0, 255)
+
+	var s Set[int]
+	s.Add()
+	check(t, &s)
+	s.Add(3, 64, 3, 127)
+	check(t, &s, 3, 64, 127)
+	s.Remove(127, -1, 1000)
+	check(t, &s, 3, 64)
+	if len(s.w) != 2 {
+		t.Errorf("after removing the largest element, got %d words; want 2", len(s.w))
+	}
+	s.Remove(3, 64)
+	check(t, &s)
+	if len(s.w) != 0 {
+		t.Errorf("after removing all elements, got %d words; want 0", len(s.w))
+	}
+}
+
+func TestNegative(t *testing.T) {
+	for name, f := range map[string]func(*Set[int]){
+		"Add":      func(s *Set[int]) { s.Add(1, -1) },
+		"AddRange": func(s *Set[int]) { s.AddRange(-1, 3) },
+		"Of":       func(*Set[int]) { Of(-5) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s with a negative element did not panic", name)
+				}
+			}()
+			f(new(Set[int]))
+		}()
+	}
+	s := Of(0, 1)
+	if s.Contains(-1) {
+		t.Error("Contains(-1): got true")
+	}
+	s.RemoveRange(-10, 1)
+	check(t, s, 1)
+}
+
+func TestRanges(t *testing.T) {
+	for _, tt := range []struct {
+		lo, hi int
+	}{
+		{0, 0}, {5, 3}, {0, 1}, {3, 10}, {0, 64}, {63, 65}, {10, 200}, {64, 128}, {1, 1000},
+	} {
+		s := new(Set[int])
+		s.AddRange(tt.lo, tt.hi)
+		var want []int
+		for i := tt.lo; i < tt.hi; i++ {
+			want = append(want, i)
+		}
+		check(t, s, want...)
+
+		s = new(Set[int])
+		s.AddRange(0, 1100)
+		s.RemoveRange(tt.lo, tt.hi)
+		want = nil
+		for i := range 1100 {
+			if i < tt.lo || i >= tt.hi {
+				want = append(want, i)
+			}
+		}
+		check(t, s, want...)
+	}
+
+	s := Of(1, 2, 3)
+	s.RemoveRange(2, 100000)
+	check(t, s, 1)
+	s.RemoveRange(0, 2)
+	check(t, s)
+	if len(s.w) != 0 {
+		t.Errorf("after RemoveRange of all elements, got %d words; want 0", len(s.w))
+	}
+}
+
+func TestNextPrev(t *testing.T) {
+	s := Of(3, 64, 65, 200)
+	for _, tt := range []struct {
+		v      int
+		next   int
+		nextOK bool
+		prev   int
+		prevOK bool
+	}{
+		{-5, 3, true, 0, false},
+		{0, 3, true, 0, false},
+		{3, 3, true, 3, true},
+		{4, 64, true, 3, true},
+		{64, 64, true, 64, true},
+		{65, 65, true, 65, true},
+		{66, 200, true, 65, true},
+		{199, 200, true, 65, true},
+		{200, 200, true, 200, true},
+		{201, 0, false, 200, true},
+		{100000, 0, false, 200, true},
+	} {
+		if got, ok := s.NextSet(tt.v); got != tt.next || ok != tt.nextOK {
+			t.Errorf("NextSet(%d): got (%d, %t); want (%d, %t)", tt.v, got, ok, tt.next, tt.nextOK)
+		}
+		if got, ok := s.PrevSet(tt.v); got != tt.prev || ok != tt.prevOK {
+			t.Errorf("PrevSet(%d): got (%d, %t); want (%d, %t)", tt.v, got, ok, tt.prev, tt.prevOK)
+		}
+	}
+	var empty Set[uint]
+	if _, ok := empty.NextSet(0); ok {
+		t.Error("NextSet on empty set: got true")
+	}
+	if _, ok := empty.PrevSet(100); ok {
+		t.Error("PrevSet on empty set: got true")
+	}
+}
+
+func TestIterate(t *testing.T) {
+	s := Of(1, 5, 63, 64, 300)
+	if got := slices.Collect(s.All()); !slices.Equal(got, []int{1, 5, 63, 64, 300}) {
+		t.Errorf("All: got %v", got)
+	}
+	if got := slices.Collect(s.Backward()); !slices.Equal(got, []int{300, 64, 63, 5, 1}) {
+		t.Errorf("Backward: got %v", got)
+	}
+	for v := range s.All() {
+		if v != 1 {
+			t.Fatalf("first element: got %d; want 1", v)
+		}
+		break
+	}
+
+	// Elements added ahead of the iterator are produced;
+	// removed ones are not.
+	var got []int
+	for v := range s.All() {
+		got = append(got, v)
+		switch v {
+		case 1:
+			s.Add(2, 0)
+			s.Remove(63)
+		case 5:
+			s.Remove(300)
+			s.Add(100)
+		case 64:
+			s.Remove(100)
+		}
+	}
+	if !slices.Equal(got, []int{1, 2, 5, 64}) {
+		t.Errorf("All with modification: got %v", got)
+	}
+
+	s = Of(1, 5, 63, 64, 300)
+	got = nil
+	for v := range s.Backward() {
+		got = append(got, v)
+		switch v {
+		case 300:
+			s.Remove(300, 64)
+			s.Add(4)
+		case 5:
+			s.Clear()
+		}
+	}
+	if !slices.Equal(got, []int{300, 63, 5}) {
+		t.Errorf("Backward with modification: got %v", got)
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	for _, tt := range []struct {
+		s1, s2                 *Set[int]
+		union, inter, diff, sd *Set[int]
+		subset, proper, disj   bool
+	}{
+		{Of[int](), Of[int](), Of[int](), Of[int](), Of[int](), Of[int](), true, false, true},
+		{Of[int](), Of(3), Of(3), Of[int](), Of[int](), Of(3), true, true, true},
+		{Of(3), Of[int](), Of(3), Of[int](), Of(3), Of(3), false, false, true},
+		{Of(3), Of(3), Of(3), Of(3), Of[int](), Of[int](), true, false, false},
+		{Of(3), Of(3, 100), Of(3, 100), Of(3), Of[int](), Of(100), true, true, false},
+		{Of(3, 100), Of(3), Of(3, 100), Of(3), Of(100), Of(100), false, false, false},
+		{Of(1, 200), Of(2, 300), Of(1, 2, 200, 300), Of[int](), Of(1, 200), Of(1, 2, 200, 300), false, false, true},
+		{Of(1, 2, 200), Of(2, 200, 300), Of(1, 2, 200, 300), Of(2, 200), Of(1), Of(1, 300), false, false, false},
+	} {
+		old1, old2 := tt.s1.Clone(), tt.s2.Clone()
+		for _, op := range []struct {
+			name    string
+			f       func(s1, s2 *Set[int]) *Set[int]
+			inPlace func(s, s2 *Set[int])
+			want    *Set[int]
+		}{
+			{"Union", Union[int], (*Set[int]).AddSet, tt.union},
+			{"Intersection", Intersection[int], (*Set[int]).RetainSet, tt.inter},
+			{"Difference", Difference[int], (*Set[int]).RemoveSet, tt.diff},
+			{"SymmetricDifference", SymmetricDifference[int], (*Set[int]).XorSet, tt.sd},
+		} {
+			if got := op.f(tt.s1, tt.s2); !got.Equal(op.want) {
+				t.Errorf("%s(%s, %s): got %s; want %s", op.name, tt.s1, tt.s2, got, op.want)
+			}
+			s := tt.s1.Clone()
+			op.inPlace(s, tt.s2)
+			if !s.Equal(op.want) {
+				t.Errorf("in-place %s(%s, %s): got %s; want %s", op.name, tt.s1, tt.s2, s, op.want)
+			}
+		}
+		if !tt.s1.Equal(old1) || !tt.s2.Equal(old2) {
+			t.Errorf("operations on %s and %s modified their arguments", old1, old2)
+		}
+		if got := tt.s1.IsSubset(tt.s2); got != tt.subset {
+			t.Errorf("%s.IsSubset(%s): got %t", tt.s1, tt.s2, got)
+		}
+		if got := tt.s2.IsSuperset(tt.s1); got != tt.subset {
+			t.Errorf("%s.IsSuperset(%s): got %t", tt.s2, tt.s1, got)
+		}
+		if got := tt.s1.IsProperSubset(tt.s2); got != tt.proper {
+			t.Errorf("%s.IsProperSubset(%s): got %t", tt.s1, tt.s2, got)
+		}
+		if got := tt.s1.IsDisjoint(tt.s2); got != tt.disj {
+			t.Errorf("%s.IsDisjoint(%s): got %t", tt.s1, tt.s2, got)
+		}
+		if got := tt.s1.ContainsAny(tt.s2); got == tt.disj {
+			t.Errorf("%s.ContainsAny(%s): got %t", tt.s1, tt.s2, got)
+		}
+	}
+}
+
+// TestTrim checks that each operation that can clear the highest words of
+// a set drops them, so that sets with the same elements have the same
+// words and Equal can compare them directly.
+func TestTrim(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		f    func() *Set[int]
+		want []int
+	}{
+		{"Remove", func() *Set[int] { s := Of(1, 64, 200); s.Remove(200, 64); return s }, []int{1}},
+		{"RemoveRange", func() *Set[int] { s := Of(1, 64, 200); s.RemoveRange(60, 300); return s }, []int{1}},
+		{"RemoveSet", func() *Set[int] { s := Of(1, 64, 200); s.RemoveSet(Of(200)); return s }, []int{1, 64}},
+		{"RetainSet", func() *Set[int] { s := Of(1, 64, 200); s.RetainSet(Of(1, 199)); return s }, []int{1}},
+		{"XorSet", func() *Set[int] { s := Of(1, 64, 200); s.XorSet(Of(64, 200)); return s }, []int{1}},
+		{"RemoveIf", func() *Set[int] { s := Of(1, 64, 200); s.RemoveIf(func(v int) bool { return v > 1 }); return s }, []int{1}},
+		{"Intersection", func() *Set[int] { return Intersection(Of(1, 64, 200), Of(1, 65, 200+64)) }, []int{1}},
+		{"Difference", func() *Set[int] { return Difference(Of(1, 64, 200), Of(64, 200)) }, []int{1}},
+		{"SymmetricDifference", func() *Set[int] { return SymmetricDifference(Of(1, 200), Of(200)) }, []int{1}},
+		{"all removed", func() *Set[int] { s := Of(64, 200); s.XorSet(Of(64, 200)); return s }, nil},
+	} {
+		s := tt.f()
+		check(t, s, tt.want...)
+		if want := Of(tt.want...); !slices.Equal(s.w, want.w) {
+			t.Errorf("%s: got words %x; want %x", tt.name, s.w, want.w)
+		}
+	}
+}
+
+func TestRetainThenGrow(t *testing.T) {
+	// RetainSet shortens the slice; growing it again must not bring back
+	// the elements that were removed.
+	s := Of(1, 100, 200)
+	s.RetainSet(Of(1))
+	s.Add(130)
+	check(t, s, 1, 130)
+}
+
+func TestClearClone(t *testing.T) {
+	s := Of(1, 100)
+	c := s.Clone()
+	s.Clear()
+	check(t, s)
+	check(t, c, 1, 100)
+	s.Add(5)
+	check(t, s, 5)
+	check(t, c, 1, 100)
+}
+
+func TestRemoveIf(t *testing.T) {
+	s := new(Set[int])
+	s.AddRange(0, 200)
+	s.RemoveIf(func(v int) bool { return v%3 != 0 || v > 100 })
+	var want []int
+	for i := 0; i <= 100; i += 3 {
+		want = append(want, i)
+	}
+	check(t, s, want...)
+}
+
+func TestRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	randSet := func() (*Set[uint16], *set.Set[uint16]) {
+		s, m := new(Set[uint16]), new(set.Set[uint16])
+		n, limit := r.IntN(50), 1+r.IntN(1000)
+		for range n {
+			v := uint16(r.IntN(limit))
+			s.Add(v)
+			m.Add(v)
+		}
+		return s, m
+	}
+	for range 1000 {
+		s1, m1 := randSet()
+		s2, m2 := randSet()
+		checkModel(t, s1, m1)
+		checkModel(t, Union(s1, s2), set.Union(m1, m2))
+		checkModel(t, Intersection(s1, s2), set.Intersection(m1, m2))
+		checkModel(t, Difference(s1, s2), set.Difference(m1, m2))
+		checkModel(t, SymmetricDifference(s1, s2), set.SymmetricDifference(m1, m2))
+		if got, want := s1.ContainsAll(s2), m1.ContainsAll(m2); got != want {
+			t.Fatalf("%s.ContainsAll(%s): got %t", s1, s2, got)
+		}
+		if got, want := s1.ContainsAny(s2), m1.ContainsAny(m2); got != want {
+			t.Fatalf("%s.ContainsAny(%s): got %t", s1, s2, got)
+		}
+		if got, want := s1.Equal(s2), m1.Equal(m2); got != want {
+			t.Fatalf("%s.Equal(%s): got %t", s1, s2, got)
+		}
+		lo, hi := uint16(r.IntN(1000)), uint16(r.IntN(1000))
+		s1.RemoveRange(lo, hi)
+		m1.RemoveIf(func(v uint16) bool { return v >= lo && v < hi })
+		checkModel(t, s1, m1)
+	}
+}
+
+func checkModel[E Integer](t *testing.T, s *Set[E], m *set.Set[E]) {
+	t.Helper()
+	want := slices.Sorted(m.All())
+	if got := slices.Collect(s.All()); !slices.Equal(got, want) {
+		t.Fatalf("got %s; want %v", s, want)
+	}
+	if len(s.w) > 0 && s.w[len(s.w)-1] == 0 {
+		t.Fatalf("%s has trailing zero words: %x", s, s.w)
+	}
+}
+
+func check[E Integer](t *testing.T, s *Set[E], want ...E) {
+	t.Helper()
+	if got := slices.Collect(s.All()); !slices.Equal(got, want) {
+		t.Fatalf("got %s; want %v", s, want)
+	}
+	if got := s.Len(); got != len(want) {
+		t.Fatalf("Len: got %d; want %d", got, len(want))
+	}
+	for _, v := range want {
+		if !s.Contains(v) {
+			t.Fatalf("%s.Contains(%d): got false", s, v)
+		}
+	}
+	if len(s.w) > 0 && s.w[len(s.w)-1] == 0 {
+		t.Fatalf("%s has trailing zero words: %x", s, s.w)
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	for _, n := range []int{100, 10_000} {
+		s1, s2 := new(Set[int]), new(Set[int])
+		m1, m2 := new(set.Set[int]), new(set.Set[int])
+		for i := range n {
+			if i%2 == 0 {
+				s1.Add(i)
+				m1.Add(i)
+			}
+			if i%3 == 0 {
+				s2.Add(i)
+				m2.Add(i)
+			}
+		}
+		b.Run(fmt.Sprintf("n=%d/impl=bitset", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				Union(s1, s2)
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/impl=map", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				set.Union(m1, m2)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package hashset
+
+import (
+	"bytes"
+	"hash/maphash"
+	"slices"
+)
+
+// A Hasher defines the hash function and equality used by a Set.
+//
+// Equal must be an equivalence relation, and Hash must be consistent with
+// it: if Equal(a, b), then Hash must write the same data to h for a and b.
+// Hash should not call h.SetSeed or h.Reset; the Set seeds h before
+// calling Hash.
+//
+// A Set uses the zero value of its Hasher type unless it is created by New,
+// so a Hasher whose zero value is usable, such as an empty struct, makes the
+// zero value of the Set usable as well.
+type Hasher[E any] interface {
+	Hash(h *maphash.Hash, v E)
+	Equal(a, b E) bool
+}
+
+// BytesHasher is a Hasher for byte slices that compares them by content,
+// as bytes.Equal does. A nil slice and an empty slice are the same element.
+type BytesHasher struct{}
+
+// Hash writes the contents of v to h.
+func (BytesHasher) Hash(h *maphash.Hash, v []byte) {
+	h.Write(v)
+}
+
+// Equal reports whether a and b have the same contents.
+func (BytesHasher) Equal(a, b []byte) bool {
+	return bytes.Equal(a, b)
+}
+
+// SliceHasher is a Hasher for slices of comparable elements that compares
+// them element by element, as slices.Equal does. A nil slice and an empty
+// slice are the same element.
+//
+// As with ==, a slice containing a floating-point NaN is not equal to
+// itself, so each such slice added to a Set is a distinct element.
+type SliceHasher[E comparable] struct{}
+
+// Hash writes the length of v and each of its elements to h.
+func (SliceHasher[E]) Hash(h *maphash.Hash, v []E) {
+	maphash.WriteComparable(h, len(v))
+	for _, e := range v {
+		maphash.WriteComparable(h, e)
+	}
+}
+
+// Equal reports whether a and b have the same length and equal elements.
+func (SliceHasher[E]) Equal(a, b []E) bool {
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,85 @@
+package hashset
+
+import (
+	"hash/maphash"
+	"math"
+	"testing"
+)
+
+func hashOf[E any, H Hasher[E]](seed maphash.Seed, h H, v E) uint64 {
+	var mh maphash.Hash
+	mh.SetSeed(seed)
+	h.Hash(&mh, v)
+	return mh.Sum64()
+}
+
+func TestBytesHasher(t *testing.T) {
+	seed := maphash.MakeSeed()
+	var h BytesHasher
+	a, b := []byte("hello"), []byte("hello")
+	if !h.Equal(a, b) || hashOf(seed, h, a) != hashOf(seed, h, b) {
+		t.Error("equal byte slices are not Equal or have different hashes")
+	}
+	if !h.Equal(nil, []byte{}) || hashOf(seed, h, nil) != hashOf(seed, h, []byte{}) {
+		t.Error("nil and empty byte slices are not Equal or have different hashes")
+	}
+	if h.Equal(a, []byte("hellO")) {
+		t.Error("different byte slices are Equal")
+	}
+}
+
+func TestSliceHasher(t *testing.T) {
+	seed := maphash.MakeSeed()
+	var h SliceHasher[string]
+	a, b := []string{"a", "bc"}, []string{"a", "bc"}
+	if !h.Equal(a, b) || hashOf(seed, h, a) != hashOf(seed, h, b) {
+		t.Error("equal slices are not Equal or have different hashes")
+	}
+	if !h.Equal(nil, []string{}) || hashOf(seed, h, nil) != hashOf(seed, h, []string{}) {
+		t.Error("nil and empty slices are not Equal or have different hashes")
+	}
+	for _, c := range [][]string{{"ab", "c"}, {"a"}, {"a", "bc", ""}} {
+		if h.Equal(a, c) {
+			t.Errorf("%q and %q are Equal", a, c)
+		}
+	}
+
+	// As with ==, slices holding NaNs are distinct elements.
+	s := Of[[]float64, SliceHasher[float64]]([]float64{math.NaN()}, []float64{math.NaN()}, []float64{1})
+	if s.Len() != 3 {
+		t.Errorf("got %v; want 3 elements", s)
+	}
+}
+
+// A record is a struct containing a slice, which is not comparable.
+type record struct {
+	name string
+	tags []string
+}
+
+type recordHasher struct{}
+
+func (recordHasher) Hash(h *maphash.Hash, r record) {
+	h.WriteString(r.name)
+	SliceHasher[string]{}.Hash(h, r.tags)
+}
+
+func (recordHasher) Equal(a, b record) bool {
+	return a.name == b.name && SliceHasher[string]{}.Equal(a.tags, b.tags)
+}
+
+func TestStructHasher(t *testing.T) {
+	var s Set[record, recordHasher]
+	s.Add(
+		record{"a", []string{"x"}},
+		record{"a", []string{"x"}},
+		record{"a", []string{"x", "y"}},
+		record{"b", nil},
+	)
+	if s.Len() != 3 {
+		t.Errorf("got %v; want 3 elements", &s)
+	}
+	if !s.Contains(record{"b", []string{}}) {
+		t.Error("Contains: got false for record with empty tags")
+	}
+}
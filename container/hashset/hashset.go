@@ -0,0 +1,362 @@
+// Package hashset defines a Set type that holds a set of elements of any
+// type, using a hash function and equality supplied by the user.
+package hashset
+
+import (
+	"fmt"
+	"hash/maphash"
+	"iter"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// A Set is a set of elements of any type, including types that are not
+// comparable, such as slices and structs containing them. It has the same
+// methods as set.Set, but it identifies elements using the Hash and Equal
+// methods of its Hasher H rather than the == operator.
+//
+// Sets are implemented using maps from hash values to elements, and have
+// similar performance characteristics, plus the cost of hashing. Each Set
+// hashes with its own random maphash.Seed, so the hash values of elements
+// cannot be predicted by an adversary.
+//
+// Copying a Set that has held elements gives a second Set that shares the
+// first one's map but keeps its own count of elements: elements added to
+// or removed from either one appear in both, but only the one that changed
+// reports the right Len. Use Clone to make an independent copy.
+// If H's zero value is a usable Hasher, as it is for BytesHasher and
+// SliceHasher, the zero value of a Set is an empty set ready to use.
+// As with maps, concurrent calls to functions and methods that read values
+// are fine; concurrent calls to functions and methods that write values are
+// racy.
+type Set[E any, H Hasher[E]] struct {
+	hasher H
+	seed   maphash.Seed
+	// m maps each hash value to the elements with that hash, which are
+	// almost always only one. The slices are never modified in place,
+	// only replaced or appended to, so they may be shared by Clone
+	// and held by iterators.
+	m map[uint64][]E
+	n int
+}
+
+// New returns a new, empty set that uses the Hasher h.
+func New[E any, H Hasher[E]](h H) *Set[E, H] {
+	return &Set[E, H]{hasher: h}
+}
+
+// Of returns a new set containing the listed elements,
+// using the zero value of H as its Hasher.
+func Of[E any, H Hasher[E]](v ...E) *Set[E, H] {
+	s := new(Set[E, H])
+	s.Add(v...)
+	return s
+}
+
+// String returns a human-readable representation of the set.
+// The elements are listed in the lexical order of their string
+// representations.
+func (s *Set[E, H]) String() string {
+	return fmt.Sprintf("hashset[%s]", strings.Join(s.formatElems("%v"), " "))
+}
+
+// GoString returns a Go syntax representation of the set.
+// The elements are listed in the same order as by String.
+func (s *Set[E, H]) GoString() string {
+	var v E
+	var h H
+	elems := strings.Join(s.formatElems("%#v"), ", ")
+	return fmt.Sprintf("hashset.Of[%T, %T](%s)", v, h, elems)
+}
+
+func (s *Set[E, H]) formatElems(format string) []string {
+	vals := make([]string, 0, s.n)
+	for v := range s.All() {
+		vals = append(vals, fmt.Sprintf(format, v))
+	}
+	sort.Strings(vals)
+	return vals
+}
+
+// hash returns the hash of v under s's seed, which must be initialized.
+func (s *Set[E, H]) hash(v E) uint64 {
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	s.hasher.Hash(&h, v)
+	return h.Sum64()
+}
+
+// lookup returns the hash of v and its index in the bucket for that hash,
+// or -1 if v is not present. If s is empty, lookup does not hash v.
+func (s *Set[E, H]) lookup(v E) (h uint64, i int) {
+	if s.n == 0 {
+		return 0, -1
+	}
+	h = s.hash(v)
+	return h, slices.IndexFunc(s.m[h], func(x E) bool { return s.hasher.Equal(x, v) })
+}
+
+func (s *Set[E, H]) add(v E) {
+	if s.m == nil {
+		s.m = make(map[uint64][]E)
+		s.seed = maphash.MakeSeed()
+	}
+	h := s.hash(v)
+	b := s.m[h]
+	for _, x := range b {
+		if s.hasher.Equal(x, v) {
+			return
+		}
+	}
+	s.m[h] = append(b, v)
+	s.n++
+}
+
+func (s *Set[E, H]) remove(v E) {
+	h, i := s.lookup(v)
+	if i < 0 {
+		return
+	}
+	if b := s.m[h]; len(b) == 1 {
+		delete(s.m, h)
+	} else {
+		s.m[h] = slices.Delete(slices.Clone(b), i, i+1)
+	}
+	s.n--
+}
+
+// Add adds elements to a set.
+func (s *Set[E, H]) Add(v ...E) {
+	for _, vv := range v {
+		s.add(vv)
+	}
+}
+
+// AddSet adds the elements of set s2 to s.
+func (s *Set[E, H]) AddSet(s2 *Set[E, H]) {
+	for v := range s2.All() {
+		s.add(v)
+	}
+}
+
+// Remove removes elements from a set.
+// Elements that are not present are ignored.
+func (s *Set[E, H]) Remove(v ...E) {
+	for _, vv := range v {
+		s.remove(vv)
+	}
+}
+
+// RemoveSet removes the elements of set s2 from s.
+// Elements present in s2 but not s are ignored.
+func (s *Set[E, H]) RemoveSet(s2 *Set[E, H]) {
+	for v := range s2.All() {
+		s.remove(v)
+	}
+}
+
+// Contains reports whether v is in the set.
+func (s *Set[E, H]) Contains(v E) bool {
+	_, i := s.lookup(v)
+	return i >= 0
+}
+
+// ContainsAny reports whether any of the elements in s2 are in s.
+func (s *Set[E, H]) ContainsAny(s2 *Set[E, H]) bool {
+	small, large := smallLarge(s, s2)
+	for v := range small.All() {
+		if large.Contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAll reports whether all of the elements in s2 are in s.
+func (s *Set[E, H]) ContainsAll(s2 *Set[E, H]) bool {
+	if s2.n > s.n {
+		return false
+	}
+	for v := range s2.All() {
+		if !s.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether s and s2 contain the same elements.
+func (s *Set[E, H]) Equal(s2 *Set[E, H]) bool {
+	return s.n == s2.n && s.ContainsAll(s2)
+}
+
+// Clear removes all elements from s, leaving it empty.
+func (s *Set[E, H]) Clear() {
+	clear(s.m)
+	s.n = 0
+}
+
+// Clone returns a copy of s that uses the same Hasher.
+// The elements are copied using assignment,
+// so this is a shallow clone.
+func (s *Set[E, H]) Clone() *Set[E, H] {
+	s1 := &Set[E, H]{hasher: s.hasher, seed: s.seed, n: s.n}
+	if s.m != nil {
+		s1.m = make(map[uint64][]E, len(s.m))
+		for h, b := range s.m {
+			// Clip the shared bucket so that appending to it in s1
+			// reallocates rather than using spare capacity that s may
+			// also append to.
+			s1.m[h] = slices.Clip(b)
+		}
+	}
+	return s1
+}
+
+// RemoveIf deletes any elements from s for which remove returns true.
+func (s *Set[E, H]) RemoveIf(remove func(E) bool) {
+	for h, b := range s.m {
+		if len(b) == 1 {
+			if remove(b[0]) {
+				delete(s.m, h)
+				s.n--
+			}
+			continue
+		}
+		kept := make([]E, 0, len(b))
+		for _, v := range b {
+			if remove(v) {
+				s.n--
+			} else {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.m, h)
+		} else {
+			s.m[h] = kept
+		}
+	}
+}
+
+// Len returns the number of elements in s.
+func (s *Set[E, H]) Len() int {
+	return s.n
+}
+
+// All returns an iterator over the elements in the set.
+// The iteration order is not specified
+// and is not guaranteed to be the same from one call to the next.
+// As with ranging over a map, if an element is removed during iteration
+// before it is reached, it is not produced, and an element that is added
+// during iteration may or may not be produced.
+func (s *Set[E, H]) All() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for h, b := range s.m {
+			for i, v := range b {
+				// Skip elements of the bucket that an earlier yield
+				// removed. The first one is current, since the map
+				// produced the bucket just now.
+				if i > 0 && !slices.ContainsFunc(s.m[h], func(x E) bool { return s.hasher.Equal(x, v) }) {
+					continue
+				}
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// smallLarge returns s1 and s2 ordered by size.
+func smallLarge[E any, H Hasher[E]](s1, s2 *Set[E, H]) (small, large *Set[E, H]) {
+	if s2.n < s1.n {
+		return s2, s1
+	}
+	return s1, s2
+}
+
+// Union constructs a new set containing the union of s1 and s2.
+func Union[E any, H Hasher[E]](s1, s2 *Set[E, H]) *Set[E, H] {
+	// Copy the larger set, reusing its hash values, and add the elements
+	// of the smaller one.
+	small, large := smallLarge(s1, s2)
+	s := large.Clone()
+	s.AddSet(small)
+	return s
+}
+
+// Intersection constructs a new set containing the intersection of s1 and s2.
+func Intersection[E any, H Hasher[E]](s1, s2 *Set[E, H]) *Set[E, H] {
+	s := New[E](s1.hasher)
+	small, large := smallLarge(s1, s2)
+	for v := range small.All() {
+		if large.Contains(v) {
+			s.add(v)
+		}
+	}
+	return s
+}
+
+// Difference constructs a new set containing the elements of s1 that
+// are not present in s2.
+func Difference[E any, H Hasher[E]](s1, s2 *Set[E, H]) *Set[E, H] {
+	s := New[E](s1.hasher)
+	for v := range s1.All() {
+		if !s2.Contains(v) {
+			s.add(v)
+		}
+	}
+	return s
+}
+
+// SymmetricDifference constructs a new set containing the elements that are
+// present in exactly one of s1 and s2.
+func SymmetricDifference[E any, H Hasher[E]](s1, s2 *Set[E, H]) *Set[E, H] {
+	small, large := smallLarge(s1, s2)
+	s := large.Clone()
+	s.XorSet(small)
+	return s
+}
+
+// RetainSet removes the elements of s that are not present in s2,
+// leaving s as the intersection of s and s2.
+func (s *Set[E, H]) RetainSet(s2 *Set[E, H]) {
+	s.RemoveIf(func(v E) bool { return !s2.Contains(v) })
+}
+
+// XorSet removes the elements of s that are present in s2 and adds the
+// elements of s2 that are not present in s, leaving s as the symmetric
+// difference of s and s2.
+func (s *Set[E, H]) XorSet(s2 *Set[E, H]) {
+	for v := range s2.All() {
+		if s.Contains(v) {
+			s.remove(v)
+		} else {
+			s.add(v)
+		}
+	}
+}
+
+// IsSubset reports whether every element of s is in s2.
+func (s *Set[E, H]) IsSubset(s2 *Set[E, H]) bool {
+	return s2.ContainsAll(s)
+}
+
+// IsSuperset reports whether every element of s2 is in s.
+// It is the same as ContainsAll.
+func (s *Set[E, H]) IsSuperset(s2 *Set[E, H]) bool {
+	return s.ContainsAll(s2)
+}
+
+// IsProperSubset reports whether s is a subset of s2 and s2 has elements
+// that are not in s.
+func (s *Set[E, H]) IsProperSubset(s2 *Set[E, H]) bool {
+	return s.n < s2.n && s2.ContainsAll(s)
+}
+
+// IsDisjoint reports whether s and s2 have no elements in common.
+func (s *Set[E, H]) IsDisjoint(s2 *Set[E, H]) bool {
+	return !s.ContainsAny(s2)
+}
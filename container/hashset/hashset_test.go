@@ -0,0 +1,283 @@
+package hashset
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// collider is a Hasher for ints that puts every element in the same bucket.
+type collider struct{}
+
+func (collider) Hash(*maphash.Hash, int) {}
+func (collider) Equal(a, b int) bool     { return a == b }
+
+// mod10 is a Hasher for ints that treats numbers with the same last digit
+// as the same element.
+type mod10 struct{}
+
+func (mod10) Hash(h *maphash.Hash, v int) { maphash.WriteComparable(h, v%10) }
+func (mod10) Equal(a, b int) bool         { return a%10 == b%10 }
+
+// TestString checks that elements are listed in the lexical order of
+// their string representations, since they have no natural order.
+func TestString(t *testing.T) {
+	s := Of[[]int, SliceHasher[int]]([]int{9}, []int{10}, []int{1, 2})
+	if got, want := s.String(), "hashset[[1 2] [10] [9]]"; got != want {
+		t.Errorf("String: got %q; want %q", got, want)
+	}
+	if got, want := fmt.Sprintf("%#v", s), "hashset.Of[[]int, hashset.SliceHasher[int]]([]int{1, 2}, []int{10}, []int{9})"; got != want {
+		t.Errorf("GoString: got %q; want %q", got, want)
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	check(t, Of[[]byte, BytesHasher]([]byte("b"), []byte("a"), []byte("b"), nil), "", "a", "b")
+
+	var s Set[[]byte, BytesHasher]
+	s.Add()
+	check(t, &s)
+	s.Remove([]byte("x"))
+	check(t, &s)
+	b := []byte("abc")
+	s.Add(b, []byte("abc"), []byte("xyz"))
+	check(t, &s, "abc", "xyz")
+	if !s.Contains([]byte("ab" + "c")) {
+		t.Error("Contains: got false for an equal slice")
+	}
+	s.Remove([]byte("abc"), []byte("nope"))
+	check(t, &s, "xyz")
+	s.AddSet(Of[[]byte, BytesHasher]([]byte("a"), []byte("xyz")))
+	check(t, &s, "a", "xyz")
+	s.RemoveSet(Of[[]byte, BytesHasher]([]byte("a"), []byte("b")))
+	check(t, &s, "xyz")
+}
+
+func TestNew(t *testing.T) {
+	s := New[int](mod10{})
+	s.Add(1, 11, 21, 2)
+	if s.Len() != 2 || !s.Contains(31) || s.Contains(3) {
+		t.Errorf("got %v; want 2 elements, {1, 2} mod 10", s)
+	}
+	s.Remove(41)
+	if s.Len() != 1 || !s.Contains(2) {
+		t.Errorf("after Remove(41): got %v", s)
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	var s Set[int, collider]
+	for i := range 20 {
+		s.Add(i)
+	}
+	s.Remove(3, 7, 100)
+	s.RemoveIf(func(v int) bool { return v%5 == 0 })
+	want := []int{1, 2, 4, 6, 8, 9, 11, 12, 13, 14, 16, 17, 18, 19}
+	if got := slices.Sorted(s.All()); !slices.Equal(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if s.Len() != len(want) || len(s.m) != 1 {
+		t.Errorf("got Len %d with %d buckets; want %d with 1", s.Len(), len(s.m), len(want))
+	}
+
+	// Removing elements of the current bucket during iteration
+	// keeps them from being produced.
+	var got []int
+	for v := range s.All() {
+		got = append(got, v)
+		if v%2 == 0 {
+			for _, w := range want {
+				if w != v && w%2 == 0 {
+					s.Remove(w)
+				}
+			}
+		}
+	}
+	evens := 0
+	for _, v := range got {
+		if v%2 == 0 {
+			evens++
+		}
+	}
+	if evens != 1 {
+		t.Errorf("iteration with removal produced %v; want exactly one even element", got)
+	}
+
+	// Clones don't share appended elements.
+	c := s.Clone()
+	s.Add(100)
+	c.Add(200)
+	if s.Contains(200) || c.Contains(100) {
+		t.Error("appending to a clone's bucket affected the original, or vice versa")
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	of := func(v ...string) *Set[[]byte, BytesHasher] {
+		s := new(Set[[]byte, BytesHasher])
+		for _, vv := range v {
+			s.Add([]byte(vv))
+		}
+		return s
+	}
+	for _, tt := range []struct {
+		s1, s2                       []string
+		union, inter, diff, symmDiff []string
+	}{
+		{nil, nil, nil, nil, nil, nil},
+		{[]string{"a", "b"}, nil, []string{"a", "b"}, nil, []string{"a", "b"}, []string{"a", "b"}},
+		{nil, []string{"a", "b"}, []string{"a", "b"}, nil, nil, []string{"a", "b"}},
+		{[]string{"a", "b", "c"}, []string{"b", "c", "d"}, []string{"a", "b", "c", "d"}, []string{"b", "c"}, []string{"a"}, []string{"a", "d"}},
+	} {
+		s1, s2 := of(tt.s1...), of(tt.s2...)
+		check(t, Union(s1, s2), tt.union...)
+		check(t, Intersection(s1, s2), tt.inter...)
+		check(t, Difference(s1, s2), tt.diff...)
+		check(t, SymmetricDifference(s1, s2), tt.symmDiff...)
+		check(t, s1, tt.s1...)
+		check(t, s2, tt.s2...)
+
+		if got, want := s1.ContainsAny(s2), len(tt.inter) > 0; got != want {
+			t.Errorf("%v.ContainsAny(%v): got %t", s1, s2, got)
+		}
+		if got, want := s1.IsSubset(s2), len(tt.diff) == 0; got != want {
+			t.Errorf("%v.IsSubset(%v): got %t", s1, s2, got)
+		}
+		if got, want := s1.Equal(s2), len(tt.symmDiff) == 0; got != want {
+			t.Errorf("%v.Equal(%v): got %t", s1, s2, got)
+		}
+		r := s1.Clone()
+		r.RetainSet(s2)
+		check(t, r, tt.inter...)
+		x := s1.Clone()
+		x.XorSet(s2)
+		check(t, x, tt.symmDiff...)
+	}
+}
+
+func TestClearClone(t *testing.T) {
+	s := Of[[]int, SliceHasher[int]]([]int{1}, []int{1, 2})
+	c := s.Clone()
+	s.Clear()
+	if s.Len() != 0 || s.Contains([]int{1}) {
+		t.Errorf("after Clear: got %v", s)
+	}
+	if c.Len() != 2 || !c.Contains([]int{1, 2}) {
+		t.Errorf("clone: got %v", c)
+	}
+	s.Add([]int{3})
+	if c.Contains([]int{3}) {
+		t.Error("adding to the original after Clone affected the clone")
+	}
+}
+
+// lowBits is a Hasher for ints that hashes only their lowest three bits,
+// so that a set of many ints has only a few, crowded buckets.
+type lowBits struct{}
+
+func (lowBits) Hash(h *maphash.Hash, v int) { maphash.WriteComparable(h, v&7) }
+func (lowBits) Equal(a, b int) bool         { return a == b }
+
+// TestRandomCollisions checks the set operations on sets whose buckets
+// hold many elements, against a map.
+func TestRandomCollisions(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	randSet := func() (*Set[int, lowBits], map[int]bool) {
+		s, m := new(Set[int, lowBits]), make(map[int]bool)
+		for range r.IntN(100) {
+			v := r.IntN(200)
+			s.Add(v)
+			m[v] = true
+		}
+		return s, m
+	}
+	filter := func(m1, m2 map[int]bool, keep func(in1, in2 bool) bool) map[int]bool {
+		m := make(map[int]bool)
+		for v := range 200 {
+			if keep(m1[v], m2[v]) {
+				m[v] = true
+			}
+		}
+		return m
+	}
+	for range 200 {
+		s1, m1 := randSet()
+		s2, m2 := randSet()
+		checkBuckets(t, Union(s1, s2), filter(m1, m2, func(a, b bool) bool { return a || b }))
+		checkBuckets(t, Intersection(s1, s2), filter(m1, m2, func(a, b bool) bool { return a && b }))
+		checkBuckets(t, Difference(s1, s2), filter(m1, m2, func(a, b bool) bool { return a && !b }))
+		checkBuckets(t, SymmetricDifference(s1, s2), filter(m1, m2, func(a, b bool) bool { return a != b }))
+		c := s1.Clone()
+		c.RetainSet(s2)
+		checkBuckets(t, c, filter(m1, m2, func(a, b bool) bool { return a && b }))
+		c = s1.Clone()
+		c.XorSet(s2)
+		checkBuckets(t, c, filter(m1, m2, func(a, b bool) bool { return a != b }))
+		checkBuckets(t, s1, m1)
+		checkBuckets(t, s2, m2)
+
+		for range 50 {
+			v := r.IntN(200)
+			if r.IntN(2) == 0 {
+				s1.Add(v)
+				m1[v] = true
+			} else {
+				s1.Remove(v)
+				delete(m1, v)
+			}
+		}
+		checkBuckets(t, s1, m1)
+	}
+}
+
+// checkBuckets checks that s holds exactly the elements of want, that each
+// element is in the bucket for its hash, and that no bucket holds an element
+// twice.
+func checkBuckets(t *testing.T, s *Set[int, lowBits], want map[int]bool) {
+	t.Helper()
+	n := 0
+	for h, b := range s.m {
+		if len(b) == 0 {
+			t.Fatalf("empty bucket for hash %#x", h)
+		}
+		for i, v := range b {
+			if !want[v] {
+				t.Fatalf("got unexpected element %d", v)
+			}
+			if s.hash(v) != h {
+				t.Fatalf("element %d is in the wrong bucket", v)
+			}
+			if slices.Contains(b[:i], v) {
+				t.Fatalf("element %d is in its bucket twice", v)
+			}
+		}
+		n += len(b)
+	}
+	if n != len(want) || s.Len() != len(want) {
+		t.Fatalf("got %d elements in buckets and Len %d; want %d", n, s.Len(), len(want))
+	}
+}
+
+// check checks that s holds exactly the byte slices with the contents of
+// want, which must be sorted.
+func check(t *testing.T, s *Set[[]byte, BytesHasher], want ...string) {
+	t.Helper()
+	var got []string
+	for v := range s.All() {
+		got = append(got, string(v))
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	if got := s.Len(); got != len(want) {
+		t.Fatalf("Len: got %d; want %d", got, len(want))
+	}
+	for _, v := range want {
+		if !s.Contains([]byte(v)) {
+			t.Fatalf("Contains(%q): got false", v)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package lru implements a capacity-bounded cache with a least-recently-used
+// eviction policy.
+package lru
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/cespare/next/container/ordmap"
+)
+
+// A Cache is a map from keys to values that holds a bounded total weight of
+// entries. When adding an entry causes the total weight to exceed the
+// capacity, the least recently used entries are evicted until the cache is
+// back within its capacity.
+//
+// A Cache must be created with New.
+// A Cache is not safe for concurrent use by multiple goroutines.
+// Note that Get modifies the cache (it updates the recency of the entry).
+type Cache[K comparable, V any] struct {
+	// Weight optionally reports the weight of an entry.
+	// If Weight is nil, every entry has weight 1,
+	// so the capacity is the maximum number of entries.
+	// Weight must return a non-negative value.
+	// Weight should be set before the cache is used and not changed after.
+	Weight func(K, V) int
+	// OnEvict is an optional function that is called whenever an entry
+	// leaves the cache, with the reason the entry was removed.
+	// OnEvict is called after the entry has been removed.
+	// OnEvict must not modify the cache.
+	OnEvict func(K, V, EvictReason)
+
+	capacity int
+	m        *ordmap.Map[K, entry[V]] // in AccessOrder
+	weight   int
+	stats    Stats
+}
+
+type entry[V any] struct {
+	v      V
+	weight int
+}
+
+// An EvictReason describes why an entry was removed from a Cache.
+type EvictReason int
+
+const (
+	// ReasonCapacity means that the entry was evicted to keep the cache
+	// within its capacity.
+	ReasonCapacity EvictReason = iota
+	// ReasonReplaced means that the entry's value was replaced by Set.
+	ReasonReplaced
+	// ReasonDeleted means that the entry was removed by Delete or Clear.
+	ReasonDeleted
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("EvictReason(%d)", int(r))
+	}
+}
+
+// Stats are counters describing the lifetime activity of a Cache.
+type Stats struct {
+	Hits      uint64 // calls to Get that found the key
+	Misses    uint64 // calls to Get that did not find the key
+	Evictions uint64 // entries evicted because of capacity
+}
+
+// New creates a Cache with the given capacity.
+// Unless the Weight field is set, the capacity is the maximum number of entries.
+// New panics if capacity is not positive.
+func New[K comparable, V any](capacity int) *Cache[K, V] {
+	if capacity <= 0 {
+		panic("lru: non-positive capacity")
+	}
+	return &Cache[K, V]{
+		capacity: capacity,
+		m:        ordmap.New[K, entry[V]](ordmap.AccessOrder),
+	}
+}
+
+// Get returns the value stored in the cache for a key,
+// or the zero value of V if no value is present.
+// The ok result indicates whether the key was found in the cache.
+// If the key is present, it becomes the most recently used entry.
+func (c *Cache[K, V]) Get(key K) (val V, ok bool) {
+	e, ok := c.m.Get(key)
+	if !ok {
+		c.stats.Misses++
+		return val, false
+	}
+	c.stats.Hits++
+	return e.v, true
+}
+
+// Peek is like Get but it does not update the recency of the entry
+// or the hit and miss counters.
+func (c *Cache[K, V]) Peek(key K) (val V, ok bool) {
+	e, ok := c.m.Peek(key)
+	return e.v, ok
+}
+
+// Set sets the value for a key, making it the most recently used entry.
+// If the key was already present, OnEvict is called for the old value with
+// ReasonReplaced. If adding the entry causes the cache to exceed its capacity,
+// least recently used entries (possibly including the new one, if its weight
+// alone exceeds the capacity) are evicted.
+func (c *Cache[K, V]) Set(key K, val V) {
+	e := entry[V]{v: val, weight: 1}
+	if c.Weight != nil {
+		e.weight = c.Weight(key, val)
+		if e.weight < 0 {
+			panic("lru: negative weight")
+		}
+	}
+	old, replaced := c.m.Peek(key)
+	c.m.Set(key, e)
+	c.weight += e.weight
+	if replaced {
+		c.weight -= old.weight
+		c.evicted(key, old.v, ReasonReplaced)
+	}
+	for c.weight > c.capacity {
+		c.removeOldest(ReasonCapacity)
+	}
+}
+
+// Delete deletes the value for a key.
+// If the key was present, OnEvict is called with ReasonDeleted.
+// The ok result indicates whether the key was found in the cache.
+func (c *Cache[K, V]) Delete(key K) (ok bool) {
+	e, ok := c.m.Peek(key)
+	if !ok {
+		return false
+	}
+	c.m.Delete(key)
+	c.weight -= e.weight
+	c.evicted(key, e.v, ReasonDeleted)
+	return true
+}
+
+// Clear removes all entries from the cache, calling OnEvict with
+// ReasonDeleted for each of them in least to most recently used order.
+func (c *Cache[K, V]) Clear() {
+	for c.m.Len() > 0 {
+		c.removeOldest(ReasonDeleted)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache[K, V]) Len() int {
+	return c.m.Len()
+}
+
+// Capacity returns the capacity the cache was created with.
+func (c *Cache[K, V]) Capacity() int {
+	return c.capacity
+}
+
+// TotalWeight returns the sum of the weights of the entries in the cache.
+// If the Weight field is nil, this is the same as Len.
+func (c *Cache[K, V]) TotalWeight() int {
+	return c.weight
+}
+
+// Stats returns the hit, miss, and eviction counters for the cache.
+func (c *Cache[K, V]) Stats() Stats {
+	return c.stats
+}
+
+// All returns an iterator over key-value pairs in the cache,
+// from least to most recently used.
+// Iterating does not update the recency of the entries.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, e := range c.m.All() {
+			if !yield(k, e.v) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Cache[K, V]) removeOldest(reason EvictReason) {
+	key, e, _ := c.m.PopFirst()
+	c.weight -= e.weight
+	if reason == ReasonCapacity {
+		c.stats.Evictions++
+	}
+	c.evicted(key, e.v, reason)
+}
+
+func (c *Cache[K, V]) evicted(key K, val V, reason EvictReason) {
+	if c.OnEvict != nil {
+		c.OnEvict(key, val, reason)
+	}
+}
@@ -0,0 +1,144 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type eviction struct {
+	Key    string
+	Val    int
+	Reason EvictReason
+}
+
+func newRecordingCache(capacity int) (*Cache[string, int], *[]eviction) {
+	var evictions []eviction
+	c := New[string, int](capacity)
+	c.OnEvict = func(k string, v int, reason EvictReason) {
+		evictions = append(evictions, eviction{k, v, reason})
+	}
+	return c, &evictions
+}
+
+func TestCache(t *testing.T) {
+	c, evictions := newRecordingCache(3)
+
+	checkGet(t, c, "a", 0, false)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	checkKeys(t, c, "a", "b", "c")
+
+	// Get promotes; Peek does not.
+	checkGet(t, c, "a", 1, true)
+	checkKeys(t, c, "b", "c", "a")
+	if v, ok := c.Peek("b"); v != 2 || !ok {
+		t.Fatalf(`Peek("b"): got (%d, %t); want (2, true)`, v, ok)
+	}
+	checkKeys(t, c, "b", "c", "a")
+
+	c.Set("d", 4)
+	checkKeys(t, c, "c", "a", "d")
+	c.Set("c", 30)
+	checkKeys(t, c, "a", "d", "c")
+	if !c.Delete("a") {
+		t.Fatal(`Delete("a"): got false`)
+	}
+	if c.Delete("a") {
+		t.Fatal(`second Delete("a"): got true`)
+	}
+	checkKeys(t, c, "d", "c")
+	c.Clear()
+	checkKeys(t, c)
+
+	want := []eviction{
+		{"b", 2, ReasonCapacity},
+		{"c", 3, ReasonReplaced},
+		{"a", 1, ReasonDeleted},
+		{"d", 4, ReasonDeleted},
+		{"c", 30, ReasonDeleted},
+	}
+	if diff := cmp.Diff(*evictions, want); diff != "" {
+		t.Fatalf("evictions (-got, +want):\n%s", diff)
+	}
+	wantStats := Stats{Hits: 1, Misses: 1, Evictions: 1}
+	if got := c.Stats(); got != wantStats {
+		t.Fatalf("Stats: got %+v; want %+v", got, wantStats)
+	}
+}
+
+func TestCacheWeight(t *testing.T) {
+	c, evictions := newRecordingCache(10)
+	c.Weight = func(_ string, v int) int { return v }
+
+	c.Set("a", 3)
+	c.Set("b", 3)
+	c.Set("c", 3)
+	checkKeys(t, c, "a", "b", "c")
+	if got := c.TotalWeight(); got != 9 {
+		t.Fatalf("TotalWeight: got %d; want 9", got)
+	}
+	c.Set("d", 5) // evicts a and b
+	checkKeys(t, c, "c", "d")
+	c.Set("c", 1)
+	checkKeys(t, c, "d", "c")
+	if got := c.TotalWeight(); got != 6 {
+		t.Fatalf("TotalWeight: got %d; want 6", got)
+	}
+	c.Set("e", 0)
+	checkKeys(t, c, "d", "c", "e")
+	// An entry which is too heavy by itself evicts everything, including itself.
+	c.Set("f", 11)
+	checkKeys(t, c)
+	if got := c.TotalWeight(); got != 0 {
+		t.Fatalf("TotalWeight: got %d; want 0", got)
+	}
+
+	want := []eviction{
+		{"a", 3, ReasonCapacity},
+		{"b", 3, ReasonCapacity},
+		{"c", 3, ReasonReplaced},
+		{"d", 5, ReasonCapacity},
+		{"c", 1, ReasonCapacity},
+		{"e", 0, ReasonCapacity},
+		{"f", 11, ReasonCapacity},
+	}
+	if diff := cmp.Diff(*evictions, want); diff != "" {
+		t.Fatalf("evictions (-got, +want):\n%s", diff)
+	}
+	if got := c.Stats().Evictions; got != 6 {
+		t.Fatalf("Stats().Evictions: got %d; want 6", got)
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("New(0) did not panic")
+		}
+	}()
+	New[string, int](0)
+}
+
+func checkGet(t *testing.T, c *Cache[string, int], key string, want int, wantOK bool) {
+	t.Helper()
+	got, ok := c.Get(key)
+	if got != want || ok != wantOK {
+		t.Fatalf("Get(%q): got (%d, %t); want (%d, %t)", key, got, ok, want, wantOK)
+	}
+}
+
+func checkKeys(t *testing.T, c *Cache[string, int], want ...string) {
+	t.Helper()
+	var got []string
+	for k := range c.All() {
+		got = append(got, k)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("keys, least to most recently used (-got, +want):\n%s", diff)
+	}
+	if c.Len() != len(want) {
+		t.Fatalf("Len: got %d; want %d", c.Len(), len(want))
+	}
+}
@@ -0,0 +1,265 @@
+package ordmap
+
+import (
+	"fmt"
+	"iter"
+	"testing"
+)
+
+// The benchmarks in this file compare Map against listMap, a copy of the
+// original implementation of Map that used individually allocated list
+// elements linked by pointers.
+
+type benchMap interface {
+	Get(int) (int, bool)
+	Set(int, int)
+	Delete(int)
+	All() iter.Seq2[int, int]
+}
+
+var benchSizes = []int{100, 10_000, 1_000_000}
+
+var benchLayouts = []struct {
+	name   string
+	newMap func() benchMap
+}{
+	{"layout=slice", func() benchMap { return new(Map[int, int]) }},
+	{"layout=pointer", func() benchMap { return new(listMap[int, int]) }},
+}
+
+func runBench(b *testing.B, f func(b *testing.B, newMap func() benchMap, size int)) {
+	for _, size := range benchSizes {
+		for _, layout := range benchLayouts {
+			b.Run(fmt.Sprintf("size=%d/%s", size, layout.name), func(b *testing.B) {
+				f(b, layout.newMap, size)
+			})
+		}
+	}
+}
+
+func fillBenchMap(newMap func() benchMap, size int) benchMap {
+	m := newMap()
+	for i := range size {
+		m.Set(i, i)
+	}
+	return m
+}
+
+func BenchmarkSetInsert(b *testing.B) {
+	runBench(b, func(b *testing.B, newMap func() benchMap, size int) {
+		b.ReportAllocs()
+		for range b.N {
+			fillBenchMap(newMap, size)
+		}
+	})
+}
+
+func BenchmarkSetUpdate(b *testing.B) {
+	runBench(b, func(b *testing.B, newMap func() benchMap, size int) {
+		m := fillBenchMap(newMap, size)
+		b.ReportAllocs()
+		b.ResetTimer()
+		i := 0
+		for range b.N {
+			m.Set(i, i)
+			i = (i + 7919) % size
+		}
+	})
+}
+
+func BenchmarkGet(b *testing.B) {
+	runBench(b, func(b *testing.B, newMap func() benchMap, size int) {
+		m := fillBenchMap(newMap, size)
+		b.ReportAllocs()
+		b.ResetTimer()
+		i := 0
+		for range b.N {
+			m.Get(i)
+			i = (i + 7919) % size
+		}
+	})
+}
+
+func BenchmarkDelete(b *testing.B) {
+	runBench(b, func(b *testing.B, newMap func() benchMap, size int) {
+		m := fillBenchMap(newMap, size)
+		b.ReportAllocs()
+		b.ResetTimer()
+		i := 0
+		for range b.N {
+			// Delete and reinsert to keep the size steady.
+			m.Delete(i)
+			m.Set(i, i)
+			i = (i + 7919) % size
+		}
+	})
+}
+
+func BenchmarkAll(b *testing.B) {
+	runBench(b, func(b *testing.B, newMap func() benchMap, size int) {
+		m := fillBenchMap(newMap, size)
+		// Shuffle the list order so that it doesn't match allocation order.
+		for i := 0; i < size; i++ {
+			j := (i * 7919) % size
+			m.Set(j, j)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for range b.N {
+			var sum int
+			for _, v := range m.All() {
+				sum += v
+			}
+		}
+	})
+}
+
+type listMap[K comparable, V any] struct {
+	m     map[K]*listElement[K, V]
+	first *listElement[K, V]
+	last  *listElement[K, V]
+}
+
+type listElement[K comparable, V any] struct {
+	k    K
+	v    V
+	prev *listElement[K, V]
+	next *listElement[K, V]
+}
+
+func (m *listMap[K, V]) Get(key K) (val V, ok bool) {
+	if e, ok := m.m[key]; ok {
+		return e.v, true
+	}
+	return val, false
+}
+
+func (m *listMap[K, V]) Set(key K, v V) {
+	if e, ok := m.m[key]; ok {
+		e.v = v
+		m.listMoveToEnd(e)
+		return
+	}
+	if m.m == nil {
+		m.m = make(map[K]*listElement[K, V])
+	}
+	e := &listElement[K, V]{k: key, v: v}
+	m.listAppend(e)
+	m.m[key] = e
+}
+
+func (m *listMap[K, V]) Delete(key K) {
+	e, ok := m.m[key]
+	if !ok {
+		return
+	}
+	m.listDelete(e)
+	delete(m.m, key)
+}
+
+func (m *listMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := m.first; e != nil; e = e.next {
+			if !yield(e.k, e.v) {
+				return
+			}
+		}
+	}
+}
+
+func (m *listMap[K, V]) listAppend(e *listElement[K, V]) {
+	if m.first == nil {
+		m.first = e
+	} else {
+		m.last.next = e
+	}
+	e.prev = m.last
+	e.next = nil
+	m.last = e
+}
+
+func (m *listMap[K, V]) listMoveToEnd(e *listElement[K, V]) {
+	if m.last == e {
+		return
+	}
+	prev, next := e.prev, e.next
+	if prev == nil {
+		m.first = next
+	} else {
+		prev.next = next
+	}
+	next.prev = prev
+	m.last.next = e
+	e.prev = m.last
+	e.next = nil
+	m.last = e
+}
+
+func (m *listMap[K, V]) listDelete(e *listElement[K, V]) {
+	if e.prev == nil {
+		m.first = e.next
+	} else {
+		e.prev.next = e.next
+	}
+	if e.next == nil {
+		m.last = e.prev
+	} else {
+		e.next.prev = e.prev
+	}
+	e.prev = nil
+	e.next = nil
+}
+
+func BenchmarkAt(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			m := new(Map[int, int])
+			for i := range size {
+				m.Set(i, i)
+			}
+			m.At(0) // build the index
+			b.ResetTimer()
+			i := 0
+			for range b.N {
+				m.At(i)
+				i = (i + 7919) % size
+			}
+		})
+	}
+}
+
+func BenchmarkIndexOf(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			m := new(Map[int, int])
+			for i := range size {
+				m.Set(i, i)
+			}
+			m.At(0) // build the index
+			b.ResetTimer()
+			i := 0
+			for range b.N {
+				m.IndexOf(i)
+				i = (i + 7919) % size
+			}
+		})
+	}
+}
+
+func BenchmarkSetUpdateIndexed(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			m := new(Map[int, int])
+			for i := range size {
+				m.Set(i, i)
+			}
+			m.At(0) // build the index
+			b.ResetTimer()
+			i := 0
+			for range b.N {
+				m.Set(i, i)
+				i = (i + 7919) % size
+			}
+		})
+	}
+}
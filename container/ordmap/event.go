@@ -0,0 +1,52 @@
+package ordmap
+
+import (
+	"strconv"
+	"strings"
+)
+
+// An Event describes a change to a Map. See Map.OnChange.
+type Event[K comparable, V any] struct {
+	Kind EventKind
+	Key  K
+	// Value is the key's value after the change,
+	// or its last value if the key was deleted.
+	Value V
+}
+
+// An EventKind describes what happened to the key of an Event.
+// It is a set of flags: in particular, a call to Set that both updates the
+// value of a key and moves it to the end of the map has the kind
+// EventUpdated|EventMoved.
+type EventKind uint8
+
+const (
+	// EventInserted means that the key was added to the map.
+	EventInserted EventKind = 1 << iota
+	// EventUpdated means that the key's value was set.
+	EventUpdated
+	// EventMoved means that the key was moved to a new position.
+	// The key may end up in the same position if it was moved to where it
+	// already was (for example, by calling MoveToBack on the last key).
+	EventMoved
+	// EventDeleted means that the key was removed from the map.
+	EventDeleted
+)
+
+var eventKindNames = []string{"Inserted", "Updated", "Moved", "Deleted"}
+
+func (k EventKind) String() string {
+	if k == 0 {
+		return "0"
+	}
+	var names []string
+	for i, name := range eventKindNames {
+		if k&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if k>>len(eventKindNames) != 0 {
+		names = append(names, "EventKind("+strconv.Itoa(int(k&^(1<<len(eventKindNames)-1)))+")")
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,167 @@
+package ordmap
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newRecordingMap(order Order) (*Map[string, int], *[]Event[string, int]) {
+	var events []Event[string, int]
+	m := New[string, int](order)
+	m.OnChange = func(e Event[string, int]) {
+		events = append(events, e)
+	}
+	return m, &events
+}
+
+func TestEvents(t *testing.T) {
+	m, events := newRecordingMap(AccessOrder)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("c", 3)
+	m.Set("a", 4)
+	m.Get("b")
+	m.Get("b") // already last: no event
+	m.Get("x")
+	m.Peek("c")
+	m.MoveToFront("c")
+	m.MoveBefore("a", "a")
+	m.MoveAfter("c", "b")
+	m.SetBefore("d", 5, "a")
+	m.SetAfter("d", 6, "d")
+	m.SetAfter("a", 7, "c")
+	m.Delete("b")
+	m.Delete("x")
+	m.PopFirst()
+	checkAll(t, m, []keyVal[string, int]{{"c", 3}, {"a", 7}})
+	m.Clear()
+
+	want := []Event[string, int]{
+		{EventInserted, "a", 1},
+		{EventInserted, "b", 2},
+		{EventInserted, "c", 3},
+		{EventUpdated | EventMoved, "a", 4},
+		{EventMoved, "b", 2},
+		{EventMoved, "c", 3},
+		{EventMoved, "c", 3},
+		{EventInserted, "d", 5},
+		{EventUpdated, "d", 6},
+		{EventUpdated | EventMoved, "a", 7},
+		{EventDeleted, "b", 2},
+		{EventDeleted, "d", 6},
+		{EventDeleted, "c", 3},
+		{EventDeleted, "a", 7},
+	}
+	if diff := cmp.Diff(*events, want); diff != "" {
+		t.Fatalf("events (-got, +want):\n%s", diff)
+	}
+}
+
+func TestEventsInsertOrder(t *testing.T) {
+	m, events := newRecordingMap(InsertOrder)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("a", 3)
+	m.Get("a")
+	want := []Event[string, int]{
+		{EventInserted, "a", 1},
+		{EventInserted, "b", 2},
+		{EventUpdated, "a", 3},
+	}
+	if diff := cmp.Diff(*events, want); diff != "" {
+		t.Fatalf("events (-got, +want):\n%s", diff)
+	}
+}
+
+func TestEventsReadMap(t *testing.T) {
+	// OnChange may read the map; it observes the state after the change.
+	m := new(Map[string, int])
+	var positions []int
+	m.OnChange = func(e Event[string, int]) {
+		positions = append(positions, m.IndexOf(e.Key))
+	}
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.MoveToFront("b")
+	m.Delete("a")
+	if diff := cmp.Diff(positions, []int{0, 1, 0, -1}); diff != "" {
+		t.Fatalf("positions (-got, +want):\n%s", diff)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	m := New[string, int](AccessOrder)
+	v := m.Version()
+	check := func(desc string, wantChange bool) {
+		t.Helper()
+		v1 := m.Version()
+		if changed := v1 != v; changed != wantChange {
+			t.Fatalf("after %s: version changed = %t; want %t", desc, changed, wantChange)
+		}
+		if v1 < v {
+			t.Fatalf("after %s: version decreased from %d to %d", desc, v, v1)
+		}
+		v = v1
+	}
+	m.Clear()
+	check("Clear of empty map", false)
+	m.Set("a", 1)
+	check("Set", true)
+	m.Set("b", 1)
+	check("Set", true)
+	m.Peek("a")
+	check("Peek", false)
+	m.Get("x")
+	check("Get of missing key", false)
+	m.Get("a")
+	check("Get", true)
+	m.Get("a")
+	check("Get of last key", false)
+	for range m.All() {
+	}
+	check("All", false)
+	m.MoveToFront("x")
+	check("MoveToFront of missing key", false)
+	m.MoveToFront("a")
+	check("MoveToFront", true)
+	m.Delete("x")
+	check("Delete of missing key", false)
+	m.Delete("a")
+	check("Delete", true)
+	m.Clear()
+	check("Clear", true)
+
+	// Fail fast on modification during iteration.
+	m.Set("a", 1)
+	m.Set("b", 2)
+	v = m.Version()
+	modified := false
+	for k := range m.Keys() {
+		if m.Version() != v {
+			modified = true
+			break
+		}
+		m.Set(k+k, 0)
+	}
+	if !modified {
+		t.Fatal("modification during iteration was not detected")
+	}
+}
+
+func TestEventKindString(t *testing.T) {
+	for _, tt := range []struct {
+		k    EventKind
+		want string
+	}{
+		{0, "0"},
+		{EventInserted, "Inserted"},
+		{EventUpdated | EventMoved, "Updated|Moved"},
+		{EventDeleted, "Deleted"},
+		{EventMoved | 1<<6, "Moved|EventKind(64)"},
+	} {
+		if got := tt.k.String(); got != tt.want {
+			t.Errorf("EventKind(%d).String(): got %q; want %q", uint8(tt.k), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package ordmap
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// FuzzMap decodes its input into a sequence of operations on a Map and checks
+// the results against modelMap, a simple slice-based implementation.
+// After each operation it checks the map's contents in both directions and
+// the invariants of its internal representation.
+//
+// The first byte of the input selects the Order. Each operation is
+// encoded as three bytes: an opcode, a key, and an argument (a value or
+// another key).
+func FuzzMap(f *testing.F) {
+	f.Add([]byte{0, opSet, 1, 1, opSet, 2, 2, opSet, 1, 3, opDelete, 2, 0})
+	f.Add([]byte{1, opSet, 1, 1, opSet, 2, 2, opSetBefore, 3, 1, opMoveAfter, 1, 3, opPopFirst, 0, 0})
+	f.Add([]byte{2, opSet, 1, 1, opSet, 2, 2, opGet, 1, 0, opAt, 1, 0, opMoveToFront, 2, 0, opClear, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		order := Order(data[0] % 3)
+		m := New[uint8, uint8](order)
+		model := &modelMap{order: order}
+		data = data[1:]
+		for step := 0; len(data) >= 3; step++ {
+			op, k, arg := data[0]%numOps, data[1]%16, data[2]
+			data = data[3:]
+			desc := fmt.Sprintf("step %d: %s(%d, %d)", step, opNames[op], k, arg)
+			applyOp(t, desc, m, model, op, k, arg)
+			checkModel(t, desc, m, model)
+			checkInvariants(t, desc, m)
+		}
+	})
+}
+
+const (
+	opSet = iota
+	opGet
+	opPeek
+	opDelete
+	opMoveToFront
+	opMoveToBack
+	opMoveBefore
+	opMoveAfter
+	opSetBefore
+	opSetAfter
+	opPopFirst
+	opPopLast
+	opClear
+	opAt
+	opDeleteDuringIteration
+	numOps
+)
+
+var opNames = [numOps]string{
+	"Set", "Get", "Peek", "Delete",
+	"MoveToFront", "MoveToBack", "MoveBefore", "MoveAfter",
+	"SetBefore", "SetAfter", "PopFirst", "PopLast", "Clear", "At",
+	"DeleteDuringIteration",
+}
+
+func applyOp(t *testing.T, desc string, m *Map[uint8, uint8], model *modelMap, op, k, arg uint8) {
+	mark := arg % 16
+	switch op {
+	case opSet:
+		m.Set(k, arg)
+		model.set(k, arg)
+	case opGet:
+		v, ok := m.Get(k)
+		v1, ok1 := model.get(k)
+		if v != v1 || ok != ok1 {
+			t.Fatalf("%s: got (%d, %t); want (%d, %t)", desc, v, ok, v1, ok1)
+		}
+	case opPeek:
+		v, ok := m.Peek(k)
+		v1, ok1 := model.peek(k)
+		if v != v1 || ok != ok1 {
+			t.Fatalf("%s: got (%d, %t); want (%d, %t)", desc, v, ok, v1, ok1)
+		}
+	case opDelete:
+		m.Delete(k)
+		model.delete(k)
+	case opMoveToFront, opMoveToBack:
+		var ok bool
+		if op == opMoveToFront {
+			ok = m.MoveToFront(k)
+		} else {
+			ok = m.MoveToBack(k)
+		}
+		i := model.index(k)
+		if ok != (i >= 0) {
+			t.Fatalf("%s: got %t", desc, ok)
+		}
+		if ok {
+			kv := model.kvs[i]
+			model.kvs = slices.Delete(model.kvs, i, i+1)
+			if op == opMoveToFront {
+				model.kvs = slices.Insert(model.kvs, 0, kv)
+			} else {
+				model.kvs = append(model.kvs, kv)
+			}
+		}
+	case opMoveBefore, opMoveAfter:
+		var ok bool
+		if op == opMoveBefore {
+			ok = m.MoveBefore(k, mark)
+		} else {
+			ok = m.MoveAfter(k, mark)
+		}
+		i, j := model.index(k), model.index(mark)
+		if ok != (i >= 0 && j >= 0) {
+			t.Fatalf("%s: got %t", desc, ok)
+		}
+		if ok && i != j {
+			kv := model.kvs[i]
+			model.kvs = slices.Delete(model.kvs, i, i+1)
+			model.insertAt(kv, mark, op == opMoveAfter)
+		}
+	case opSetBefore, opSetAfter:
+		var ok bool
+		if op == opSetBefore {
+			ok = m.SetBefore(k, arg, mark)
+		} else {
+			ok = m.SetAfter(k, arg, mark)
+		}
+		i, j := model.index(k), model.index(mark)
+		if ok != (j >= 0) {
+			t.Fatalf("%s: got %t", desc, ok)
+		}
+		switch {
+		case !ok:
+		case i == j:
+			model.kvs[i].Val = arg
+		default:
+			if i >= 0 {
+				model.kvs = slices.Delete(model.kvs, i, i+1)
+			}
+			model.insertAt(keyVal[uint8, uint8]{k, arg}, mark, op == opSetAfter)
+		}
+	case opPopFirst, opPopLast:
+		var k, v uint8
+		var ok bool
+		if op == opPopFirst {
+			k, v, ok = m.PopFirst()
+		} else {
+			k, v, ok = m.PopLast()
+		}
+		if ok != (len(model.kvs) > 0) {
+			t.Fatalf("%s: got ok=%t", desc, ok)
+		}
+		if !ok {
+			return
+		}
+		i := 0
+		if op == opPopLast {
+			i = len(model.kvs) - 1
+		}
+		if want := model.kvs[i]; k != want.Key || v != want.Val {
+			t.Fatalf("%s: got (%d, %d); want (%d, %d)", desc, k, v, want.Key, want.Val)
+		}
+		model.kvs = slices.Delete(model.kvs, i, i+1)
+	case opClear:
+		m.Clear()
+		model.kvs = nil
+	case opAt:
+		if len(model.kvs) == 0 {
+			return
+		}
+		i := int(arg) % len(model.kvs)
+		k, v := m.At(i)
+		if want := model.kvs[i]; k != want.Key || v != want.Val {
+			t.Fatalf("%s: At(%d): got (%d, %d); want (%d, %d)", desc, i, k, v, want.Key, want.Val)
+		}
+		for i, kv := range model.kvs {
+			if got := m.IndexOf(kv.Key); got != i {
+				t.Fatalf("%s: IndexOf(%d): got %d; want %d", desc, kv.Key, got, i)
+			}
+		}
+	case opDeleteDuringIteration:
+		// Iterate, deleting each entry whose value has the bit selected by k,
+		// along with the entry following it, and setting another key.
+		// The iteration must produce the original entries, in order,
+		// except those deleted or moved before they are reached.
+		want := slices.Clone(model.kvs)
+		var got []keyVal[uint8, uint8]
+		reached := func(k uint8) bool {
+			return slices.ContainsFunc(got, func(kv keyVal[uint8, uint8]) bool { return kv.Key == k })
+		}
+		omit := func(k uint8) {
+			if !reached(k) {
+				want = slices.DeleteFunc(want, func(kv keyVal[uint8, uint8]) bool { return kv.Key == k })
+			}
+		}
+		bit := uint8(1) << (k % 8)
+		for k1, v1 := range m.All() {
+			got = append(got, keyVal[uint8, uint8]{k1, v1})
+			if v1&bit == 0 {
+				continue
+			}
+			i := model.index(k1)
+			m.Delete(k1)
+			model.delete(k1)
+			if i < len(model.kvs) {
+				next := model.kvs[i].Key
+				m.Delete(next)
+				model.delete(next)
+				omit(next)
+			}
+			k2 := 16 + k1
+			if !reached(k2) && model.index(k2) >= 0 {
+				if model.order == InsertOrder {
+					// The value change is observed.
+					if i := slices.IndexFunc(want, func(kv keyVal[uint8, uint8]) bool { return kv.Key == k2 }); i >= 0 {
+						want[i].Val = v1
+					}
+				} else {
+					omit(k2)
+				}
+			}
+			m.Set(k2, v1)
+			model.set(k2, v1)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("%s: iteration gave %v; want %v", desc, got, want)
+		}
+	default:
+		panic("unreachable")
+	}
+}
+
+// A modelMap is a simple, slow implementation of Map for testing.
+type modelMap struct {
+	order Order
+	kvs   []keyVal[uint8, uint8]
+}
+
+func (m *modelMap) index(k uint8) int {
+	return slices.IndexFunc(m.kvs, func(kv keyVal[uint8, uint8]) bool { return kv.Key == k })
+}
+
+func (m *modelMap) get(k uint8) (uint8, bool) {
+	i := m.index(k)
+	if i < 0 {
+		return 0, false
+	}
+	kv := m.kvs[i]
+	if m.order == AccessOrder {
+		m.kvs = append(slices.Delete(m.kvs, i, i+1), kv)
+	}
+	return kv.Val, true
+}
+
+func (m *modelMap) peek(k uint8) (uint8, bool) {
+	i := m.index(k)
+	if i < 0 {
+		return 0, false
+	}
+	return m.kvs[i].Val, true
+}
+
+func (m *modelMap) set(k, v uint8) {
+	i := m.index(k)
+	switch {
+	case i < 0:
+		m.kvs = append(m.kvs, keyVal[uint8, uint8]{k, v})
+	case m.order == InsertOrder:
+		m.kvs[i].Val = v
+	default:
+		m.kvs = append(slices.Delete(m.kvs, i, i+1), keyVal[uint8, uint8]{k, v})
+	}
+}
+
+func (m *modelMap) delete(k uint8) {
+	if i := m.index(k); i >= 0 {
+		m.kvs = slices.Delete(m.kvs, i, i+1)
+	}
+}
+
+// insertAt inserts kv before (or after) the entry with key mark.
+func (m *modelMap) insertAt(kv keyVal[uint8, uint8], mark uint8, after bool) {
+	j := m.index(mark)
+	if after {
+		j++
+	}
+	m.kvs = slices.Insert(m.kvs, j, kv)
+}
+
+func checkModel(t *testing.T, desc string, m *Map[uint8, uint8], model *modelMap) {
+	t.Helper()
+	if got := collectKVs(m.All()); !slices.Equal(got, model.kvs) {
+		t.Fatalf("%s: All gave %v; want %v", desc, got, model.kvs)
+	}
+	backward := collectKVs(m.Backward())
+	slices.Reverse(backward)
+	if !slices.Equal(backward, model.kvs) {
+		t.Fatalf("%s: Backward (reversed) gave %v; want %v", desc, backward, model.kvs)
+	}
+	if got := m.Len(); got != len(model.kvs) {
+		t.Fatalf("%s: Len: got %d; want %d", desc, got, len(model.kvs))
+	}
+	for k := range uint8(32) {
+		v, ok := m.Peek(k)
+		v1, ok1 := model.peek(k)
+		if v != v1 || ok != ok1 {
+			t.Fatalf("%s: Peek(%d): got (%d, %t); want (%d, %t)", desc, k, v, ok, v1, ok1)
+		}
+	}
+}
+
+// checkInvariants checks the internal consistency of m's representation.
+func checkInvariants[K comparable, V any](t *testing.T, desc string, m *Map[K, V]) {
+	t.Helper()
+	checkList(t, desc, &m.entries, len(m.m))
+	n := 0
+	for i := m.entries.first(); i != 0; i = m.entries.slots[i].next {
+		e := m.entries.at(i)
+		if j, ok := m.m[e.k]; !ok || j != i {
+			t.Fatalf("%s: key %v of entry %d maps to (%d, %t)", desc, e.k, i, j, ok)
+		}
+		if m.idx != nil {
+			if r := m.idx.rank(i); r != n {
+				t.Fatalf("%s: index gives entry %d rank %d; want %d", desc, i, r, n)
+			}
+		}
+		n++
+	}
+	if m.idx != nil && int(m.idx.nodes[m.idx.root].size) != n {
+		t.Fatalf("%s: index has size %d; want %d", desc, m.idx.nodes[m.idx.root].size, n)
+	}
+}
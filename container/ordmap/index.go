@@ -0,0 +1,262 @@
+package ordmap
+
+import (
+	"fmt"
+	"iter"
+	"math/rand/v2"
+)
+
+// At returns the key and value of the entry at position i in the map
+// ordering, where the first entry is at position 0.
+// At panics if i is negative or is not less than the number of entries.
+//
+// The first call to At, IndexOf, or Range builds an index of the map's
+// positions in O(n) time. From then on, the map keeps the index up to date,
+// which adds O(log n) time to each operation that inserts, moves, or deletes
+// an entry, and At, IndexOf, and Range take O(log n) time.
+func (m *Map[K, V]) At(i int) (key K, val V) {
+	if i < 0 || i >= len(m.m) {
+		panic(fmt.Sprintf("ordmap: index %d out of range [0:%d]", i, len(m.m)))
+	}
+	e := m.entries.at(m.index().sel(i))
+	return e.k, e.v
+}
+
+// IndexOf returns the position of key in the map ordering,
+// or -1 if key is not present.
+// See At for the time complexity.
+func (m *Map[K, V]) IndexOf(key K) int {
+	i, ok := m.m[key]
+	if !ok {
+		return -1
+	}
+	return m.index().rank(i)
+}
+
+// Range returns an iterator over the key-value pairs at positions
+// i, i+1, ..., j-1 in the map ordering.
+// The positions are evaluated when iteration begins;
+// at that time, Range panics unless 0 <= i <= j <= n,
+// where n is the number of entries in the map.
+// If the map is modified during iteration, the iterator still produces
+// at most j-i entries.
+// See At for the time complexity.
+func (m *Map[K, V]) Range(i, j int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if i < 0 || j < i || j > len(m.m) {
+			panic(fmt.Sprintf("ordmap: range [%d:%d] out of range [0:%d]", i, j, len(m.m)))
+		}
+		if i == j {
+			return
+		}
+		n := j - i
+		m.all(m.index().sel(i), false, func(k K, v V) bool {
+			n--
+			return yield(k, v) && n > 0
+		})
+	}
+}
+
+// index returns m.idx, building it first if necessary.
+func (m *Map[K, V]) index() *index {
+	if m.idx == nil {
+		m.idx = newIndex(len(m.entries.slots), func(yield func(int32) bool) {
+			for i := m.entries.first(); i != 0; i = m.entries.slots[i].next {
+				if !yield(i) {
+					return
+				}
+			}
+		})
+	}
+	return m.idx
+}
+
+// An index is an order-statistic tree over the entries of a Map: a treap
+// whose in-order traversal matches the list ordering and in which each node
+// records the size of its subtree. The nodes are stored in a slice parallel
+// to the slots of Map.entries (a node and its entry share an index), and,
+// as in the list, index 0 is reserved to mean "none".
+type index struct {
+	nodes []node
+	root  int32
+}
+
+type node struct {
+	left   int32
+	right  int32
+	parent int32
+	size   int32 // number of nodes in the subtree rooted here
+	prio   uint32
+}
+
+// newIndex builds an index holding the entries produced by list, in order.
+// The index has room for n slots.
+func newIndex(n int, list iter.Seq[int32]) *index {
+	x := &index{nodes: make([]node, n)}
+	// Build the treap as a Cartesian tree using a stack holding the right
+	// spine of the tree built so far. A node's subtree is complete
+	// once it is popped from the stack.
+	var stack []int32
+	for i := range list {
+		nd := &x.nodes[i]
+		*nd = node{prio: rand.Uint32()}
+		var last int32
+		for len(stack) > 0 && x.nodes[stack[len(stack)-1]].prio < nd.prio {
+			last = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x.resize(last)
+		}
+		nd.left = last
+		if last != 0 {
+			x.nodes[last].parent = i
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			x.nodes[top].right = i
+			nd.parent = top
+		}
+		stack = append(stack, i)
+	}
+	if len(stack) > 0 {
+		x.root = stack[0]
+	}
+	for len(stack) > 0 {
+		x.resize(stack[len(stack)-1])
+		stack = stack[:len(stack)-1]
+	}
+	return x
+}
+
+func (x *index) resize(i int32) {
+	nd := &x.nodes[i]
+	nd.size = x.nodes[nd.left].size + x.nodes[nd.right].size + 1
+}
+
+// insert adds node i to the tree. The position of i is given by its
+// neighbors in the list, prev and next, either of which may be 0.
+func (x *index) insert(i, prev, next int32) {
+	if n := int(i) + 1; n > len(x.nodes) {
+		x.nodes = append(x.nodes, make([]node, n-len(x.nodes))...)
+	}
+	x.nodes[i] = node{size: 1, prio: rand.Uint32()}
+	if x.root == 0 {
+		x.root = i
+		return
+	}
+	// Attach i as a leaf: either as the right child of its predecessor or,
+	// if that spot is taken, as the left child of its successor (which is
+	// then the leftmost node of the predecessor's right subtree).
+	var p int32
+	if prev != 0 && x.nodes[prev].right == 0 {
+		p = prev
+		x.nodes[p].right = i
+	} else {
+		p = next
+		x.nodes[p].left = i
+	}
+	x.nodes[i].parent = p
+	for q := p; q != 0; q = x.nodes[q].parent {
+		x.nodes[q].size++
+	}
+	for p := x.nodes[i].parent; p != 0 && x.nodes[p].prio < x.nodes[i].prio; p = x.nodes[i].parent {
+		x.rotateUp(i)
+	}
+}
+
+// remove removes node i from the tree.
+func (x *index) remove(i int32) {
+	// Rotate i down until it has at most one child.
+	for {
+		nd := &x.nodes[i]
+		if nd.left == 0 || nd.right == 0 {
+			break
+		}
+		c := nd.left
+		if x.nodes[nd.right].prio > x.nodes[c].prio {
+			c = nd.right
+		}
+		x.rotateUp(c)
+	}
+	nd := x.nodes[i]
+	c := nd.left
+	if c == 0 {
+		c = nd.right
+	}
+	if c != 0 {
+		x.nodes[c].parent = nd.parent
+	}
+	x.replaceChild(nd.parent, i, c)
+	for q := nd.parent; q != 0; q = x.nodes[q].parent {
+		x.nodes[q].size--
+	}
+	x.nodes[i] = node{}
+}
+
+// rotateUp rotates node i above its parent.
+func (x *index) rotateUp(i int32) {
+	p := x.nodes[i].parent
+	g := x.nodes[p].parent
+	if x.nodes[p].left == i {
+		c := x.nodes[i].right
+		x.nodes[p].left = c
+		if c != 0 {
+			x.nodes[c].parent = p
+		}
+		x.nodes[i].right = p
+	} else {
+		c := x.nodes[i].left
+		x.nodes[p].right = c
+		if c != 0 {
+			x.nodes[c].parent = p
+		}
+		x.nodes[i].left = p
+	}
+	x.nodes[p].parent = i
+	x.nodes[i].parent = g
+	x.replaceChild(g, p, i)
+	x.resize(p)
+	x.resize(i)
+}
+
+// replaceChild replaces the child old of node p with child.
+// If p is 0, old is the root.
+func (x *index) replaceChild(p, old, child int32) {
+	switch {
+	case p == 0:
+		x.root = child
+	case x.nodes[p].left == old:
+		x.nodes[p].left = child
+	default:
+		x.nodes[p].right = child
+	}
+}
+
+// rank returns the position of node i in the ordering.
+func (x *index) rank(i int32) int {
+	r := int(x.nodes[x.nodes[i].left].size)
+	for p := x.nodes[i].parent; p != 0; i, p = p, x.nodes[p].parent {
+		if x.nodes[p].right == i {
+			r += int(x.nodes[x.nodes[p].left].size) + 1
+		}
+	}
+	return r
+}
+
+// sel returns the node at position r in the ordering,
+// which must be in range.
+func (x *index) sel(r int) int32 {
+	i := x.root
+	for {
+		nd := &x.nodes[i]
+		ls := int(x.nodes[nd.left].size)
+		switch {
+		case r < ls:
+			i = nd.left
+		case r == ls:
+			return i
+		default:
+			r -= ls + 1
+			i = nd.right
+		}
+	}
+}
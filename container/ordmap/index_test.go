@@ -0,0 +1,187 @@
+package ordmap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAtIndexOf(t *testing.T) {
+	m := new(Map[string, int])
+	if got := m.IndexOf("a"); got != -1 {
+		t.Fatalf(`IndexOf("a") on empty map: got %d; want -1`, got)
+	}
+	for i, k := range []string{"a", "b", "c", "d"} {
+		m.Set(k, i)
+	}
+	checkPositions(t, m, []string{"a", "b", "c", "d"})
+	m.Set("a", 10)
+	checkPositions(t, m, []string{"b", "c", "d", "a"})
+	m.Delete("c")
+	checkPositions(t, m, []string{"b", "d", "a"})
+	m.SetBefore("x", 0, "b")
+	m.MoveAfter("b", "a")
+	checkPositions(t, m, []string{"x", "d", "a", "b"})
+	if got := m.IndexOf("c"); got != -1 {
+		t.Fatalf(`IndexOf("c"): got %d; want -1`, got)
+	}
+	if k, v := m.At(2); k != "a" || v != 10 {
+		t.Fatalf("At(2): got (%q, %d); want (\"a\", 10)", k, v)
+	}
+}
+
+func TestAtPanics(t *testing.T) {
+	m := new(Map[string, int])
+	m.Set("a", 1)
+	for _, i := range []int{-1, 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("At(%d) did not panic", i)
+				}
+			}()
+			m.At(i)
+		}()
+	}
+}
+
+func TestRange(t *testing.T) {
+	m := new(Map[string, int])
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		m.Set(k, i)
+	}
+	for _, tt := range []struct {
+		i, j int
+		want []keyVal[string, int]
+	}{
+		{0, 0, nil},
+		{5, 5, nil},
+		{0, 5, []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4}}},
+		{1, 3, []keyVal[string, int]{{"b", 1}, {"c", 2}}},
+		{4, 5, []keyVal[string, int]{{"e", 4}}},
+	} {
+		if diff := cmp.Diff(collectKVs(m.Range(tt.i, tt.j)), tt.want); diff != "" {
+			t.Errorf("Range(%d, %d) gave incorrect sequence (-got, +want):\n%s", tt.i, tt.j, diff)
+		}
+	}
+
+	// Break out early.
+	for k := range m.Range(1, 4) {
+		if k != "b" {
+			t.Fatalf("first key from Range(1, 4): got %q", k)
+		}
+		break
+	}
+
+	// Modification during iteration.
+	var got []string
+	for k := range m.Range(1, 4) {
+		got = append(got, k)
+		if k == "b" {
+			m.Delete("c")
+		}
+	}
+	if diff := cmp.Diff(got, []string{"b", "d", "e"}); diff != "" {
+		t.Errorf("Range(1, 4) with deletion gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+
+	for _, r := range [][2]int{{-1, 2}, {2, 1}, {0, 5}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Range(%d, %d) did not panic", r[0], r[1])
+				}
+			}()
+			for range m.Range(r[0], r[1]) {
+			}
+		}()
+	}
+}
+
+func TestIndexRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	m := new(Map[int, int])
+	var model []int // keys in order
+	for op := range 20000 {
+		k := r.IntN(500)
+		i := slices.Index(model, k)
+		switch r.IntN(6) {
+		case 0, 1:
+			m.Set(k, k)
+			if i >= 0 {
+				model = slices.Delete(model, i, i+1)
+			}
+			model = append(model, k)
+		case 2:
+			m.Delete(k)
+			if i >= 0 {
+				model = slices.Delete(model, i, i+1)
+			}
+		case 3:
+			if m.MoveToFront(k) {
+				model = slices.Delete(model, i, i+1)
+				model = slices.Insert(model, 0, k)
+			}
+		case 4:
+			if len(model) == 0 {
+				continue
+			}
+			mark := model[r.IntN(len(model))]
+			if m.SetBefore(k, k, mark) && k != mark {
+				if i >= 0 {
+					model = slices.Delete(model, i, i+1)
+				}
+				model = slices.Insert(model, slices.Index(model, mark), k)
+			}
+		case 5:
+			if len(model) == 0 {
+				continue
+			}
+			mark := model[r.IntN(len(model))]
+			if m.MoveAfter(k, mark) && k != mark {
+				model = slices.Delete(model, i, i+1)
+				model = slices.Insert(model, slices.Index(model, mark)+1, k)
+			}
+		}
+		if op%5000 == 4999 {
+			// Delete most entries, which causes the map to compact itself
+			// and rebuild the index.
+			model = slices.DeleteFunc(model, func(k int) bool {
+				if k%8 == 0 {
+					return false
+				}
+				m.Delete(k)
+				return true
+			})
+		}
+		if op%100 == 0 {
+			checkPositions(t, m, model)
+		} else if len(model) > 0 {
+			// Spot check.
+			j := r.IntN(len(model))
+			if got := m.IndexOf(model[j]); got != j {
+				t.Fatalf("after op %d: IndexOf(%d): got %d; want %d", op, model[j], got, j)
+			}
+			if got, _ := m.At(j); got != model[j] {
+				t.Fatalf("after op %d: At(%d): got %d; want %d", op, j, got, model[j])
+			}
+		}
+	}
+}
+
+func checkPositions[K comparable, V any](t *testing.T, m *Map[K, V], keys []K) {
+	t.Helper()
+	for i, k := range keys {
+		if got := m.IndexOf(k); got != i {
+			t.Fatalf("IndexOf(%v): got %d; want %d", k, got, i)
+		}
+		if got, _ := m.At(i); got != k {
+			t.Fatalf("At(%d): got key %v; want %v", i, got, k)
+		}
+	}
+	if got := slices.Collect(m.Keys()); !slices.Equal(got, keys) {
+		t.Fatalf("Keys: got %v; want %v", got, keys)
+	}
+}
@@ -0,0 +1,160 @@
+package ordmap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// MarshalJSON implements json.Marshaler.
+// The map is encoded as a JSON object whose members appear in the map
+// ordering. Values are encoded using json.Marshal.
+//
+// Keys are encoded following the same rules as encoding/json uses for Go
+// maps: keys of any string type are used directly, keys that implement
+// encoding.TextMarshaler are marshaled, and integer keys are formatted in
+// base 10. MarshalJSON returns an error for any other key type.
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for k, v := range m.All() {
+		ks, err := marshalKey(k)
+		if err != nil {
+			return nil, err
+		}
+		kb, err := json.Marshal(ks)
+		if err != nil {
+			return nil, err
+		}
+		vb, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// The data must be a JSON object or null. The members of the object are
+// added to the map by calling Set in the order they appear in the document,
+// so a key that appears more than once takes its last value and is positioned
+// according to the map's Order. Entries already in the map are retained
+// (as with encoding/json and Go maps). A JSON null leaves the map unchanged.
+//
+// Values are decoded using encoding/json. Keys are decoded following the same
+// rules as encoding/json uses for Go maps: keys whose pointer type implements
+// encoding.TextUnmarshaler are unmarshaled, keys of any string type are used
+// directly, and integer keys are parsed in base 10.
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("ordmap: cannot unmarshal JSON %s into %T", describeToken(tok), m)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		ks := tok.(string) // object keys are always strings
+		k, err := unmarshalKey[K](ks)
+		if err != nil {
+			return err
+		}
+		var v V
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		m.Set(k, v)
+	}
+	if _, err := dec.Token(); err != nil { // closing '}'
+		return err
+	}
+	return nil
+}
+
+func describeToken(tok json.Token) string {
+	switch tok.(type) {
+	case json.Delim:
+		return "array"
+	case bool:
+		return "bool"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprint(tok)
+	}
+}
+
+func marshalKey[K comparable](k K) (string, error) {
+	rv := reflect.ValueOf(&k).Elem()
+	if rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	if tm, ok := any(k).(encoding.TextMarshaler); ok {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("ordmap: unsupported key type %s for JSON", rv.Type())
+}
+
+func unmarshalKey[K comparable](s string) (K, error) {
+	var k K
+	rv := reflect.ValueOf(&k).Elem()
+	if tu, ok := any(&k).(encoding.TextUnmarshaler); ok {
+		err := tu.UnmarshalText([]byte(s))
+		return k, err
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+		return k, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return k, fmt.Errorf("ordmap: cannot unmarshal JSON key %q into %s", s, rv.Type())
+		}
+		rv.SetInt(n)
+		return k, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return k, fmt.Errorf("ordmap: cannot unmarshal JSON key %q into %s", s, rv.Type())
+		}
+		rv.SetUint(n)
+		return k, nil
+	}
+	return k, fmt.Errorf("ordmap: unsupported key type %s for JSON", rv.Type())
+}
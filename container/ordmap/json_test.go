@@ -0,0 +1,209 @@
+package ordmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	m := new(Map[string, int])
+	checkMarshalJSON(t, m, `{}`)
+	m.Set("z", 1)
+	m.Set("a", 2)
+	m.Set("m", 3)
+	checkMarshalJSON(t, m, `{"z":1,"a":2,"m":3}`)
+	m.Set("z", 4)
+	checkMarshalJSON(t, m, `{"a":2,"m":3,"z":4}`)
+
+	// As a nil pointer.
+	var nilMap *Map[string, int]
+	checkMarshalJSON(t, nilMap, `null`)
+
+	// Nested ordered maps.
+	outer := new(Map[string, *Map[string, int]])
+	inner := new(Map[string, int])
+	inner.Set("y", 1)
+	inner.Set("x", 2)
+	outer.Set("b", inner)
+	outer.Set("a", new(Map[string, int]))
+	checkMarshalJSON(t, outer, `{"b":{"y":1,"x":2},"a":{}}`)
+
+	// Special characters in keys.
+	m2 := new(Map[string, string])
+	m2.Set(`"<k>"`, "v")
+	checkMarshalJSON(t, m2, `{"\"\u003ck\u003e\"":"v"}`)
+}
+
+type myString string
+
+// point is a key type that implements encoding.TextMarshaler and
+// encoding.TextUnmarshaler.
+type point struct {
+	X, Y int
+}
+
+func (p point) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "%d,%d", p.X, p.Y), nil
+}
+
+func (p *point) UnmarshalText(b []byte) error {
+	_, err := fmt.Sscanf(string(b), "%d,%d", &p.X, &p.Y)
+	return err
+}
+
+func TestMarshalJSONKeys(t *testing.T) {
+	m1 := new(Map[myString, bool])
+	m1.Set("b", true)
+	m1.Set("a", false)
+	checkMarshalJSON(t, m1, `{"b":true,"a":false}`)
+
+	m2 := new(Map[point, int])
+	m2.Set(point{3, 4}, 1)
+	m2.Set(point{1, 2}, 2)
+	m2.Set(point{-1, 0}, 3)
+	checkMarshalJSON(t, m2, `{"3,4":1,"1,2":2,"-1,0":3}`)
+
+	m3 := new(Map[int8, string])
+	m3.Set(10, "a")
+	m3.Set(-3, "b")
+	checkMarshalJSON(t, m3, `{"10":"a","-3":"b"}`)
+
+	m4 := new(Map[float64, string])
+	m4.Set(1.5, "a")
+	if b, err := json.Marshal(m4); err == nil {
+		t.Errorf("Marshal with float64 keys: got %s; want error", b)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var m Map[string, int]
+	if err := json.Unmarshal([]byte(`{"z": 1, "a": 2, "m": 3}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	checkAll(t, &m, []keyVal[string, int]{{"z", 1}, {"a", 2}, {"m", 3}})
+
+	// Existing entries are kept; null is a no-op.
+	if err := json.Unmarshal([]byte(`{"b": 4, "z": 5}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	checkAll(t, &m, []keyVal[string, int]{{"a", 2}, {"m", 3}, {"b", 4}, {"z", 5}})
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil {
+		t.Fatal(err)
+	}
+	checkAll(t, &m, []keyVal[string, int]{{"a", 2}, {"m", 3}, {"b", 4}, {"z", 5}})
+
+	// Round trip.
+	b, err := json.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m2 Map[string, int]
+	if err := json.Unmarshal(b, &m2); err != nil {
+		t.Fatal(err)
+	}
+	checkAll(t, &m2, []keyVal[string, int]{{"a", 2}, {"m", 3}, {"b", 4}, {"z", 5}})
+}
+
+func TestUnmarshalJSONDuplicates(t *testing.T) {
+	const doc = `{"a": 1, "b": 2, "a": 3, "c": 4}`
+	for _, tt := range []struct {
+		order Order
+		want  []keyVal[string, int]
+	}{
+		{UpdateOrder, []keyVal[string, int]{{"b", 2}, {"a", 3}, {"c", 4}}},
+		{InsertOrder, []keyVal[string, int]{{"a", 3}, {"b", 2}, {"c", 4}}},
+		{AccessOrder, []keyVal[string, int]{{"b", 2}, {"a", 3}, {"c", 4}}},
+	} {
+		t.Run(tt.order.String(), func(t *testing.T) {
+			m := New[string, int](tt.order)
+			if err := json.Unmarshal([]byte(doc), m); err != nil {
+				t.Fatal(err)
+			}
+			checkAll(t, m, tt.want)
+		})
+	}
+}
+
+func TestUnmarshalJSONNested(t *testing.T) {
+	const doc = `{"b": {"y": 1, "x": 2}, "a": {}, "c": null}`
+	var m Map[string, *Map[string, int]]
+	if err := json.Unmarshal([]byte(doc), &m); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for k := range m.Keys() {
+		keys = append(keys, k)
+	}
+	if got, want := len(keys), 3; got != want {
+		t.Fatalf("got %d keys; want %d", got, want)
+	}
+	b, _ := m.Peek("b")
+	checkAll(t, b, []keyVal[string, int]{{"y", 1}, {"x", 2}})
+	a, _ := m.Peek("a")
+	checkAll(t, a, nil)
+	if c, ok := m.Peek("c"); c != nil || !ok {
+		t.Fatalf(`Peek("c"): got (%v, %t); want (nil, true)`, c, ok)
+	}
+	checkMarshalJSON(t, &m, `{"b":{"y":1,"x":2},"a":{},"c":null}`)
+}
+
+func TestUnmarshalJSONKeys(t *testing.T) {
+	var m1 Map[myString, int]
+	if err := json.Unmarshal([]byte(`{"b": 1, "a": 2}`), &m1); err != nil {
+		t.Fatal(err)
+	}
+	checkAll(t, &m1, []keyVal[myString, int]{{"b", 1}, {"a", 2}})
+
+	var m2 Map[point, int]
+	if err := json.Unmarshal([]byte(`{"3,4": 1, "1,2": 2}`), &m2); err != nil {
+		t.Fatal(err)
+	}
+	checkAll(t, &m2, []keyVal[point, int]{{point{3, 4}, 1}, {point{1, 2}, 2}})
+
+	var m3 Map[uint8, int]
+	if err := json.Unmarshal([]byte(`{"255": 1, "0": 2}`), &m3); err != nil {
+		t.Fatal(err)
+	}
+	checkAll(t, &m3, []keyVal[uint8, int]{{255, 1}, {0, 2}})
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	for _, doc := range []string{
+		`[]`,
+		`"a"`,
+		`3`,
+		`{"a": "b"}`,
+		`{"a": 1,}`,
+		`{"a" 1}`,
+	} {
+		var m Map[string, int]
+		if err := json.Unmarshal([]byte(doc), &m); err == nil {
+			t.Errorf("Unmarshal(%s): got nil error", doc)
+		}
+	}
+	for _, doc := range []string{
+		`{"256": 1}`,
+		`{"x": 1}`,
+	} {
+		var m Map[uint8, int]
+		if err := json.Unmarshal([]byte(doc), &m); err == nil {
+			t.Errorf("Unmarshal(%s): got nil error", doc)
+		}
+	}
+	var m Map[point, int]
+	if err := json.Unmarshal([]byte(`{"x": 1}`), &m); err == nil {
+		t.Error("Unmarshal with bad point key: got nil error")
+	}
+}
+
+func checkMarshalJSON(t *testing.T, v any, want string) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	if string(b) != want {
+		t.Fatalf("Marshal: got %s; want %s", b, want)
+	}
+}
@@ -0,0 +1,223 @@
+package ordmap
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// A list is a doubly-linked list of items stored in a slice. It holds the
+// entries of a Map and the pairs of a MultiMap. Storing the list in a slice
+// rather than as individually allocated elements is friendlier to the cache
+// and (if T is pointer-free) to the garbage collector.
+//
+// A list may be modified while it is being iterated over (see
+// beginIteration). Iterations that do not modify the list only read it,
+// apart from atomically counting themselves, so concurrent iterations of a
+// list that is not being modified do not race.
+type list[T any] struct {
+	// slots[0] is a sentinel: its next is the first slot in the list and
+	// its prev is the last. Slots that are not in use are chained together
+	// by their next indexes, starting at free.
+	slots []slot[T]
+	free  int32
+
+	// seq counts the slots that have been added to the list.
+	seq uint64
+	// iterating is the number of iterations in progress.
+	iterating atomic.Int32
+	// retired holds slots that were released during an iteration.
+	// They are not reused until all iterations are finished.
+	retired []int32
+}
+
+type slot[T any] struct {
+	item T
+	prev int32
+	next int32
+	// seq is the value of list.seq when the slot was added to the list,
+	// or 0 if the slot is not in use.
+	seq uint64
+}
+
+// at returns a pointer to the item in the slot at index i.
+// The pointer is invalidated by the next call to alloc.
+func (l *list[T]) at(i int32) *T {
+	return &l.slots[i].item
+}
+
+// first returns the index of the first slot in the list, or 0 if the list
+// is empty.
+func (l *list[T]) first() int32 {
+	if len(l.slots) == 0 {
+		return 0
+	}
+	return l.slots[0].next
+}
+
+// last returns the index of the last slot in the list, or 0 if the list
+// is empty.
+func (l *list[T]) last() int32 {
+	if len(l.slots) == 0 {
+		return 0
+	}
+	return l.slots[0].prev
+}
+
+func (l *list[T]) step(i int32, backward bool) int32 {
+	if backward {
+		return l.slots[i].prev
+	}
+	return l.slots[i].next
+}
+
+// iterate calls yield for the item at index i and each subsequent item of
+// the list (or each preceding item, if backward is true) until yield returns
+// false. The items it produces are those that were in the list when iterate
+// was called (see beginIteration).
+func (l *list[T]) iterate(i int32, backward bool, yield func(*T) bool) {
+	if i == 0 {
+		return
+	}
+	start := l.beginIteration()
+	defer l.doneIterating()
+	for ; i != 0; i = l.step(i, backward) {
+		s := &l.slots[i]
+		if s.seq == 0 || s.seq > start {
+			continue
+		}
+		if !yield(&s.item) {
+			return
+		}
+	}
+}
+
+// beginIteration records the start of an iteration. It returns the current
+// seq: the iteration produces only the slots that are in use and whose seq
+// is at most this value.
+//
+// While any iteration is in progress, the owner of the list must never move
+// an item within the list: instead, it should remove the old slot and insert
+// a new one (see isIterating). Released slots are not reused until all
+// iterations finish (see release). Removed slots keep their links, so an
+// iteration can always proceed from the slot it last produced, even if that
+// slot was removed; it skips over removed slots and over slots that were
+// inserted after the iteration began.
+//
+// Iterations count themselves atomically, so concurrent iterations of a list
+// that is not being modified do not race. Slots are only retired if the list
+// is modified during an iteration, which must not happen while other
+// goroutines are reading it.
+//
+// Each call to beginIteration must be paired with a call to doneIterating.
+func (l *list[T]) beginIteration() (start uint64) {
+	l.iterating.Add(1)
+	return l.seq
+}
+
+func (l *list[T]) doneIterating() {
+	if l.iterating.Add(-1) > 0 || len(l.retired) == 0 {
+		return
+	}
+	for _, i := range l.retired {
+		l.slots[i].next = l.free
+		l.free = i
+	}
+	l.retired = l.retired[:0]
+}
+
+// isIterating reports whether any iteration is in progress.
+func (l *list[T]) isIterating() bool {
+	return l.iterating.Load() > 0
+}
+
+// alloc returns the index of an unused slot holding item.
+func (l *list[T]) alloc(item T) int32 {
+	if l.slots == nil {
+		l.slots = make([]slot[T], 1, 8)
+	}
+	if i := l.free; i != 0 {
+		l.free = l.slots[i].next
+		l.slots[i] = slot[T]{item: item}
+		return i
+	}
+	if len(l.slots) == math.MaxInt32 {
+		panic("ordmap: too many entries")
+	}
+	l.slots = append(l.slots, slot[T]{item: item})
+	return int32(len(l.slots) - 1)
+}
+
+// release marks the slot at index i, which must have been removed from the
+// list, as unused.
+func (l *list[T]) release(i int32) {
+	s := &l.slots[i]
+	// Clear the item so it may be garbage collected,
+	// but leave the links for any iterations positioned here.
+	var zero T
+	s.item = zero
+	s.seq = 0
+	if l.isIterating() {
+		l.retired = append(l.retired, i)
+		return
+	}
+	s.next = l.free
+	l.free = i
+}
+
+// insertBefore links the slot at index i into the list immediately before
+// the slot at index mark. If mark is 0, the slot is added to the end of the
+// list.
+func (l *list[T]) insertBefore(i, mark int32) {
+	prev := l.slots[mark].prev
+	s := &l.slots[i]
+	s.prev = prev
+	s.next = mark
+	l.slots[prev].next = i
+	l.slots[mark].prev = i
+	l.seq++
+	s.seq = l.seq
+}
+
+// remove unlinks the slot at index i from the list.
+// It leaves the slot's own links intact (see beginIteration).
+func (l *list[T]) remove(i int32) {
+	s := &l.slots[i]
+	l.slots[s.prev].next = s.next
+	l.slots[s.next].prev = s.prev
+}
+
+// reset empties the list, which must not be being iterated over.
+func (l *list[T]) reset() {
+	clear(l.slots)
+	l.slots = l.slots[:min(len(l.slots), 1)]
+	l.free = 0
+}
+
+// maybeCompact rebuilds the slots if fewer than a quarter of them hold the
+// n items in the list and no iteration is in progress. It reports whether
+// it did so, in which case the items have new indexes, in list order
+// starting at 1.
+func (l *list[T]) maybeCompact(n int) bool {
+	size := len(l.slots)
+	if size < 64 || n >= size/4 || l.isIterating() {
+		return false
+	}
+	slots := make([]slot[T], 1, 2*(n+1))
+	for i := l.first(); i != 0; i = l.slots[i].next {
+		s := l.slots[i]
+		j := int32(len(slots))
+		s.prev = j - 1
+		s.next = j + 1
+		slots = append(slots, s)
+	}
+	last := int32(len(slots) - 1)
+	slots[last].next = 0
+	slots[0].prev = last
+	slots[0].next = 1
+	if last == 0 {
+		slots[0].next = 0
+	}
+	l.slots = slots
+	l.free = 0
+	return true
+}
@@ -0,0 +1,55 @@
+package ordmap
+
+import "testing"
+
+// checkList checks the internal consistency of l, which must hold n items
+// and must not be being iterated over.
+func checkList[T any](t *testing.T, desc string, l *list[T], n int) {
+	t.Helper()
+	if l.isIterating() || len(l.retired) != 0 {
+		t.Fatalf("%s: iterating = %d, %d retired slots; want none", desc, l.iterating.Load(), len(l.retired))
+	}
+	if len(l.slots) == 0 {
+		if n != 0 {
+			t.Fatalf("%s: no slots but %d items", desc, n)
+		}
+		return
+	}
+	// Walk the list, checking the links in both directions.
+	used := 0
+	prev := int32(0)
+	for i := l.slots[0].next; i != 0; i = l.slots[i].next {
+		s := &l.slots[i]
+		if s.prev != prev {
+			t.Fatalf("%s: slot %d has prev %d; want %d", desc, i, s.prev, prev)
+		}
+		if s.seq == 0 || s.seq > l.seq {
+			t.Fatalf("%s: slot %d has seq %d (list seq %d)", desc, i, s.seq, l.seq)
+		}
+		prev = i
+		used++
+		if used > len(l.slots) {
+			t.Fatalf("%s: list has a cycle", desc)
+		}
+	}
+	if l.slots[0].prev != prev {
+		t.Fatalf("%s: sentinel prev is %d; want last slot %d", desc, l.slots[0].prev, prev)
+	}
+	if used != n {
+		t.Fatalf("%s: list has %d slots in use; want %d", desc, used, n)
+	}
+	// Every other slot must be on the free list.
+	free := 0
+	for i := l.free; i != 0; i = l.slots[i].next {
+		if l.slots[i].seq != 0 {
+			t.Fatalf("%s: free slot %d is in use", desc, i)
+		}
+		free++
+		if free > len(l.slots) {
+			t.Fatalf("%s: free list has a cycle", desc)
+		}
+	}
+	if used+free != len(l.slots)-1 {
+		t.Fatalf("%s: %d slots in use and %d free; want %d total", desc, used, free, len(l.slots)-1)
+	}
+}
@@ -0,0 +1,220 @@
+package ordmap
+
+import "iter"
+
+// A MultiMap is an ordered map that may hold multiple values for each key,
+// such as the fields of an HTTP or email header. It remembers the order of
+// every key-value pair, including how the pairs for different keys are
+// interleaved.
+//
+// Add appends a pair to the end of the map. Set replaces the values for a key
+// without moving it: the first pair for the key keeps its position and takes
+// the new value, and the other pairs for the key are deleted.
+//
+// Iterating over a MultiMap while modifying it follows the same rules as for
+// Map: an iteration produces only the pairs present when it began,
+// omits pairs that are deleted before they are reached,
+// and observes values changed by Set.
+//
+// The zero value of a MultiMap is an empty map ready to use.
+// Like a Map, a MultiMap must not be copied after first use.
+// As with Map, concurrent calls to methods that only read a MultiMap,
+// including iterating over it, are fine; concurrent calls to methods that
+// modify it are racy.
+type MultiMap[K comparable, V any] struct {
+	m map[K]chain
+	n int // number of pairs
+	// entries holds the pairs in order, as in Map.
+	// Additionally, the pairs for each key form a singly linked chain
+	// through the knext fields.
+	entries list[multiEntry[K, V]]
+}
+
+// A chain locates the pairs for one key of a MultiMap.
+type chain struct {
+	first int32
+	last  int32
+	n     int
+}
+
+type multiEntry[K comparable, V any] struct {
+	k     K
+	v     V
+	knext int32 // next pair with the same key, or 0
+}
+
+// Add appends a pair with the given key and value to the end of the map.
+func (m *MultiMap[K, V]) Add(key K, val V) {
+	if m.m == nil {
+		m.m = make(map[K]chain)
+	}
+	i := m.entries.alloc(multiEntry[K, V]{k: key, v: val})
+	m.entries.insertBefore(i, 0)
+	m.n++
+	c, ok := m.m[key]
+	if !ok {
+		m.m[key] = chain{first: i, last: i, n: 1}
+		return
+	}
+	m.entries.at(c.last).knext = i
+	c.last = i
+	c.n++
+	m.m[key] = c
+}
+
+// Get returns the first value associated with key,
+// or the zero value of V if key is not present.
+// The ok result indicates whether key was found in the map.
+func (m *MultiMap[K, V]) Get(key K) (val V, ok bool) {
+	c, ok := m.m[key]
+	if !ok {
+		return val, false
+	}
+	return m.entries.at(c.first).v, true
+}
+
+// GetAll returns all the values associated with key, in order.
+// It returns nil if key is not present.
+func (m *MultiMap[K, V]) GetAll(key K) []V {
+	c, ok := m.m[key]
+	if !ok {
+		return nil
+	}
+	vals := make([]V, 0, c.n)
+	for i := c.first; i != 0; i = m.entries.at(i).knext {
+		vals = append(vals, m.entries.at(i).v)
+	}
+	return vals
+}
+
+// Count returns the number of values associated with key.
+func (m *MultiMap[K, V]) Count(key K) int {
+	return m.m[key].n
+}
+
+// Set replaces the values associated with key by the single value val.
+// If key is present, the first pair for key keeps its position and the
+// others are deleted; otherwise, the pair is added to the end of the map.
+func (m *MultiMap[K, V]) Set(key K, val V) {
+	c, ok := m.m[key]
+	if !ok {
+		m.Add(key, val)
+		return
+	}
+	e := m.entries.at(c.first)
+	e.v = val
+	i := e.knext
+	e.knext = 0
+	m.removeChain(i)
+	m.m[key] = chain{first: c.first, last: c.first, n: 1}
+	m.maybeCompact()
+}
+
+// Delete deletes all the pairs for key.
+// If key is not present, Delete is a no-op.
+func (m *MultiMap[K, V]) Delete(key K) {
+	c, ok := m.m[key]
+	if !ok {
+		return
+	}
+	m.removeChain(c.first)
+	delete(m.m, key)
+	m.maybeCompact()
+}
+
+// removeChain removes the pair at index i and the pairs that follow it in its
+// key's chain.
+func (m *MultiMap[K, V]) removeChain(i int32) {
+	for i != 0 {
+		next := m.entries.at(i).knext
+		m.entries.remove(i)
+		m.entries.release(i)
+		m.n--
+		i = next
+	}
+}
+
+// maybeCompact compacts the list if it is mostly unused
+// and relinks the chains to the new indexes.
+func (m *MultiMap[K, V]) maybeCompact() {
+	if !m.entries.maybeCompact(m.n) {
+		return
+	}
+	// Each key's pairs are in list order, so the chains can be rebuilt
+	// by walking the list.
+	for k, c := range m.m {
+		m.m[k] = chain{n: c.n}
+	}
+	for i := m.entries.first(); i != 0; i = m.entries.slots[i].next {
+		e := m.entries.at(i)
+		e.knext = 0
+		c := m.m[e.k]
+		if c.first == 0 {
+			c.first = i
+		} else {
+			m.entries.at(c.last).knext = i
+		}
+		c.last = i
+		m.m[e.k] = c
+	}
+}
+
+// Clear deletes all the pairs in the map.
+func (m *MultiMap[K, V]) Clear() {
+	if m.entries.isIterating() {
+		for k := range m.m {
+			m.Delete(k)
+		}
+		return
+	}
+	clear(m.m)
+	m.entries.reset()
+	m.n = 0
+}
+
+// Len returns the number of key-value pairs in the map.
+func (m *MultiMap[K, V]) Len() int {
+	return m.n
+}
+
+// KeyLen returns the number of distinct keys in the map.
+func (m *MultiMap[K, V]) KeyLen() int {
+	return len(m.m)
+}
+
+// All returns an iterator over all the key-value pairs in the map, in order.
+func (m *MultiMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.entries.iterate(m.entries.first(), false, func(e *multiEntry[K, V]) bool {
+			return yield(e.k, e.v)
+		})
+	}
+}
+
+// Keys returns an iterator over the distinct keys in the map,
+// ordered by the position of the first pair for each key.
+func (m *MultiMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.firsts(func(k K) bool { return yield(k) })
+	}
+}
+
+// Grouped returns an iterator over the distinct keys in the map, each paired
+// with all of its values in order (as returned by GetAll).
+// The keys are ordered by the position of the first pair for each key.
+func (m *MultiMap[K, V]) Grouped() iter.Seq2[K, []V] {
+	return func(yield func(K, []V) bool) {
+		m.firsts(func(k K) bool { return yield(k, m.GetAll(k)) })
+	}
+}
+
+// firsts calls yield with the key of each pair that is the first pair
+// for its key.
+func (m *MultiMap[K, V]) firsts(yield func(K) bool) {
+	m.entries.iterate(m.entries.first(), false, func(e *multiEntry[K, V]) bool {
+		if m.entries.at(m.m[e.k].first) != e {
+			return true
+		}
+		return yield(e.k)
+	})
+}
@@ -0,0 +1,206 @@
+package ordmap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMultiMap(t *testing.T) {
+	var m MultiMap[string, int]
+	checkMulti(t, &m, nil)
+	if v, ok := m.Get("a"); v != 0 || ok {
+		t.Fatalf(`Get("a") on empty map: got (%d, %t)`, v, ok)
+	}
+	if got := m.GetAll("a"); got != nil {
+		t.Fatalf(`GetAll("a") on empty map: got %v`, got)
+	}
+
+	m.Add("a", 1)
+	m.Add("b", 2)
+	m.Add("a", 3)
+	m.Add("c", 4)
+	m.Add("b", 5)
+	m.Add("a", 6)
+	checkMulti(t, &m, []keyVal[string, int]{{"a", 1}, {"b", 2}, {"a", 3}, {"c", 4}, {"b", 5}, {"a", 6}})
+	if v, ok := m.Get("a"); v != 1 || !ok {
+		t.Fatalf(`Get("a"): got (%d, %t); want (1, true)`, v, ok)
+	}
+
+	m.Set("a", 10)
+	checkMulti(t, &m, []keyVal[string, int]{{"a", 10}, {"b", 2}, {"c", 4}, {"b", 5}})
+	m.Set("d", 7)
+	checkMulti(t, &m, []keyVal[string, int]{{"a", 10}, {"b", 2}, {"c", 4}, {"b", 5}, {"d", 7}})
+	m.Delete("b")
+	m.Delete("x")
+	checkMulti(t, &m, []keyVal[string, int]{{"a", 10}, {"c", 4}, {"d", 7}})
+	m.Add("c", 8)
+	m.Add("b", 9)
+	checkMulti(t, &m, []keyVal[string, int]{{"a", 10}, {"c", 4}, {"d", 7}, {"c", 8}, {"b", 9}})
+
+	m.Clear()
+	checkMulti(t, &m, nil)
+	m.Add("e", 1)
+	m.Add("e", 2)
+	checkMulti(t, &m, []keyVal[string, int]{{"e", 1}, {"e", 2}})
+}
+
+func TestMultiMapModifyDuringIteration(t *testing.T) {
+	var m MultiMap[string, int]
+	m.Add("a", 1)
+	m.Add("b", 2)
+	m.Add("a", 3)
+	m.Add("c", 4)
+	m.Add("b", 5)
+
+	var got []keyVal[string, int]
+	for k, v := range m.All() {
+		got = append(got, keyVal[string, int]{k, v})
+		switch k {
+		case "a":
+			// Set changes the value of the first "a", which was already
+			// produced, and deletes the second.
+			m.Set("a", 10)
+			m.Add("d", 6) // not produced
+		case "b":
+			m.Delete("c")
+			m.Set("b", 20)
+		}
+	}
+	want := []keyVal[string, int]{{"a", 1}, {"b", 2}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("iteration gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	checkMulti(t, &m, []keyVal[string, int]{{"a", 10}, {"b", 20}, {"d", 6}})
+
+	// Clear during iteration.
+	var keys []string
+	for k := range m.Keys() {
+		keys = append(keys, k)
+		m.Clear()
+		m.Add("e", 7)
+	}
+	if diff := cmp.Diff(keys, []string{"a"}); diff != "" {
+		t.Fatalf("iteration gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	checkMulti(t, &m, []keyVal[string, int]{{"e", 7}})
+}
+
+func TestMultiMapRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	var m MultiMap[int, int]
+	var model []keyVal[int, int]
+	for op := range 5000 {
+		k := r.IntN(20)
+		switch r.IntN(5) {
+		case 0, 1:
+			m.Add(k, op)
+			model = append(model, keyVal[int, int]{k, op})
+		case 2:
+			m.Set(k, op)
+			if i := slices.IndexFunc(model, func(kv keyVal[int, int]) bool { return kv.Key == k }); i >= 0 {
+				model[i].Val = op
+				model = slices.DeleteFunc(model, func(kv keyVal[int, int]) bool {
+					return kv.Key == k && kv.Val != op
+				})
+			} else {
+				model = append(model, keyVal[int, int]{k, op})
+			}
+		case 3:
+			m.Delete(k)
+			model = slices.DeleteFunc(model, func(kv keyVal[int, int]) bool { return kv.Key == k })
+		case 4:
+			if r.IntN(50) == 0 {
+				m.Clear()
+				model = nil
+			}
+		}
+		if op%50 == 0 {
+			checkMulti(t, &m, model)
+		}
+	}
+}
+
+func TestMultiMapCompact(t *testing.T) {
+	var m MultiMap[int, int]
+	var want []keyVal[int, int]
+	for i := range 1000 {
+		m.Add(i%200, i)
+		if i%200 == 0 {
+			m.Add(-1, i)
+		}
+	}
+	for i := range 200 {
+		if i%50 != 0 {
+			m.Delete(i)
+		}
+	}
+	// Leave a chain for -1 that is interleaved with the other keys.
+	for i := range 1000 {
+		if i%200 == 0 {
+			want = append(want, keyVal[int, int]{i % 200, i}, keyVal[int, int]{-1, i})
+		} else if i%50 == 0 {
+			want = append(want, keyVal[int, int]{i % 200, i})
+		}
+	}
+	checkMulti(t, &m, want)
+	if len(m.entries.slots) > 100 {
+		t.Errorf("after deleting most pairs, len(m.entries.slots) = %d", len(m.entries.slots))
+	}
+	m.Add(50, 1000)
+	m.Set(-1, 1)
+	want = slices.DeleteFunc(want, func(kv keyVal[int, int]) bool { return kv.Key == -1 && kv.Val != 0 })
+	want[1].Val = 1
+	want = append(want, keyVal[int, int]{50, 1000})
+	checkMulti(t, &m, want)
+}
+
+// checkMulti checks the contents of m against want using all of
+// the MultiMap's accessors.
+func checkMulti[K comparable, V any](t *testing.T, m *MultiMap[K, V], want []keyVal[K, V]) {
+	t.Helper()
+	checkList(t, "MultiMap", &m.entries, m.n)
+	if diff := cmp.Diff(collectKVs(m.All()), want); diff != "" {
+		t.Fatalf("All gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	if got := m.Len(); got != len(want) {
+		t.Fatalf("Len: got %d; want %d", got, len(want))
+	}
+	var keys []K
+	groups := make(map[K][]V)
+	for _, kv := range want {
+		if _, ok := groups[kv.Key]; !ok {
+			keys = append(keys, kv.Key)
+		}
+		groups[kv.Key] = append(groups[kv.Key], kv.Val)
+	}
+	if diff := cmp.Diff(slices.Collect(m.Keys()), keys); diff != "" {
+		t.Fatalf("Keys gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	if got := m.KeyLen(); got != len(keys) {
+		t.Fatalf("KeyLen: got %d; want %d", got, len(keys))
+	}
+	var gotKeys []K
+	for k, vs := range m.Grouped() {
+		gotKeys = append(gotKeys, k)
+		if diff := cmp.Diff(vs, groups[k]); diff != "" {
+			t.Fatalf("Grouped gave incorrect values for %v (-got, +want):\n%s", k, diff)
+		}
+	}
+	if diff := cmp.Diff(gotKeys, keys); diff != "" {
+		t.Fatalf("Grouped gave incorrect keys (-got, +want):\n%s", diff)
+	}
+	for k, vs := range groups {
+		if diff := cmp.Diff(m.GetAll(k), vs); diff != "" {
+			t.Fatalf("GetAll(%v) (-got, +want):\n%s", k, diff)
+		}
+		if v, ok := m.Get(k); !ok || !cmp.Equal(v, vs[0]) {
+			t.Fatalf("Get(%v): got (%v, %t); want (%v, true)", k, v, ok, vs[0])
+		}
+		if got := m.Count(k); got != len(vs) {
+			t.Fatalf("Count(%v): got %d; want %d", k, got, len(vs))
+		}
+	}
+}
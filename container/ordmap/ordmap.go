@@ -1,137 +1,608 @@
-// Package ordmap implements an ordered map type.
+// Package ordmap implements ordered map types.
 package ordmap
 
-import "iter"
+import (
+	"fmt"
+	"iter"
+)
 
-// TODO(caleb): This list-based approach looks pretty cache-inefficient.
-// Add some benchmarks; optimize.
-
-// Map is like a Go map[K]V but is ordered: it retains the insertion/update
-// ordering where less recently updated elements precede more recently updated
-// elements.
+// Map is like a Go map[K]V but is ordered: it retains an ordering of its
+// entries, which is reflected in iteration.
+//
+// The ordering rule is selected by the map's Order. The zero value of a Map
+// uses UpdateOrder: it retains the insertion/update ordering where less
+// recently updated elements precede more recently updated elements.
+// Use New to create a Map with a different Order.
+//
+// A Map may be modified while it is being iterated over. Each iteration
+// produces only the entries that were present when the iteration began, in the
+// order they had at that time, omitting entries that are deleted or moved
+// before the iteration reaches them. In particular, entries inserted during an
+// iteration are never produced by it, and moving an entry (for example, by
+// calling Set in UpdateOrder) never causes it to be produced a second time.
+// Value changes that do not move an entry (Set in InsertOrder) are observed
+// by an iteration that has not yet reached the entry.
+//
+// Code that mirrors a Map elsewhere may observe its modifications by setting
+// OnChange, and may cheaply detect that it has been modified using Version.
+//
+// As with Go maps, concurrent calls to methods that only read a Map,
+// including iterating over it, are fine; concurrent calls to methods that
+// modify it are racy. (Get modifies a map that uses AccessOrder.)
+//
+// A Map must not be copied after first use: a copy shares the storage of
+// its entries with the original, and modifying either one corrupts the
+// other. Use Clone to make a copy. (The go vet copylocks check reports
+// such copies.)
 type Map[K comparable, V any] struct {
-	m     map[K]*element[K, V]
-	first *element[K, V]
-	last  *element[K, V]
+	// OnChange is an optional function that is called after each change to
+	// the map with an Event describing the change.
+	// OnChange may read the map (for example, calling IndexOf to find the
+	// new position of a moved key), but it must not modify it.
+	OnChange func(Event[K, V])
+
+	// m maps each key to the index of its entry in entries.
+	m map[K]int32
+	// entries holds the entries of the map in order.
+	entries list[entry[K, V]]
+	order   Order
+
+	// idx, if non-nil, indexes the positions of the entries.
+	// It is built on demand by the positional methods (see At).
+	idx *index
+
+	// version counts the modifications made to the map.
+	version uint64
 }
 
-type element[K comparable, V any] struct {
-	k    K
-	v    V
-	prev *element[K, V]
-	next *element[K, V]
+type entry[K comparable, V any] struct {
+	k K
+	v V
+}
+
+// An Order is a rule for ordering the entries of a Map.
+//
+// All three orders place a newly inserted key at the end of the map.
+// They differ in how they treat operations on keys that are already present:
+//
+//	            Set on existing key   Get on existing key
+//	UpdateOrder moves key to end      no effect
+//	InsertOrder no effect             no effect
+//	AccessOrder moves key to end      moves key to end
+//
+// Peek, Delete, and iteration never move keys in any order.
+type Order int
+
+const (
+	// UpdateOrder orders entries from least to most recently updated (by Set).
+	// This is the order used by the zero value of a Map.
+	UpdateOrder Order = iota
+	// InsertOrder orders entries by when their keys were first inserted
+	// into the map. Setting the value for an existing key leaves it in place.
+	// (Deleting a key and setting it again inserts it anew at the end.)
+	// This matches the ordering of Python dicts.
+	InsertOrder
+	// AccessOrder orders entries from least to most recently accessed,
+	// where both Get and Set count as accesses.
+	// This is the order needed for least-recently-used caches.
+	// Note that in this order Get modifies the map.
+	AccessOrder
+)
+
+func (o Order) String() string {
+	switch o {
+	case UpdateOrder:
+		return "UpdateOrder"
+	case InsertOrder:
+		return "InsertOrder"
+	case AccessOrder:
+		return "AccessOrder"
+	default:
+		return fmt.Sprintf("Order(%d)", int(o))
+	}
+}
+
+// New creates an empty Map that orders its entries according to order.
+// New(UpdateOrder) is equivalent to new(Map[K, V]).
+func New[K comparable, V any](order Order) *Map[K, V] {
+	checkOrder(order)
+	return &Map[K, V]{order: order}
+}
+
+func checkOrder(order Order) {
+	switch order {
+	case UpdateOrder, InsertOrder, AccessOrder:
+	default:
+		panic("ordmap: invalid Order")
+	}
+}
+
+// Order returns the ordering rule used by m.
+func (m *Map[K, V]) Order() Order {
+	return m.order
 }
 
 // Get returns the value stored in the map for a key,
 // or the zero value of V if no value is present.
 // The ok result indicates whether the key was found in the map.
+// If m uses AccessOrder, Get moves the key to the end of the map;
+// if the key is already last, this is not a modification of the map.
 func (m *Map[K, V]) Get(key K) (val V, ok bool) {
-	if e, ok := m.m[key]; ok {
-		return e.v, true
+	i, ok := m.m[key]
+	if !ok {
+		return val, false
 	}
-	return val, false
+	if m.order == AccessOrder && i != m.entries.last() {
+		i = m.moveToEnd(i)
+		m.changed(EventMoved, i)
+	}
+	return m.entries.at(i).v, true
+}
+
+// Peek is like Get but it never changes the ordering of the map,
+// regardless of m's Order.
+func (m *Map[K, V]) Peek(key K) (val V, ok bool) {
+	i, ok := m.m[key]
+	if !ok {
+		return val, false
+	}
+	return m.entries.at(i).v, true
 }
 
 // Set sets the value for a key.
+// If the key is not present, it is added to the end of the map.
+// If the key is present, it is moved to the end of the map
+// unless m uses InsertOrder.
 func (m *Map[K, V]) Set(key K, v V) {
-	if e, ok := m.m[key]; ok {
-		e.v = v
-		m.listMoveToEnd(e)
+	if i, ok := m.m[key]; ok {
+		m.entries.at(i).v = v
+		kind := EventUpdated
+		if m.order != InsertOrder {
+			i = m.moveToEnd(i)
+			kind |= EventMoved
+		}
+		m.changed(kind, i)
 		return
 	}
 	if m.m == nil {
-		m.m = make(map[K]*element[K, V])
+		m.m = make(map[K]int32)
 	}
-	e := &element[K, V]{k: key, v: v}
-	m.listAppend(e)
-	m.m[key] = e
+	i := m.entries.alloc(entry[K, V]{key, v})
+	m.listInsertBefore(i, 0)
+	m.m[key] = i
+	m.changed(EventInserted, i)
 }
 
 // Delete deletes the value for a key.
 func (m *Map[K, V]) Delete(key K) {
-	e, ok := m.m[key]
+	i, ok := m.m[key]
 	if !ok {
 		return
 	}
-	m.listDelete(e)
-	delete(m.m, key)
+	m.remove(i)
+	m.maybeCompact()
+}
+
+// remove removes the entry at index i from the map.
+func (m *Map[K, V]) remove(i int32) {
+	e := m.entries.at(i)
+	k, v := e.k, e.v
+	m.listRemove(i)
+	m.entries.release(i)
+	delete(m.m, k)
+	m.version++
+	if m.OnChange != nil {
+		m.OnChange(Event[K, V]{Kind: EventDeleted, Key: k, Value: v})
+	}
+}
+
+// Len returns the number of entries in the map.
+func (m *Map[K, V]) Len() int {
+	return len(m.m)
+}
+
+// Version returns a number that increases whenever the map is modified:
+// when an entry is inserted, updated, moved, or deleted.
+// (Get modifies a map that uses AccessOrder, unless the key is already last.)
+// Comparing versions is a cheap way to detect that the map has changed.
+// For example, a loop over All that must not tolerate modification of the
+// map can fail fast by checking that the version is unchanged at each step.
+//
+// Versions of different maps (including clones) are unrelated.
+func (m *Map[K, V]) Version() uint64 {
+	return m.version
+}
+
+// Clear deletes all entries from the map, leaving it empty.
+// The map retains its Order.
+//
+// If OnChange is set, it is called for each entry, in order.
+func (m *Map[K, V]) Clear() {
+	if len(m.m) == 0 {
+		return
+	}
+	if m.entries.isIterating() || m.OnChange != nil {
+		// Remove entries one at a time so that iterations in progress can
+		// still find their way (see list.beginIteration).
+		for i := m.entries.first(); i != 0; {
+			next := m.entries.slots[i].next
+			m.remove(i)
+			i = next
+		}
+		m.maybeCompact()
+		return
+	}
+	m.version++
+	clear(m.m)
+	m.entries.reset()
+	m.idx = nil
+}
+
+// Clone returns a copy of m with the same entries, in the same order,
+// and the same Order. The copy does not have an OnChange function.
+// The keys and values are copied using assignment,
+// so this is a shallow clone.
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	m1 := &Map[K, V]{order: m.order}
+	if len(m.m) == 0 {
+		return m1
+	}
+	m1.m = make(map[K]int32, len(m.m))
+	m1.entries.slots = make([]slot[entry[K, V]], 1, len(m.m)+1)
+	for i := m.entries.first(); i != 0; i = m.entries.slots[i].next {
+		e := *m.entries.at(i)
+		j := m1.entries.alloc(e)
+		m1.entries.insertBefore(j, 0)
+		m1.m[e.k] = j
+	}
+	return m1
+}
+
+// DeleteFunc deletes any entries from m for which del returns true.
+// The entries are visited in the map ordering.
+func (m *Map[K, V]) DeleteFunc(del func(K, V) bool) {
+	for k, v := range m.All() {
+		if del(k, v) {
+			m.Delete(k)
+		}
+	}
+}
+
+// Insert sets the key-value pairs from seq in m, in order,
+// as if by calling Set for each pair.
+func (m *Map[K, V]) Insert(seq iter.Seq2[K, V]) {
+	for k, v := range seq {
+		m.Set(k, v)
+	}
+}
+
+// Collect collects the key-value pairs from seq into a new Map
+// (with UpdateOrder) and returns it.
+// If seq yields the same key more than once,
+// the key takes its last value and is positioned accordingly.
+func Collect[K comparable, V any](seq iter.Seq2[K, V]) *Map[K, V] {
+	m := new(Map[K, V])
+	m.Insert(seq)
+	return m
+}
+
+// Equal reports whether two maps contain the same key-value pairs
+// in the same order. Values are compared using ==.
+// The Orders of the maps are not compared.
+func Equal[K, V comparable](m1, m2 *Map[K, V]) bool {
+	return EqualFunc(m1, m2, func(v1, v2 V) bool { return v1 == v2 })
+}
+
+// EqualFunc is like Equal but compares values using eq.
+// Keys are still compared with ==.
+func EqualFunc[K comparable, V1, V2 any](m1 *Map[K, V1], m2 *Map[K, V2], eq func(V1, V2) bool) bool {
+	if m1.Len() != m2.Len() {
+		return false
+	}
+	i1, i2 := m1.entries.first(), m2.entries.first()
+	for i1 != 0 {
+		e1, e2 := m1.entries.at(i1), m2.entries.at(i2)
+		if e1.k != e2.k || !eq(e1.v, e2.v) {
+			return false
+		}
+		i1, i2 = m1.entries.slots[i1].next, m2.entries.slots[i2].next
+	}
+	return true
+}
+
+// First returns the first key in the map and its value.
+// If the map is empty, First returns zero values and ok is false.
+// First does not change the ordering of the map.
+func (m *Map[K, V]) First() (key K, val V, ok bool) {
+	return m.peekAt(m.entries.first())
+}
+
+// Last returns the last key in the map and its value.
+// If the map is empty, Last returns zero values and ok is false.
+// Last does not change the ordering of the map.
+func (m *Map[K, V]) Last() (key K, val V, ok bool) {
+	return m.peekAt(m.entries.last())
+}
+
+// PopFirst deletes the first entry in the map and returns its key and value.
+// If the map is empty, PopFirst returns zero values and ok is false.
+func (m *Map[K, V]) PopFirst() (key K, val V, ok bool) {
+	return m.popAt(m.entries.first())
+}
+
+// PopLast deletes the last entry in the map and returns its key and value.
+// If the map is empty, PopLast returns zero values and ok is false.
+func (m *Map[K, V]) PopLast() (key K, val V, ok bool) {
+	return m.popAt(m.entries.last())
+}
+
+func (m *Map[K, V]) peekAt(i int32) (key K, val V, ok bool) {
+	if i == 0 {
+		return key, val, false
+	}
+	e := m.entries.at(i)
+	return e.k, e.v, true
+}
+
+func (m *Map[K, V]) popAt(i int32) (key K, val V, ok bool) {
+	key, val, ok = m.peekAt(i)
+	if ok {
+		m.Delete(key)
+	}
+	return key, val, ok
+}
+
+// MoveToFront moves key to the front of the map.
+// It reports whether key was present in the map.
+func (m *Map[K, V]) MoveToFront(key K) bool {
+	i, ok := m.m[key]
+	if !ok {
+		return false
+	}
+	i = m.move(i, 0, true)
+	m.changed(EventMoved, i)
+	return true
+}
+
+// MoveToBack moves key to the end of the map.
+// It reports whether key was present in the map.
+func (m *Map[K, V]) MoveToBack(key K) bool {
+	i, ok := m.m[key]
+	if !ok {
+		return false
+	}
+	i = m.moveToEnd(i)
+	m.changed(EventMoved, i)
+	return true
+}
+
+// MoveBefore moves key to the position immediately preceding mark.
+// It reports whether both key and mark were present in the map;
+// if either is missing, the map is not modified.
+// If key and mark are the same, MoveBefore does nothing.
+func (m *Map[K, V]) MoveBefore(key, mark K) bool {
+	return m.moveRelative(key, mark, false)
+}
+
+// MoveAfter moves key to the position immediately following mark.
+// It reports whether both key and mark were present in the map;
+// if either is missing, the map is not modified.
+// If key and mark are the same, MoveAfter does nothing.
+func (m *Map[K, V]) MoveAfter(key, mark K) bool {
+	return m.moveRelative(key, mark, true)
+}
+
+func (m *Map[K, V]) moveRelative(key, mark K, after bool) bool {
+	i, ok := m.m[key]
+	if !ok {
+		return false
+	}
+	j, ok := m.m[mark]
+	if !ok {
+		return false
+	}
+	if i != j {
+		i = m.move(i, j, after)
+		m.changed(EventMoved, i)
+	}
+	return true
+}
+
+// SetBefore sets the value for key and places key at the position
+// immediately preceding mark, regardless of m's Order.
+// If key is already present, it is moved.
+// SetBefore reports whether mark was present in the map;
+// if it was not, the map is not modified.
+// If key and mark are the same, SetBefore only sets the value.
+func (m *Map[K, V]) SetBefore(key K, v V, mark K) bool {
+	return m.setRelative(key, v, mark, false)
+}
+
+// SetAfter sets the value for key and places key at the position
+// immediately following mark, regardless of m's Order.
+// If key is already present, it is moved.
+// SetAfter reports whether mark was present in the map;
+// if it was not, the map is not modified.
+// If key and mark are the same, SetAfter only sets the value.
+func (m *Map[K, V]) SetAfter(key K, v V, mark K) bool {
+	return m.setRelative(key, v, mark, true)
+}
+
+func (m *Map[K, V]) setRelative(key K, v V, mark K, after bool) bool {
+	j, ok := m.m[mark]
+	if !ok {
+		return false
+	}
+	if i, ok := m.m[key]; ok {
+		m.entries.at(i).v = v
+		kind := EventUpdated
+		if i != j {
+			i = m.move(i, j, after)
+			kind |= EventMoved
+		}
+		m.changed(kind, i)
+		return true
+	}
+	i := m.entries.alloc(entry[K, V]{key, v})
+	if after {
+		j = m.entries.slots[j].next
+	}
+	m.listInsertBefore(i, j)
+	m.m[key] = i
+	m.changed(EventInserted, i)
+	return true
 }
 
 // All returns an iterator over key-value pairs in the map.
-// The iteration order follows the map ordering: least recently updated first.
+// The iteration order follows the map ordering (see Order).
 func (m *Map[K, V]) All() iter.Seq2[K, V] {
 	return func(yield func(K, V) bool) {
-		for e := m.first; e != nil; e = e.next {
-			if !yield(e.k, e.v) {
-				return
-			}
-		}
+		m.all(m.entries.first(), false, yield)
 	}
 }
 
 // Keys returns an iterator over keys in the map.
-// The iteration order follows the map ordering: least recently updated first.
+// The iteration order follows the map ordering (see Order).
 func (m *Map[K, V]) Keys() iter.Seq[K] {
 	return func(yield func(K) bool) {
-		for e := m.first; e != nil; e = e.next {
-			if !yield(e.k) {
-				return
-			}
-		}
+		m.entries.iterate(m.entries.first(), false, func(e *entry[K, V]) bool {
+			return yield(e.k)
+		})
 	}
 }
 
 // Values returns an iterator over values in the map.
-// The iteration order follows the map ordering: least recently updated first.
+// The iteration order follows the map ordering (see Order).
 func (m *Map[K, V]) Values() iter.Seq[V] {
 	return func(yield func(V) bool) {
-		for e := m.first; e != nil; e = e.next {
-			if !yield(e.v) {
-				return
-			}
+		m.entries.iterate(m.entries.first(), false, func(e *entry[K, V]) bool {
+			return yield(e.v)
+		})
+	}
+}
+
+// Backward returns an iterator over key-value pairs in the map
+// in the reverse of the map ordering: the last entry first.
+func (m *Map[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.all(m.entries.last(), true, yield)
+	}
+}
+
+// AllFrom returns an iterator over key-value pairs in the map
+// in the map ordering, starting with key.
+// If key is not present in the map when iteration begins,
+// the iterator produces nothing.
+func (m *Map[K, V]) AllFrom(key K) iter.Seq2[K, V] {
+	return m.seek(key, false, false)
+}
+
+// AllAfter is like AllFrom but it starts with the entry following key.
+func (m *Map[K, V]) AllAfter(key K) iter.Seq2[K, V] {
+	return m.seek(key, false, true)
+}
+
+// BackwardFrom returns an iterator over key-value pairs in the map
+// in the reverse of the map ordering, starting with key.
+// If key is not present in the map when iteration begins,
+// the iterator produces nothing.
+func (m *Map[K, V]) BackwardFrom(key K) iter.Seq2[K, V] {
+	return m.seek(key, true, false)
+}
+
+// BackwardBefore is like BackwardFrom but it starts with the entry
+// preceding key.
+func (m *Map[K, V]) BackwardBefore(key K) iter.Seq2[K, V] {
+	return m.seek(key, true, true)
+}
+
+func (m *Map[K, V]) seek(key K, backward, exclusive bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		i, ok := m.m[key]
+		if !ok {
+			return
 		}
+		if exclusive {
+			i = m.entries.step(i, backward)
+		}
+		m.all(i, backward, yield)
 	}
 }
 
-func (m *Map[K, V]) listAppend(e *element[K, V]) {
-	if m.first == nil {
-		m.first = e
-	} else {
-		m.last.next = e
+// all calls yield for the entry at index i and each subsequent entry of the
+// list (or each preceding entry, if backward is true) until yield returns
+// false.
+func (m *Map[K, V]) all(i int32, backward bool, yield func(K, V) bool) {
+	m.entries.iterate(i, backward, func(e *entry[K, V]) bool {
+		return yield(e.k, e.v)
+	})
+}
+
+// changed records a change of the given kind to the entry at index i.
+func (m *Map[K, V]) changed(kind EventKind, i int32) {
+	m.version++
+	if m.OnChange != nil {
+		e := m.entries.at(i)
+		m.OnChange(Event[K, V]{Kind: kind, Key: e.k, Value: e.v})
 	}
-	e.prev = m.last
-	e.next = nil
-	m.last = e
 }
 
-func (m *Map[K, V]) listMoveToEnd(e *element[K, V]) {
-	if m.last == e {
-		return
+// moveToEnd moves the entry at index i to the end of the list and returns
+// its new index.
+func (m *Map[K, V]) moveToEnd(i int32) int32 {
+	return m.move(i, 0, false)
+}
+
+// move moves the entry at index i, which must differ from mark, to the
+// position immediately before the entry at index mark (or after it, if after
+// is true) and returns the entry's new index. A mark of 0 refers to the
+// sentinel: that is, the entry is moved to the end of the list (or the front,
+// if after is true).
+func (m *Map[K, V]) move(i, mark int32, after bool) int32 {
+	j := i
+	if m.entries.isIterating() {
+		j = m.entries.alloc(*m.entries.at(i))
 	}
-	prev, next := e.prev, e.next
-	if prev == nil {
-		m.first = next
-	} else {
-		prev.next = next
+	m.listRemove(i)
+	if after {
+		mark = m.entries.slots[mark].next
+	}
+	m.listInsertBefore(j, mark)
+	if j != i {
+		m.m[m.entries.at(j).k] = j
+		m.entries.release(i)
+	}
+	return j
+}
+
+// listInsertBefore links the entry at index i into the list immediately
+// before the entry at index mark, keeping the index (if any) up to date.
+// If mark is 0, the entry is added to the end of the list.
+func (m *Map[K, V]) listInsertBefore(i, mark int32) {
+	prev := m.entries.slots[mark].prev
+	m.entries.insertBefore(i, mark)
+	if m.idx != nil {
+		m.idx.insert(i, prev, mark)
 	}
-	next.prev = prev
-	m.last.next = e
-	e.prev = m.last
-	e.next = nil
-	m.last = e
 }
 
-func (m *Map[K, V]) listDelete(e *element[K, V]) {
-	if e.prev == nil {
-		m.first = e.next
-	} else {
-		e.prev.next = e.next
+// listRemove unlinks the entry at index i from the list,
+// keeping the index (if any) up to date.
+func (m *Map[K, V]) listRemove(i int32) {
+	m.entries.remove(i)
+	if m.idx != nil {
+		m.idx.remove(i)
+	}
+}
+
+// maybeCompact rebuilds the list if it is mostly unused.
+func (m *Map[K, V]) maybeCompact() {
+	if !m.entries.maybeCompact(len(m.m)) {
+		return
 	}
-	if e.next == nil {
-		m.last = e.prev
-	} else {
-		e.next.prev = e.prev
+	for i := m.entries.first(); i != 0; i = m.entries.slots[i].next {
+		m.m[m.entries.at(i).k] = i
 	}
-	e.prev = nil
-	e.next = nil
+	// The index is keyed by the old slots; drop it (it is rebuilt on demand).
+	m.idx = nil
 }
@@ -1,7 +1,11 @@
 package ordmap
 
 import (
+	"encoding/json"
+	"iter"
 	"slices"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -47,6 +51,764 @@ func TestMap(t *testing.T) {
 	checkAll(t, m, []keyVal[string, int]{{"x", 10}, {"y", 20}})
 }
 
+func TestOrder(t *testing.T) {
+	// Run the same sequence of operations against each Order.
+	for _, tt := range []struct {
+		order Order
+		want  [][]keyVal[string, int]
+	}{
+		{
+			order: UpdateOrder,
+			want: [][]keyVal[string, int]{
+				{{"a", 1}, {"b", 2}, {"c", 3}},
+				{{"b", 2}, {"c", 3}, {"a", 10}},
+				{{"b", 2}, {"c", 3}, {"a", 10}},
+				{{"c", 3}, {"a", 10}, {"b", 2}},
+			},
+		},
+		{
+			order: InsertOrder,
+			want: [][]keyVal[string, int]{
+				{{"a", 1}, {"b", 2}, {"c", 3}},
+				{{"a", 10}, {"b", 2}, {"c", 3}},
+				{{"a", 10}, {"b", 2}, {"c", 3}},
+				{{"a", 10}, {"c", 3}, {"b", 2}},
+			},
+		},
+		{
+			order: AccessOrder,
+			want: [][]keyVal[string, int]{
+				{{"a", 1}, {"b", 2}, {"c", 3}},
+				{{"b", 2}, {"c", 3}, {"a", 10}},
+				{{"c", 3}, {"a", 10}, {"b", 2}},
+				{{"c", 3}, {"a", 10}, {"b", 2}},
+			},
+		},
+	} {
+		t.Run(tt.order.String(), func(t *testing.T) {
+			m := New[string, int](tt.order)
+			if got := m.Order(); got != tt.order {
+				t.Fatalf("Order(): got %s", got)
+			}
+			m.Set("a", 1)
+			m.Set("b", 2)
+			m.Set("c", 3)
+			checkAll(t, m, tt.want[0])
+
+			m.Set("a", 10)
+			checkAll(t, m, tt.want[1])
+
+			checkGet(t, m, "b", 2, true)
+			checkGet(t, m, "x", 0, false)
+			checkAll(t, m, tt.want[2])
+
+			// Delete and re-insert always goes to the end.
+			m.Delete("b")
+			m.Set("b", 2)
+			checkAll(t, m, tt.want[3])
+		})
+	}
+
+	if got := new(Map[string, int]).Order(); got != UpdateOrder {
+		t.Errorf("zero Map has order %s; want UpdateOrder", got)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	m := New[string, int](AccessOrder)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	if v, ok := m.Peek("a"); v != 1 || !ok {
+		t.Fatalf(`Peek("a"): got (%d, %t); want (1, true)`, v, ok)
+	}
+	if v, ok := m.Peek("z"); v != 0 || ok {
+		t.Fatalf(`Peek("z"): got (%d, %t); want (0, false)`, v, ok)
+	}
+	checkAll(t, m, []keyVal[string, int]{{"a", 1}, {"b", 2}})
+}
+
+func TestSeek(t *testing.T) {
+	m := new(Map[string, int])
+	for i, k := range []string{"a", "b", "c", "d"} {
+		m.Set(k, i)
+	}
+	for _, tt := range []struct {
+		name string
+		seq  iter.Seq2[string, int]
+		want []keyVal[string, int]
+	}{
+		{"AllFrom(a)", m.AllFrom("a"), []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}}},
+		{"AllFrom(c)", m.AllFrom("c"), []keyVal[string, int]{{"c", 2}, {"d", 3}}},
+		{"AllFrom(x)", m.AllFrom("x"), nil},
+		{"AllAfter(a)", m.AllAfter("a"), []keyVal[string, int]{{"b", 1}, {"c", 2}, {"d", 3}}},
+		{"AllAfter(d)", m.AllAfter("d"), nil},
+		{"AllAfter(x)", m.AllAfter("x"), nil},
+		{"BackwardFrom(c)", m.BackwardFrom("c"), []keyVal[string, int]{{"c", 2}, {"b", 1}, {"a", 0}}},
+		{"BackwardFrom(x)", m.BackwardFrom("x"), nil},
+		{"BackwardBefore(c)", m.BackwardBefore("c"), []keyVal[string, int]{{"b", 1}, {"a", 0}}},
+		{"BackwardBefore(a)", m.BackwardBefore("a"), nil},
+	} {
+		if diff := cmp.Diff(collectKVs(tt.seq), tt.want); diff != "" {
+			t.Errorf("%s gave incorrect sequence (-got, +want):\n%s", tt.name, diff)
+		}
+	}
+
+	// The starting key is looked up when iteration begins.
+	seq := m.AllAfter("e")
+	m.Set("e", 4)
+	m.Set("f", 5)
+	if diff := cmp.Diff(collectKVs(seq), []keyVal[string, int]{{"f", 5}}); diff != "" {
+		t.Errorf("AllAfter(e) gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+}
+
+func TestModifyDuringIteration(t *testing.T) {
+	newMap := func(order Order) *Map[string, int] {
+		m := New[string, int](order)
+		for i, k := range []string{"a", "b", "c", "d", "e"} {
+			m.Set(k, i)
+		}
+		return m
+	}
+	for _, tt := range []struct {
+		name     string
+		order    Order
+		backward bool
+		// body is called for each produced entry.
+		body func(m *Map[string, int], k string)
+		want []keyVal[string, int]
+		// after is the map contents after iteration.
+		after []keyVal[string, int]
+	}{
+		{
+			name: "delete current",
+			body: func(m *Map[string, int], k string) { m.Delete(k) },
+			want: []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4}},
+		},
+		{
+			name:     "delete current backward",
+			backward: true,
+			body:     func(m *Map[string, int], k string) { m.Delete(k) },
+			want:     []keyVal[string, int]{{"e", 4}, {"d", 3}, {"c", 2}, {"b", 1}, {"a", 0}},
+		},
+		{
+			name: "delete next two",
+			body: func(m *Map[string, int], k string) {
+				if k == "a" {
+					m.Delete("b")
+					m.Delete("c")
+				}
+			},
+			want:  []keyVal[string, int]{{"a", 0}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{{"a", 0}, {"d", 3}, {"e", 4}},
+		},
+		{
+			name: "delete current and next",
+			body: func(m *Map[string, int], k string) {
+				if k == "b" {
+					m.Delete("b")
+					m.Delete("c")
+				}
+			},
+			want:  []keyVal[string, int]{{"a", 0}, {"b", 1}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{{"a", 0}, {"d", 3}, {"e", 4}},
+		},
+		{
+			name: "set current",
+			body: func(m *Map[string, int], k string) {
+				v, _ := m.Peek(k)
+				m.Set(k, v+10)
+			},
+			want:  []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{{"a", 10}, {"b", 11}, {"c", 12}, {"d", 13}, {"e", 14}},
+		},
+		{
+			name:     "set current backward",
+			backward: true,
+			body: func(m *Map[string, int], k string) {
+				v, _ := m.Peek(k)
+				m.Set(k, v+10)
+			},
+			want:  []keyVal[string, int]{{"e", 4}, {"d", 3}, {"c", 2}, {"b", 1}, {"a", 0}},
+			after: []keyVal[string, int]{{"e", 14}, {"d", 13}, {"c", 12}, {"b", 11}, {"a", 10}},
+		},
+		{
+			name:  "set later in InsertOrder",
+			order: InsertOrder,
+			body: func(m *Map[string, int], k string) {
+				if k == "a" {
+					m.Set("c", 20)
+				}
+			},
+			want:  []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 20}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 20}, {"d", 3}, {"e", 4}},
+		},
+		{
+			name: "move later",
+			body: func(m *Map[string, int], k string) {
+				if k == "a" {
+					m.Set("b", 20)
+				}
+			},
+			want:  []keyVal[string, int]{{"a", 0}, {"c", 2}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{{"a", 0}, {"c", 2}, {"d", 3}, {"e", 4}, {"b", 20}},
+		},
+		{
+			name:  "get in AccessOrder",
+			order: AccessOrder,
+			body:  func(m *Map[string, int], k string) { m.Get(k) },
+			want:  []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4}},
+		},
+		{
+			name: "insert",
+			body: func(m *Map[string, int], k string) { m.Set(k+k, 100) },
+			want: []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{
+				{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"e", 4},
+				{"aa", 100}, {"bb", 100}, {"cc", 100}, {"dd", 100}, {"ee", 100},
+			},
+		},
+		{
+			name: "delete and reinsert",
+			body: func(m *Map[string, int], k string) {
+				if k == "b" {
+					m.Delete("c")
+					m.Set("c", 30)
+				}
+			},
+			want:  []keyVal[string, int]{{"a", 0}, {"b", 1}, {"d", 3}, {"e", 4}},
+			after: []keyVal[string, int]{{"a", 0}, {"b", 1}, {"d", 3}, {"e", 4}, {"c", 30}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMap(tt.order)
+			seq := m.All()
+			if tt.backward {
+				seq = m.Backward()
+			}
+			var got []keyVal[string, int]
+			for k, v := range seq {
+				got = append(got, keyVal[string, int]{k, v})
+				tt.body(m, k)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Fatalf("iteration gave incorrect sequence (-got, +want):\n%s", diff)
+			}
+			checkAll(t, m, tt.after)
+		})
+	}
+}
+
+func TestModifyDuringNestedIteration(t *testing.T) {
+	m := new(Map[string, int])
+	for i, k := range []string{"a", "b", "c"} {
+		m.Set(k, i)
+	}
+	var got []string
+	for k1 := range m.Keys() {
+		for k2 := range m.Keys() {
+			got = append(got, k1+k2)
+			// This moves every entry before the outer loop reaches it.
+			m.Set(k2, 0)
+		}
+	}
+	want := []string{"aa", "ab", "ac"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("nested iteration gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+}
+
+func TestPositional(t *testing.T) {
+	abcd := func() *Map[string, int] {
+		m := new(Map[string, int])
+		for i, k := range []string{"a", "b", "c", "d"} {
+			m.Set(k, i)
+		}
+		return m
+	}
+	for _, tt := range []struct {
+		name   string
+		op     func(m *Map[string, int]) bool
+		wantOK bool
+		want   []keyVal[string, int]
+	}{
+		{
+			name:   "MoveToFront(c)",
+			op:     func(m *Map[string, int]) bool { return m.MoveToFront("c") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"c", 2}, {"a", 0}, {"b", 1}, {"d", 3}},
+		},
+		{
+			name:   "MoveToFront(a)",
+			op:     func(m *Map[string, int]) bool { return m.MoveToFront("a") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveToFront(x)",
+			op:     func(m *Map[string, int]) bool { return m.MoveToFront("x") },
+			wantOK: false,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveToBack(a)",
+			op:     func(m *Map[string, int]) bool { return m.MoveToBack("a") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"b", 1}, {"c", 2}, {"d", 3}, {"a", 0}},
+		},
+		{
+			name:   "MoveToBack(d)",
+			op:     func(m *Map[string, int]) bool { return m.MoveToBack("d") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveToBack(x)",
+			op:     func(m *Map[string, int]) bool { return m.MoveToBack("x") },
+			wantOK: false,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveBefore(d, b)",
+			op:     func(m *Map[string, int]) bool { return m.MoveBefore("d", "b") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"d", 3}, {"b", 1}, {"c", 2}},
+		},
+		{
+			name:   "MoveBefore(a, d)",
+			op:     func(m *Map[string, int]) bool { return m.MoveBefore("a", "d") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"b", 1}, {"c", 2}, {"a", 0}, {"d", 3}},
+		},
+		{
+			name:   "MoveBefore(a, b)",
+			op:     func(m *Map[string, int]) bool { return m.MoveBefore("a", "b") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveBefore(b, b)",
+			op:     func(m *Map[string, int]) bool { return m.MoveBefore("b", "b") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveBefore(x, b)",
+			op:     func(m *Map[string, int]) bool { return m.MoveBefore("x", "b") },
+			wantOK: false,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveBefore(b, x)",
+			op:     func(m *Map[string, int]) bool { return m.MoveBefore("b", "x") },
+			wantOK: false,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveAfter(a, c)",
+			op:     func(m *Map[string, int]) bool { return m.MoveAfter("a", "c") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"b", 1}, {"c", 2}, {"a", 0}, {"d", 3}},
+		},
+		{
+			name:   "MoveAfter(a, d)",
+			op:     func(m *Map[string, int]) bool { return m.MoveAfter("a", "d") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"b", 1}, {"c", 2}, {"d", 3}, {"a", 0}},
+		},
+		{
+			name:   "MoveAfter(c, b)",
+			op:     func(m *Map[string, int]) bool { return m.MoveAfter("c", "b") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "MoveAfter(d, a)",
+			op:     func(m *Map[string, int]) bool { return m.MoveAfter("d", "a") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"d", 3}, {"b", 1}, {"c", 2}},
+		},
+		{
+			name:   "MoveAfter(x, a)",
+			op:     func(m *Map[string, int]) bool { return m.MoveAfter("x", "a") },
+			wantOK: false,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "SetBefore(new)",
+			op:     func(m *Map[string, int]) bool { return m.SetBefore("x", 10, "c") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"x", 10}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "SetBefore(new, first)",
+			op:     func(m *Map[string, int]) bool { return m.SetBefore("x", 10, "a") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"x", 10}, {"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "SetBefore(existing)",
+			op:     func(m *Map[string, int]) bool { return m.SetBefore("d", 10, "a") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"d", 10}, {"a", 0}, {"b", 1}, {"c", 2}},
+		},
+		{
+			name:   "SetBefore(self)",
+			op:     func(m *Map[string, int]) bool { return m.SetBefore("b", 10, "b") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 10}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "SetBefore(missing mark)",
+			op:     func(m *Map[string, int]) bool { return m.SetBefore("b", 10, "x") },
+			wantOK: false,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "SetAfter(new)",
+			op:     func(m *Map[string, int]) bool { return m.SetAfter("x", 10, "b") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"x", 10}, {"c", 2}, {"d", 3}},
+		},
+		{
+			name:   "SetAfter(new, last)",
+			op:     func(m *Map[string, int]) bool { return m.SetAfter("x", 10, "d") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}, {"x", 10}},
+		},
+		{
+			name:   "SetAfter(existing)",
+			op:     func(m *Map[string, int]) bool { return m.SetAfter("a", 10, "c") },
+			wantOK: true,
+			want:   []keyVal[string, int]{{"b", 1}, {"c", 2}, {"a", 10}, {"d", 3}},
+		},
+		{
+			name:   "SetAfter(missing mark)",
+			op:     func(m *Map[string, int]) bool { return m.SetAfter("y", 10, "x") },
+			wantOK: false,
+			want:   []keyVal[string, int]{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 3}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := abcd()
+			if ok := tt.op(m); ok != tt.wantOK {
+				t.Fatalf("got %t; want %t", ok, tt.wantOK)
+			}
+			checkAll(t, m, tt.want)
+		})
+	}
+
+	// Positional operations are fine on an empty map.
+	m := new(Map[string, int])
+	if m.MoveToFront("a") || m.MoveAfter("a", "b") || m.SetBefore("a", 1, "b") {
+		t.Fatal("positional operation on empty map returned true")
+	}
+	checkAll(t, m, nil)
+}
+
+func TestPositionalDuringIteration(t *testing.T) {
+	m := new(Map[string, int])
+	for i, k := range []string{"a", "b", "c", "d"} {
+		m.Set(k, i)
+	}
+	var got []string
+	for k := range m.Keys() {
+		got = append(got, k)
+		switch k {
+		case "a":
+			m.MoveToFront("c") // moved; not produced
+			m.SetAfter("x", 10, "b")
+		case "b":
+			m.MoveAfter("b", "d")
+		}
+	}
+	if diff := cmp.Diff(got, []string{"a", "b", "d"}); diff != "" {
+		t.Fatalf("iteration gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	checkAll(t, m, []keyVal[string, int]{{"c", 2}, {"a", 0}, {"x", 10}, {"d", 3}, {"b", 1}})
+}
+
+func TestLenClear(t *testing.T) {
+	m := new(Map[string, int])
+	if got := m.Len(); got != 0 {
+		t.Fatalf("Len of empty map: got %d", got)
+	}
+	m.Clear()
+	checkAll(t, m, nil)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("a", 3)
+	if got := m.Len(); got != 2 {
+		t.Fatalf("Len: got %d; want 2", got)
+	}
+	m.Clear()
+	checkAll(t, m, nil)
+	if got := m.Len(); got != 0 {
+		t.Fatalf("Len after Clear: got %d", got)
+	}
+	m.Set("c", 4)
+	m.Set("d", 5)
+	checkAll(t, m, []keyVal[string, int]{{"c", 4}, {"d", 5}})
+
+	// Clear during iteration.
+	var got []string
+	for k := range m.Keys() {
+		got = append(got, k)
+		m.Clear()
+		m.Set("e", 6)
+	}
+	if diff := cmp.Diff(got, []string{"c"}); diff != "" {
+		t.Fatalf("iteration gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	checkAll(t, m, []keyVal[string, int]{{"e", 6}})
+}
+
+func TestClone(t *testing.T) {
+	m := New[string, int](InsertOrder)
+	m1 := m.Clone()
+	checkAll(t, m1, nil)
+	if got := m1.Order(); got != InsertOrder {
+		t.Fatalf("Clone has order %s; want InsertOrder", got)
+	}
+
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("c", 3)
+	m.Delete("b")
+	m2 := m.Clone()
+	m.Set("a", 10)
+	m.Set("d", 4)
+	m2.Set("e", 5)
+	checkAll(t, m, []keyVal[string, int]{{"a", 10}, {"c", 3}, {"d", 4}})
+	checkAll(t, m2, []keyVal[string, int]{{"a", 1}, {"c", 3}, {"e", 5}})
+	if got := m2.Order(); got != InsertOrder {
+		t.Fatalf("Clone has order %s; want InsertOrder", got)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	of := func(kvs ...keyVal[string, int]) *Map[string, int] {
+		m := new(Map[string, int])
+		for _, kv := range kvs {
+			m.Set(kv.Key, kv.Val)
+		}
+		return m
+	}
+	for _, tt := range []struct {
+		m1, m2 *Map[string, int]
+		want   bool
+	}{
+		{of(), of(), true},
+		{of(), New[string, int](AccessOrder), true},
+		{of(), of(keyVal[string, int]{"a", 1}), false},
+		{of(keyVal[string, int]{"a", 1}), of(keyVal[string, int]{"a", 1}), true},
+		{of(keyVal[string, int]{"a", 1}), of(keyVal[string, int]{"a", 2}), false},
+		{of(keyVal[string, int]{"a", 1}), of(keyVal[string, int]{"b", 1}), false},
+		{
+			of(keyVal[string, int]{"a", 1}, keyVal[string, int]{"b", 2}),
+			of(keyVal[string, int]{"a", 1}, keyVal[string, int]{"b", 2}),
+			true,
+		},
+		{
+			of(keyVal[string, int]{"a", 1}, keyVal[string, int]{"b", 2}),
+			of(keyVal[string, int]{"b", 2}, keyVal[string, int]{"a", 1}),
+			false,
+		},
+	} {
+		if got := Equal(tt.m1, tt.m2); got != tt.want {
+			t.Errorf("Equal(%v, %v): got %t", collectKVs(tt.m1.All()), collectKVs(tt.m2.All()), got)
+		}
+	}
+
+	m1 := of(keyVal[string, int]{"a", 1}, keyVal[string, int]{"b", 2})
+	m2 := new(Map[string, string])
+	m2.Set("a", "1")
+	m2.Set("b", "2")
+	eq := func(v1 int, v2 string) bool { return strconv.Itoa(v1) == v2 }
+	if !EqualFunc(m1, m2, eq) {
+		t.Error("EqualFunc: got false")
+	}
+	m2.Set("a", "1")
+	if EqualFunc(m1, m2, eq) {
+		t.Error("EqualFunc with different order: got true")
+	}
+}
+
+func TestDeleteFunc(t *testing.T) {
+	m := new(Map[string, int])
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		m.Set(k, i)
+	}
+	var visited []string
+	m.DeleteFunc(func(k string, v int) bool {
+		visited = append(visited, k)
+		return v%2 == 0
+	})
+	if diff := cmp.Diff(visited, []string{"a", "b", "c", "d", "e"}); diff != "" {
+		t.Fatalf("DeleteFunc visited incorrect sequence (-got, +want):\n%s", diff)
+	}
+	checkAll(t, m, []keyVal[string, int]{{"b", 1}, {"d", 3}})
+}
+
+func TestFirstLastPop(t *testing.T) {
+	m := New[string, int](AccessOrder)
+	checkFirstLast(t, m, "", 0, "", 0, false)
+	if k, v, ok := m.PopFirst(); k != "" || v != 0 || ok {
+		t.Fatalf("PopFirst on empty map: got (%q, %d, %t)", k, v, ok)
+	}
+	if k, v, ok := m.PopLast(); k != "" || v != 0 || ok {
+		t.Fatalf("PopLast on empty map: got (%q, %d, %t)", k, v, ok)
+	}
+
+	m.Set("a", 1)
+	checkFirstLast(t, m, "a", 1, "a", 1, true)
+	m.Set("b", 2)
+	m.Set("c", 3)
+	checkFirstLast(t, m, "a", 1, "c", 3, true)
+	// First and Last do not count as accesses.
+	checkAll(t, m, []keyVal[string, int]{{"a", 1}, {"b", 2}, {"c", 3}})
+
+	if k, v, ok := m.PopFirst(); k != "a" || v != 1 || !ok {
+		t.Fatalf("PopFirst: got (%q, %d, %t); want (\"a\", 1, true)", k, v, ok)
+	}
+	if k, v, ok := m.PopLast(); k != "c" || v != 3 || !ok {
+		t.Fatalf("PopLast: got (%q, %d, %t); want (\"c\", 3, true)", k, v, ok)
+	}
+	checkAll(t, m, []keyVal[string, int]{{"b", 2}})
+	if k, v, ok := m.PopLast(); k != "b" || v != 2 || !ok {
+		t.Fatalf("PopLast: got (%q, %d, %t); want (\"b\", 2, true)", k, v, ok)
+	}
+	checkAll(t, m, nil)
+}
+
+func checkFirstLast(t *testing.T, m *Map[string, int], firstK string, firstV int, lastK string, lastV int, wantOK bool) {
+	t.Helper()
+	if k, v, ok := m.First(); k != firstK || v != firstV || ok != wantOK {
+		t.Fatalf("First: got (%q, %d, %t); want (%q, %d, %t)", k, v, ok, firstK, firstV, wantOK)
+	}
+	if k, v, ok := m.Last(); k != lastK || v != lastV || ok != wantOK {
+		t.Fatalf("Last: got (%q, %d, %t); want (%q, %d, %t)", k, v, ok, lastK, lastV, wantOK)
+	}
+}
+
+func TestCollectInsert(t *testing.T) {
+	src := []keyVal[string, int]{{"a", 1}, {"b", 2}, {"a", 3}, {"c", 4}}
+	seq := func(yield func(string, int) bool) {
+		for _, kv := range src {
+			if !yield(kv.Key, kv.Val) {
+				return
+			}
+		}
+	}
+	m := Collect(seq)
+	checkAll(t, m, []keyVal[string, int]{{"b", 2}, {"a", 3}, {"c", 4}})
+
+	m2 := New[string, int](InsertOrder)
+	m2.Set("z", 0)
+	m2.Insert(seq)
+	checkAll(t, m2, []keyVal[string, int]{{"z", 0}, {"a", 3}, {"b", 2}, {"c", 4}})
+
+	m3 := Collect(m2.All())
+	if !Equal(m2, m3) {
+		t.Fatalf("Collect(m.All()) != m")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	m := new(Map[int, int])
+	var want []keyVal[int, int]
+	for i := range 1000 {
+		m.Set(i, i)
+		if i%100 == 0 {
+			want = append(want, keyVal[int, int]{i, i})
+		}
+	}
+	for i := range 1000 {
+		if i%100 != 0 {
+			m.Delete(i)
+		}
+	}
+	checkAll(t, m, want)
+	if len(m.entries.slots) > 100 {
+		t.Errorf("after deleting most entries, len(m.entries.slots) = %d", len(m.entries.slots))
+	}
+
+	// Slots are reused after compaction.
+	for i := range 1000 {
+		m.Delete(i)
+	}
+	checkAll(t, m, nil)
+	m.Set(1, 1)
+	m.Set(2, 2)
+	checkAll(t, m, []keyVal[int, int]{{1, 1}, {2, 2}})
+}
+
+func TestNoCompactDuringIteration(t *testing.T) {
+	m := new(Map[int, int])
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+	var got []int
+	for k := range m.Keys() {
+		got = append(got, k)
+		if k%2 == 0 {
+			m.Delete(k + 1)
+			m.Set(k+2000, k)
+		}
+		m.Delete(k)
+	}
+	var want []int
+	for i := 0; i < 1000; i += 2 {
+		want = append(want, i)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("iteration gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	var wantKVs []keyVal[int, int]
+	for i := 0; i < 1000; i += 2 {
+		wantKVs = append(wantKVs, keyVal[int, int]{i + 2000, i})
+	}
+	checkAll(t, m, wantKVs)
+}
+
+func TestConcurrentIteration(t *testing.T) {
+	m := new(Map[int, int])
+	for i := range 100 {
+		m.Set(i, i)
+	}
+	// Iterations that don't modify the map only read it,
+	// so they may run concurrently (run with -race).
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				for range m.All() {
+				}
+				for range m.Backward() {
+				}
+				m.Clone()
+				Equal(m, m)
+				if _, err := json.Marshal(m); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	checkInvariants(t, "after concurrent iteration", m)
+
+	// Afterward, deleted slots are reused.
+	n := len(m.entries.slots)
+	m.Delete(0)
+	m.Set(100, 100)
+	if got := len(m.entries.slots); got != n {
+		t.Errorf("after Delete and Set, got %d slots; want %d", got, n)
+	}
+}
+
 func checkGet[K, V comparable](t *testing.T, m *Map[K, V], key K, want V, wantOK bool) {
 	t.Helper()
 	got, ok := m.Get(key)
@@ -70,12 +832,12 @@ func checkAll[K, V comparable](t *testing.T, m *Map[K, V], kvs []keyVal[K, V]) {
 	var keys []K
 	var vals []V
 	for _, kv := range kvs {
-		got, ok := m.Get(kv.Key)
+		got, ok := m.Peek(kv.Key)
 		if !ok {
-			t.Fatalf("Get(%#v): got !ok", kv.Key)
+			t.Fatalf("Peek(%#v): got !ok", kv.Key)
 		}
 		if got != kv.Val {
-			t.Fatalf("Get(%#v): got %#v; want %#v", kv.Key, got, kv.Val)
+			t.Fatalf("Peek(%#v): got %#v; want %#v", kv.Key, got, kv.Val)
 		}
 		keys = append(keys, kv.Key)
 		vals = append(vals, kv.Val)
@@ -97,4 +859,19 @@ func checkAll[K, V comparable](t *testing.T, m *Map[K, V], kvs []keyVal[K, V]) {
 	if diff := cmp.Diff(gotKVs, kvs); diff != "" {
 		t.Fatalf("All gave incorrect sequence (-got, +want):\n%s", diff)
 	}
+
+	gotKVs = collectKVs(m.Backward())
+	wantBackward := slices.Clone(kvs)
+	slices.Reverse(wantBackward)
+	if diff := cmp.Diff(gotKVs, wantBackward); diff != "" {
+		t.Fatalf("Backward gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+}
+
+func collectKVs[K comparable, V any](seq iter.Seq2[K, V]) []keyVal[K, V] {
+	var kvs []keyVal[K, V]
+	for k, v := range seq {
+		kvs = append(kvs, keyVal[K, V]{k, v})
+	}
+	return kvs
 }
@@ -0,0 +1,530 @@
+package ordmap
+
+import (
+	"hash/maphash"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// A Persistent is an immutable ordered map. Operations that change the map,
+// such as Set and Delete, leave the original map unchanged and return a new
+// map that shares most of its structure with the original, so keeping many
+// versions of a map is cheap.
+//
+// A Persistent orders its entries in the same way as a Map that uses
+// UpdateOrder: Set on a new or existing key places the key at the end of the
+// map.
+//
+// Get and Delete take O(log n) time and allocate O(log n) memory, as does
+// Set, amortized: when the entries' sequence numbers outgrow the map, Set
+// renumbers them in O(n log n) time.
+// For building a map with many operations, use a PersistentBuilder, which
+// avoids most of the copying.
+//
+// The zero value of a Persistent is an empty map ready to use.
+// A Persistent is safe for concurrent use by multiple goroutines.
+type Persistent[K comparable, V any] struct {
+	// keys maps each key to the sequence number of its entry.
+	keys *hamtNode[K]
+	// order maps sequence numbers to entries. Entries are given increasing
+	// sequence numbers as they are set, so iterating over order in sequence
+	// number order produces the entries in UpdateOrder.
+	order  *trieNode[K, V]
+	height int // height of the order trie; leaves are at height 0
+	n      int
+	next   uint64 // sequence number of the next entry to be set
+}
+
+// Len returns the number of entries in the map.
+func (p Persistent[K, V]) Len() int {
+	return p.n
+}
+
+// Get returns the value stored in the map for a key,
+// or the zero value of V if no value is present.
+// The ok result indicates whether value was found in the map.
+func (p Persistent[K, V]) Get(key K) (val V, ok bool) {
+	seq, ok := p.keys.get(hashKey(key), key, 0)
+	if !ok {
+		return val, false
+	}
+	_, v := p.order.get(seq, p.height)
+	return v, true
+}
+
+// Set returns a map that is like p but in which key has the value val,
+// positioned at the end of the map.
+func (p Persistent[K, V]) Set(key K, val V) Persistent[K, V] {
+	return p.set(nil, key, val)
+}
+
+// Delete returns a map that is like p but without key.
+// If key is not present, Delete returns p.
+func (p Persistent[K, V]) Delete(key K) Persistent[K, V] {
+	return p.delete(nil, key)
+}
+
+// All returns an iterator over the key-value pairs in the map, in order.
+func (p Persistent[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		p.order.all(p.height, false, yield)
+	}
+}
+
+// Backward returns an iterator over the key-value pairs in the map,
+// in reverse order.
+func (p Persistent[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		p.order.all(p.height, true, yield)
+	}
+}
+
+// Keys returns an iterator over the keys in the map, in order.
+func (p Persistent[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		p.order.all(p.height, false, func(k K, _ V) bool { return yield(k) })
+	}
+}
+
+// Values returns an iterator over the values in the map, in order.
+func (p Persistent[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		p.order.all(p.height, false, func(_ K, v V) bool { return yield(v) })
+	}
+}
+
+// Builder returns a PersistentBuilder whose initial contents are those of p.
+// Creating the builder takes O(1) time.
+func (p Persistent[K, V]) Builder() *PersistentBuilder[K, V] {
+	return &PersistentBuilder[K, V]{p: p, edit: new(edit)}
+}
+
+func (p Persistent[K, V]) set(e *edit, key K, val V) Persistent[K, V] {
+	if p.next == trieCap(p.height) && uint64(p.n) <= p.next/2 {
+		// Rather than adding a level to the trie to hold sequence numbers
+		// that are mostly unused, start over from 0. This keeps the height
+		// of the trie logarithmic in the size of the map, not in the number
+		// of times it has been modified. Since at least n operations led to
+		// this point, the cost is amortized over them.
+		p = p.renumber(e)
+	}
+	h := hashKey(key)
+	seq := p.next
+	p.next++
+	keys, old, replaced := p.keys.set(e, h, key, seq, 0)
+	p.keys = keys
+	if replaced {
+		p.order = p.order.delete(e, old, p.height)
+	} else {
+		p.n++
+	}
+	for seq >= trieCap(p.height) {
+		// The trie is full; add a level above the root.
+		p.order = &trieNode[K, V]{edit: e, bits: 1, kids: []*trieNode[K, V]{p.order}}
+		p.height++
+	}
+	p.order = p.order.set(e, seq, p.height, key, val)
+	return p
+}
+
+// renumber returns a map with the same entries as p, numbered from 0.
+func (p Persistent[K, V]) renumber(e *edit) Persistent[K, V] {
+	if e == nil {
+		// The new map's nodes are not shared yet,
+		// so they may be built in place.
+		e = new(edit)
+	}
+	var q Persistent[K, V]
+	for k, v := range p.All() {
+		q = q.set(e, k, v)
+	}
+	return q
+}
+
+func (p Persistent[K, V]) delete(e *edit, key K) Persistent[K, V] {
+	keys, seq, ok := p.keys.delete(e, hashKey(key), key, 0)
+	if !ok {
+		return p
+	}
+	p.keys = keys
+	p.order = p.order.delete(e, seq, p.height)
+	p.n--
+	return p
+}
+
+// A PersistentBuilder builds a Persistent map efficiently by modifying it in
+// place, copying only the parts of the map that it shares with
+// Persistent maps.
+//
+// A PersistentBuilder must be created with Persistent.Builder
+// (the zero value of Persistent builds an empty map).
+// A PersistentBuilder is not safe for concurrent use by multiple goroutines.
+type PersistentBuilder[K comparable, V any] struct {
+	p Persistent[K, V]
+	// edit identifies the nodes that belong to the builder, which it may
+	// modify in place. Nodes with any other edit are shared.
+	edit *edit
+}
+
+// An edit is a token that marks the nodes owned by a PersistentBuilder.
+// It must not be zero-sized so that each edit has a distinct address.
+type edit struct{ _ byte }
+
+// Len returns the number of entries in the map being built.
+func (b *PersistentBuilder[K, V]) Len() int {
+	return b.p.Len()
+}
+
+// Get returns the value stored in the map being built for a key,
+// as Persistent.Get does.
+func (b *PersistentBuilder[K, V]) Get(key K) (val V, ok bool) {
+	return b.p.Get(key)
+}
+
+// Set sets the value for a key, positioning it at the end of the map.
+func (b *PersistentBuilder[K, V]) Set(key K, val V) {
+	b.p = b.p.set(b.edit, key, val)
+}
+
+// Delete deletes the value for a key.
+// If key is not present, Delete is a no-op.
+func (b *PersistentBuilder[K, V]) Delete(key K) {
+	b.p = b.p.delete(b.edit, key)
+}
+
+// Persistent returns the map that has been built.
+// The builder may continue to be used after calling Persistent;
+// later changes do not affect the returned map.
+func (b *PersistentBuilder[K, V]) Persistent() Persistent[K, V] {
+	// The nodes owned by the builder are now shared with the result,
+	// so the builder must not modify them again.
+	b.edit = new(edit)
+	return b.p
+}
+
+var hashSeed = maphash.MakeSeed()
+
+func hashKey[K comparable](k K) uint64 {
+	return maphash.Comparable(hashSeed, k)
+}
+
+// The keys of a Persistent are stored in a hash array mapped trie (HAMT).
+// Each node consumes hamtBits bits of the hash to choose among its slots.
+// Below the depth at which the hash is exhausted, nodes are collision
+// nodes, which hold their entries in a list.
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+type hamtNode[K comparable] struct {
+	edit   *edit
+	bitmap uint32 // occupied slots; unused in collision nodes
+	slots  []hamtSlot[K]
+}
+
+// A hamtSlot is either a child node (if child is non-nil) or an entry.
+type hamtSlot[K comparable] struct {
+	child *hamtNode[K]
+	hash  uint64
+	k     K
+	seq   uint64
+}
+
+func isCollisionNode(shift uint) bool {
+	return shift >= 64
+}
+
+// pos returns the slot position for hash h in n at the given shift and
+// reports whether the slot is occupied.
+func (n *hamtNode[K]) pos(h uint64, shift uint) (i int, bit uint32, ok bool) {
+	bit = 1 << ((h >> shift) & hamtMask)
+	return bits.OnesCount32(n.bitmap & (bit - 1)), bit, n.bitmap&bit != 0
+}
+
+func (n *hamtNode[K]) get(h uint64, k K, shift uint) (seq uint64, ok bool) {
+	for n != nil {
+		if isCollisionNode(shift) {
+			for _, s := range n.slots {
+				if s.k == k {
+					return s.seq, true
+				}
+			}
+			return 0, false
+		}
+		i, _, ok := n.pos(h, shift)
+		if !ok {
+			return 0, false
+		}
+		s := &n.slots[i]
+		if s.child == nil {
+			if s.hash == h && s.k == k {
+				return s.seq, true
+			}
+			return 0, false
+		}
+		n = s.child
+		shift += hamtBits
+	}
+	return 0, false
+}
+
+// mutable returns n if it is owned by e, and otherwise a copy of n owned
+// by e. A nil e owns nothing.
+func (n *hamtNode[K]) mutable(e *edit) *hamtNode[K] {
+	if e != nil && n.edit == e {
+		return n
+	}
+	return &hamtNode[K]{
+		edit:   e,
+		bitmap: n.bitmap,
+		slots:  append([]hamtSlot[K](nil), n.slots...),
+	}
+}
+
+// set sets the sequence number for k, which has hash h. It returns the
+// resulting node and, if k was already present, its old sequence number.
+func (n *hamtNode[K]) set(e *edit, h uint64, k K, seq uint64, shift uint) (_ *hamtNode[K], old uint64, replaced bool) {
+	leaf := hamtSlot[K]{hash: h, k: k, seq: seq}
+	if n == nil {
+		n = &hamtNode[K]{edit: e}
+		if isCollisionNode(shift) {
+			n.slots = []hamtSlot[K]{leaf}
+			return n, 0, false
+		}
+		_, bit, _ := n.pos(h, shift)
+		n.bitmap = bit
+		n.slots = []hamtSlot[K]{leaf}
+		return n, 0, false
+	}
+	if isCollisionNode(shift) {
+		for i, s := range n.slots {
+			if s.k == k {
+				n = n.mutable(e)
+				n.slots[i].seq = seq
+				return n, s.seq, true
+			}
+		}
+		n = n.mutable(e)
+		n.slots = append(n.slots, leaf)
+		return n, 0, false
+	}
+	i, bit, ok := n.pos(h, shift)
+	if !ok {
+		n = n.mutable(e)
+		n.bitmap |= bit
+		n.slots = append(n.slots, hamtSlot[K]{})
+		copy(n.slots[i+1:], n.slots[i:])
+		n.slots[i] = leaf
+		return n, 0, false
+	}
+	s := n.slots[i]
+	switch {
+	case s.child != nil:
+		child, old, replaced := s.child.set(e, h, k, seq, shift+hamtBits)
+		if child != s.child {
+			n = n.mutable(e)
+			n.slots[i].child = child
+		}
+		return n, old, replaced
+	case s.hash == h && s.k == k:
+		n = n.mutable(e)
+		n.slots[i].seq = seq
+		return n, s.seq, true
+	default:
+		// Push the existing entry down into a new child node.
+		child, _, _ := (*hamtNode[K])(nil).set(e, s.hash, s.k, s.seq, shift+hamtBits)
+		child, _, _ = child.set(e, h, k, seq, shift+hamtBits)
+		n = n.mutable(e)
+		n.slots[i] = hamtSlot[K]{child: child}
+		return n, 0, false
+	}
+}
+
+// delete deletes k, which has hash h. It returns the resulting node, which is
+// nil if it is empty, and the sequence number of the deleted entry.
+func (n *hamtNode[K]) delete(e *edit, h uint64, k K, shift uint) (_ *hamtNode[K], seq uint64, ok bool) {
+	if n == nil {
+		return nil, 0, false
+	}
+	if isCollisionNode(shift) {
+		for i, s := range n.slots {
+			if s.k == k {
+				return n.removeSlot(e, i, 0), s.seq, true
+			}
+		}
+		return n, 0, false
+	}
+	i, bit, ok := n.pos(h, shift)
+	if !ok {
+		return n, 0, false
+	}
+	s := n.slots[i]
+	if s.child == nil {
+		if s.hash != h || s.k != k {
+			return n, 0, false
+		}
+		return n.removeSlot(e, i, bit), s.seq, true
+	}
+	child, seq, ok := s.child.delete(e, h, k, shift+hamtBits)
+	if !ok {
+		return n, 0, false
+	}
+	if child == nil {
+		return n.removeSlot(e, i, bit), seq, true
+	}
+	n = n.mutable(e)
+	if len(child.slots) == 1 && child.slots[0].child == nil {
+		// Pull a lone entry up into this node.
+		n.slots[i] = child.slots[0]
+	} else {
+		n.slots[i].child = child
+	}
+	return n, seq, true
+}
+
+// removeSlot removes the slot at position i, which corresponds to bitmap bit.
+// It returns nil if n becomes empty.
+func (n *hamtNode[K]) removeSlot(e *edit, i int, bit uint32) *hamtNode[K] {
+	if len(n.slots) == 1 {
+		return nil
+	}
+	n = n.mutable(e)
+	n.bitmap &^= bit
+	copy(n.slots[i:], n.slots[i+1:])
+	n.slots[len(n.slots)-1] = hamtSlot[K]{}
+	n.slots = n.slots[:len(n.slots)-1]
+	return n
+}
+
+// The entries of a Persistent are stored in a radix trie keyed by sequence
+// number. Each node consumes trieBits bits of the sequence number.
+// Like the nodes of the HAMT, the nodes store only their occupied slots,
+// so the trie's memory is proportional to the number of entries.
+// Nodes whose subtrees become empty are removed.
+
+const (
+	trieBits = 5
+	trieMask = 1<<trieBits - 1
+)
+
+// trieCap returns the number of sequence numbers that a trie of the given
+// height can hold.
+func trieCap(height int) uint64 {
+	if trieBits*(height+1) >= 64 {
+		return 1<<64 - 1
+	}
+	return 1 << (trieBits * (height + 1))
+}
+
+type trieNode[K, V any] struct {
+	edit *edit
+	bits uint32 // occupied slots
+	// Interior nodes use kids; leaves use keys and vals.
+	// They hold the occupied slots in order.
+	kids []*trieNode[K, V]
+	keys []K
+	vals []V
+}
+
+// pos returns the position in n's slices of the slot for seq at the
+// given height.
+func (n *trieNode[K, V]) pos(seq uint64, height int) (i int, bit uint32) {
+	bit = 1 << ((seq >> (trieBits * height)) & trieMask)
+	return bits.OnesCount32(n.bits & (bit - 1)), bit
+}
+
+func (n *trieNode[K, V]) mutable(e *edit) *trieNode[K, V] {
+	if e != nil && n.edit == e {
+		return n
+	}
+	return &trieNode[K, V]{
+		edit: e,
+		bits: n.bits,
+		kids: slices.Clone(n.kids),
+		keys: slices.Clone(n.keys),
+		vals: slices.Clone(n.vals),
+	}
+}
+
+// get returns the entry with sequence number seq,
+// which must be present in the trie.
+func (n *trieNode[K, V]) get(seq uint64, height int) (K, V) {
+	for ; height > 0; height-- {
+		i, _ := n.pos(seq, height)
+		n = n.kids[i]
+	}
+	i, _ := n.pos(seq, 0)
+	return n.keys[i], n.vals[i]
+}
+
+// set adds the entry with sequence number seq, which must be greater than
+// the sequence numbers of the entries in the trie.
+func (n *trieNode[K, V]) set(e *edit, seq uint64, height int, k K, v V) *trieNode[K, V] {
+	if n == nil {
+		n = &trieNode[K, V]{edit: e}
+	} else {
+		n = n.mutable(e)
+	}
+	// Since seq is the greatest sequence number, its slot is the last one.
+	i, bit := n.pos(seq, height)
+	switch {
+	case height == 0:
+		n.keys = append(n.keys, k)
+		n.vals = append(n.vals, v)
+	case n.bits&bit != 0:
+		n.kids[i] = n.kids[i].set(e, seq, height-1, k, v)
+	default:
+		n.kids = append(n.kids, (*trieNode[K, V])(nil).set(e, seq, height-1, k, v))
+	}
+	n.bits |= bit
+	return n
+}
+
+// delete removes the entry with sequence number seq, which must be present.
+// It returns nil if n becomes empty.
+func (n *trieNode[K, V]) delete(e *edit, seq uint64, height int) *trieNode[K, V] {
+	i, bit := n.pos(seq, height)
+	if height > 0 {
+		if kid := n.kids[i].delete(e, seq, height-1); kid != nil {
+			n = n.mutable(e)
+			n.kids[i] = kid
+			return n
+		}
+	}
+	if n.bits == bit {
+		return nil
+	}
+	n = n.mutable(e)
+	n.bits &^= bit
+	if height == 0 {
+		n.keys = slices.Delete(n.keys, i, i+1)
+		n.vals = slices.Delete(n.vals, i, i+1)
+	} else {
+		n.kids = slices.Delete(n.kids, i, i+1)
+	}
+	return n
+}
+
+func (n *trieNode[K, V]) all(height int, backward bool, yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	size := bits.OnesCount32(n.bits)
+	for j := range size {
+		i := j
+		if backward {
+			i = size - 1 - j
+		}
+		if height == 0 {
+			if !yield(n.keys[i], n.vals[i]) {
+				return false
+			}
+		} else if !n.kids[i].all(height-1, backward, yield) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,247 @@
+package ordmap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPersistent(t *testing.T) {
+	var p0 Persistent[string, int]
+	checkPersistent(t, p0, nil)
+	p1 := p0.Set("a", 1)
+	p2 := p1.Set("b", 2).Set("c", 3)
+	p3 := p2.Set("a", 4)
+	p4 := p3.Delete("c")
+	p5 := p4.Delete("x")
+
+	checkPersistent(t, p0, nil)
+	checkPersistent(t, p1, []keyVal[string, int]{{"a", 1}})
+	checkPersistent(t, p2, []keyVal[string, int]{{"a", 1}, {"b", 2}, {"c", 3}})
+	checkPersistent(t, p3, []keyVal[string, int]{{"b", 2}, {"c", 3}, {"a", 4}})
+	checkPersistent(t, p4, []keyVal[string, int]{{"b", 2}, {"a", 4}})
+	checkPersistent(t, p5, []keyVal[string, int]{{"b", 2}, {"a", 4}})
+	if v, ok := p4.Get("c"); v != 0 || ok {
+		t.Fatalf(`Get("c"): got (%d, %t); want (0, false)`, v, ok)
+	}
+
+	// Delete everything and start over.
+	p6 := p5.Delete("a").Delete("b")
+	checkPersistent(t, p6, nil)
+	checkPersistent(t, p6.Set("d", 5), []keyVal[string, int]{{"d", 5}})
+}
+
+func TestPersistentBuilder(t *testing.T) {
+	var p0 Persistent[int, int]
+	p0 = p0.Set(-1, -1)
+	b := p0.Builder()
+	for i := range 100 {
+		b.Set(i, i)
+	}
+	b.Set(-1, -2)
+	for i := 0; i < 100; i += 2 {
+		b.Delete(i)
+	}
+	if got := b.Len(); got != 51 {
+		t.Fatalf("Len: got %d; want 51", got)
+	}
+	if v, ok := b.Get(-1); v != -2 || !ok {
+		t.Fatalf("Get(-1): got (%d, %t); want (-2, true)", v, ok)
+	}
+	p1 := b.Persistent()
+
+	// Further changes to the builder don't affect p1.
+	b.Set(1, 100)
+	b.Delete(3)
+	p2 := b.Persistent()
+	b.Delete(5)
+
+	checkPersistent(t, p0, []keyVal[int, int]{{-1, -1}})
+	var want1 []keyVal[int, int]
+	for i := 1; i < 100; i += 2 {
+		want1 = append(want1, keyVal[int, int]{i, i})
+	}
+	want1 = append(want1, keyVal[int, int]{-1, -2})
+	checkPersistent(t, p1, want1)
+	want2 := slices.Clone(want1[2:])
+	want2 = append(want2, keyVal[int, int]{1, 100})
+	checkPersistent(t, p2, want2)
+}
+
+func TestPersistentRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	type version struct {
+		p     Persistent[int, int]
+		model []keyVal[int, int]
+	}
+	var versions []version
+	var p Persistent[int, int]
+	var model []keyVal[int, int]
+	b := p.Builder()
+	for op := range 20000 {
+		k := r.IntN(300)
+		i := slices.IndexFunc(model, func(kv keyVal[int, int]) bool { return kv.Key == k })
+		useBuilder := (op/1000)%2 == 1
+		if r.IntN(3) == 0 {
+			if useBuilder {
+				b.Delete(k)
+			} else {
+				p = p.Delete(k)
+			}
+			if i >= 0 {
+				model = slices.Delete(model, i, i+1)
+			}
+		} else {
+			if useBuilder {
+				b.Set(k, op)
+			} else {
+				p = p.Set(k, op)
+			}
+			if i >= 0 {
+				model = slices.Delete(model, i, i+1)
+			}
+			model = append(model, keyVal[int, int]{k, op})
+		}
+		switch {
+		case op%1000 == 999 && useBuilder:
+			p = b.Persistent()
+		case op%1000 == 999:
+			b = p.Builder()
+		}
+		if op%250 == 0 && !useBuilder {
+			versions = append(versions, version{p, slices.Clone(model)})
+		}
+	}
+	for _, v := range versions {
+		checkPersistent(t, v.p, v.model)
+	}
+}
+
+func TestPersistentChurn(t *testing.T) {
+	// Setting the same few keys over and over renumbers the entries rather
+	// than growing the trie, which stays small.
+	var p Persistent[int, int]
+	b := p.Builder()
+	for i := range 100_000 {
+		p = p.Set(i%10, i)
+		b.Set(i%20, i)
+		if i == 50_000 {
+			old := p
+			for range 1000 {
+				p = p.Set(0, 0)
+			}
+			checkPersistent(t, old, []keyVal[int, int]{
+				{1, 49_991}, {2, 49_992}, {3, 49_993}, {4, 49_994}, {5, 49_995},
+				{6, 49_996}, {7, 49_997}, {8, 49_998}, {9, 49_999}, {0, 50_000},
+			})
+		}
+	}
+	var want []keyVal[int, int]
+	for i := 99_990; i < 100_000; i++ {
+		want = append(want, keyVal[int, int]{i % 10, i})
+	}
+	checkPersistent(t, p, want)
+	for _, q := range []Persistent[int, int]{p, b.Persistent()} {
+		if q.height > 1 {
+			t.Errorf("after churn, map of %d entries has trie height %d", q.Len(), q.height)
+		}
+		if got := trieSlots(q.order, q.height); got > 3*q.Len() {
+			t.Errorf("after churn, map of %d entries has %d trie slots", q.Len(), got)
+		}
+	}
+}
+
+// trieSlots returns the total length of the slices in the trie rooted at n.
+func trieSlots[K, V any](n *trieNode[K, V], height int) int {
+	if n == nil {
+		return 0
+	}
+	total := len(n.kids) + len(n.keys) + len(n.vals)
+	for _, kid := range n.kids {
+		total += trieSlots(kid, height-1)
+	}
+	return total
+}
+
+func TestPersistentHashCollisions(t *testing.T) {
+	// Use the HAMT directly with made-up hashes to exercise collision nodes.
+	var n *hamtNode[string]
+	hashes := map[string]uint64{
+		"a": 1,
+		"b": 1,
+		"c": 1,
+		"d": 1 | 1<<63,
+		"e": 2,
+	}
+	seq := uint64(0)
+	for k, h := range hashes {
+		n, _, _ = n.set(nil, h, k, seq, 0)
+		seq++
+	}
+	for k, h := range hashes {
+		if _, ok := n.get(h, k, 0); !ok {
+			t.Fatalf("get(%q): not found", k)
+		}
+	}
+	if _, ok := n.get(1, "x", 0); ok {
+		t.Fatal(`get("x"): found`)
+	}
+	n1, old, replaced := n.set(nil, 1, "b", 100, 0)
+	if !replaced {
+		t.Fatal(`set("b"): not replaced`)
+	}
+	if s, _ := n1.get(1, "b", 0); s != 100 {
+		t.Fatalf(`get("b") after set: got %d`, s)
+	}
+	if s, _ := n.get(1, "b", 0); s != old {
+		t.Fatalf(`get("b") in original: got %d; want %d`, s, old)
+	}
+	for _, k := range []string{"a", "c", "d", "b", "e"} {
+		var ok bool
+		n1, _, ok = n1.delete(nil, hashes[k], k, 0)
+		if !ok {
+			t.Fatalf("delete(%q): not found", k)
+		}
+		if _, ok := n1.get(hashes[k], k, 0); ok {
+			t.Fatalf("get(%q) after delete: found", k)
+		}
+	}
+	if n1 != nil {
+		t.Fatal("HAMT not empty after deleting all keys")
+	}
+}
+
+func checkPersistent[K comparable, V any](t *testing.T, p Persistent[K, V], want []keyVal[K, V]) {
+	t.Helper()
+	if diff := cmp.Diff(collectKVs(p.All()), want); diff != "" {
+		t.Fatalf("All gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	backward := collectKVs(p.Backward())
+	slices.Reverse(backward)
+	if diff := cmp.Diff(backward, want); diff != "" {
+		t.Fatalf("Backward gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	if got := p.Len(); got != len(want) {
+		t.Fatalf("Len: got %d; want %d", got, len(want))
+	}
+	for _, kv := range want {
+		v, ok := p.Get(kv.Key)
+		if !ok || !cmp.Equal(v, kv.Val) {
+			t.Fatalf("Get(%v): got (%v, %t); want (%v, true)", kv.Key, v, ok, kv.Val)
+		}
+	}
+	var keys []K
+	var vals []V
+	for _, kv := range want {
+		keys = append(keys, kv.Key)
+		vals = append(vals, kv.Val)
+	}
+	if diff := cmp.Diff(slices.Collect(p.Keys()), keys); diff != "" {
+		t.Fatalf("Keys gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(slices.Collect(p.Values()), vals); diff != "" {
+		t.Fatalf("Values gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,154 @@
+package ordmap
+
+import (
+	"iter"
+	"sync"
+)
+
+// A SyncMap is an ordered map that is safe for concurrent use by multiple
+// goroutines. It orders its entries in the same way as a Map with the same
+// Order.
+//
+// A SyncMap is a Map guarded by a sync.RWMutex, so calls that only read the
+// map run in parallel with one another. In AccessOrder, however, Load moves
+// the entry it finds and so must take the lock exclusively.
+//
+// The zero value of a SyncMap is an empty map that uses UpdateOrder.
+// Use NewSyncMap to create a SyncMap with a different Order.
+// A SyncMap must not be copied after first use.
+type SyncMap[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  Map[K, V]
+}
+
+// NewSyncMap creates an empty SyncMap that uses the given Order.
+// NewSyncMap panics if order is not a valid Order.
+func NewSyncMap[K comparable, V any](order Order) *SyncMap[K, V] {
+	checkOrder(order)
+	return &SyncMap[K, V]{m: Map[K, V]{order: order}}
+}
+
+// Order returns the ordering rule used by m.
+func (m *SyncMap[K, V]) Order() Order {
+	return m.m.order
+}
+
+// Load returns the value stored in the map for a key,
+// or the zero value of V if no value is present.
+// The ok result indicates whether value was found in the map.
+// As with Map.Get, in AccessOrder Load moves the entry to the end of the map.
+func (m *SyncMap[K, V]) Load(key K) (value V, ok bool) {
+	if m.m.order == AccessOrder {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.m.Get(key)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Peek(key)
+}
+
+// Peek is like Load but it never moves the entry.
+func (m *SyncMap[K, V]) Peek(key K) (value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Peek(key)
+}
+
+// Store sets the value for a key, positioning it as Map.Set does.
+func (m *SyncMap[K, V]) Store(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m.Set(key, value)
+}
+
+// LoadOrStore returns the existing value for the key if present. Otherwise,
+// it stores and returns the given value. The loaded result is true if the
+// value was loaded, false if stored.
+// In AccessOrder, loading the value moves the entry to the end of the map.
+func (m *SyncMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.m.Get(key); ok {
+		return v, true
+	}
+	m.m.Set(key, value)
+	return value, false
+}
+
+// LoadAndDelete deletes the value for a key, returning the previous value if
+// any. The loaded result reports whether the key was present.
+func (m *SyncMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, loaded = m.m.Peek(key)
+	if loaded {
+		m.m.Delete(key)
+	}
+	return value, loaded
+}
+
+// Delete deletes the value for a key.
+func (m *SyncMap[K, V]) Delete(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m.Delete(key)
+}
+
+// Clear deletes all the entries in the map.
+func (m *SyncMap[K, V]) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m.Clear()
+}
+
+// Len returns the number of entries in the map.
+func (m *SyncMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Len()
+}
+
+// All returns an iterator over the key-value pairs in the map, in order.
+//
+// When iteration begins, All copies the entries of the map while holding
+// the read lock, and then produces the copies without holding the lock.
+// Therefore the iteration reflects a consistent snapshot of the map, the
+// loop body may call any method on m, and the iteration takes O(n) time and
+// space even if the loop exits early.
+func (m *SyncMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, kv := range m.snapshot() {
+			if !yield(kv.k, kv.v) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys in the map, in order.
+// See All for the snapshot behavior.
+func (m *SyncMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for _, kv := range m.snapshot() {
+			if !yield(kv.k) {
+				return
+			}
+		}
+	}
+}
+
+type pair[K, V any] struct {
+	k K
+	v V
+}
+
+func (m *SyncMap[K, V]) snapshot() []pair[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	kvs := make([]pair[K, V], 0, m.m.Len())
+	for k, v := range m.m.All() {
+		kvs = append(kvs, pair[K, V]{k, v})
+	}
+	return kvs
+}
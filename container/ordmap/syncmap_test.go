@@ -0,0 +1,191 @@
+package ordmap
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSyncMap(t *testing.T) {
+	var m SyncMap[string, int]
+	if got := m.Order(); got != UpdateOrder {
+		t.Fatalf("zero SyncMap has order %s", got)
+	}
+	if v, ok := m.Load("a"); v != 0 || ok {
+		t.Fatalf(`Load("a") on empty map: got (%d, %t)`, v, ok)
+	}
+	m.Store("a", 1)
+	m.Store("b", 2)
+	m.Store("c", 3)
+	m.Store("a", 4)
+	checkSyncMap(t, &m, []keyVal[string, int]{{"b", 2}, {"c", 3}, {"a", 4}})
+
+	if v, loaded := m.LoadOrStore("b", 5); v != 2 || !loaded {
+		t.Fatalf(`LoadOrStore("b", 5): got (%d, %t); want (2, true)`, v, loaded)
+	}
+	if v, loaded := m.LoadOrStore("d", 6); v != 6 || loaded {
+		t.Fatalf(`LoadOrStore("d", 6): got (%d, %t); want (6, false)`, v, loaded)
+	}
+	checkSyncMap(t, &m, []keyVal[string, int]{{"b", 2}, {"c", 3}, {"a", 4}, {"d", 6}})
+
+	if v, loaded := m.LoadAndDelete("c"); v != 3 || !loaded {
+		t.Fatalf(`LoadAndDelete("c"): got (%d, %t); want (3, true)`, v, loaded)
+	}
+	if v, loaded := m.LoadAndDelete("c"); v != 0 || loaded {
+		t.Fatalf(`second LoadAndDelete("c"): got (%d, %t); want (0, false)`, v, loaded)
+	}
+	m.Delete("b")
+	checkSyncMap(t, &m, []keyVal[string, int]{{"a", 4}, {"d", 6}})
+	m.Clear()
+	checkSyncMap(t, &m, nil)
+}
+
+func TestSyncMapAccessOrder(t *testing.T) {
+	m := NewSyncMap[string, int](AccessOrder)
+	m.Store("a", 1)
+	m.Store("b", 2)
+	m.Store("c", 3)
+	m.Load("a")
+	checkSyncMap(t, m, []keyVal[string, int]{{"b", 2}, {"c", 3}, {"a", 1}})
+	m.Peek("b")
+	checkSyncMap(t, m, []keyVal[string, int]{{"b", 2}, {"c", 3}, {"a", 1}})
+	m.LoadOrStore("b", 0)
+	checkSyncMap(t, m, []keyVal[string, int]{{"c", 3}, {"a", 1}, {"b", 2}})
+}
+
+func TestSyncMapSnapshot(t *testing.T) {
+	var m SyncMap[int, int]
+	for i := range 5 {
+		m.Store(i, i)
+	}
+	// The loop body can modify the map (which would deadlock if the
+	// iterator held the lock), and the iteration sees the snapshot.
+	var keys []int
+	for k, v := range m.All() {
+		keys = append(keys, k)
+		m.Delete(k + 1)
+		m.Store(k+10, v)
+	}
+	if diff := cmp.Diff(keys, []int{0, 1, 2, 3, 4}); diff != "" {
+		t.Fatalf("All gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	keys = slices.Collect(m.Keys())
+	if diff := cmp.Diff(keys, []int{0, 10, 11, 12, 13, 14}); diff != "" {
+		t.Fatalf("Keys gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+}
+
+func TestSyncMapConcurrent(t *testing.T) {
+	// This test is mainly useful under the race detector.
+	m := NewSyncMap[int, int](AccessOrder)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				k := (g*7919 + i) % 100
+				switch i % 5 {
+				case 0:
+					m.Store(k, i)
+				case 1:
+					m.LoadOrStore(k, i)
+				case 2:
+					m.LoadAndDelete(k)
+				case 3:
+					for range m.All() {
+						break
+					}
+				default:
+					m.Load(k)
+					m.Peek(k)
+					m.Len()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	n := 0
+	for range m.All() {
+		n++
+	}
+	if got := m.Len(); got != n {
+		t.Fatalf("Len: got %d; All produced %d entries", got, n)
+	}
+}
+
+func checkSyncMap(t *testing.T, m *SyncMap[string, int], want []keyVal[string, int]) {
+	t.Helper()
+	if diff := cmp.Diff(collectKVs(m.All()), want); diff != "" {
+		t.Fatalf("All gave incorrect sequence (-got, +want):\n%s", diff)
+	}
+	if got := m.Len(); got != len(want) {
+		t.Fatalf("Len: got %d; want %d", got, len(want))
+	}
+	for _, kv := range want {
+		if v, ok := m.Peek(kv.Key); v != kv.Val || !ok {
+			t.Fatalf("Peek(%q): got (%d, %t); want (%d, true)", kv.Key, v, ok, kv.Val)
+		}
+	}
+}
+
+// mutexMap is a Map guarded by a sync.Mutex, for comparison with SyncMap.
+type mutexMap[K comparable, V any] struct {
+	mu sync.Mutex
+	m  Map[K, V]
+}
+
+func (m *mutexMap[K, V]) Load(key K) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.m.Get(key)
+}
+
+func (m *mutexMap[K, V]) Store(key K, v V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m.Set(key, v)
+}
+
+type syncBenchMap interface {
+	Load(int) (int, bool)
+	Store(int, int)
+}
+
+func BenchmarkSyncMap(b *testing.B) {
+	const size = 10_000
+	impls := []struct {
+		name   string
+		newMap func() syncBenchMap
+	}{
+		{"impl=rwmutex", func() syncBenchMap { return new(SyncMap[int, int]) }},
+		{"impl=mutex", func() syncBenchMap { return new(mutexMap[int, int]) }},
+	}
+	// writes is the number of stores per 100 operations.
+	for _, writes := range []int{0, 1, 10, 50} {
+		for _, impl := range impls {
+			b.Run(fmt.Sprintf("writes=%d%%/%s", writes, impl.name), func(b *testing.B) {
+				m := impl.newMap()
+				for i := range size {
+					m.Store(i, i)
+				}
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := 0
+					for pb.Next() {
+						k := (i * 7919) % size
+						if i%100 < writes {
+							m.Store(k, i)
+						} else {
+							m.Load(k)
+						}
+						i++
+					}
+				})
+			})
+		}
+	}
+}
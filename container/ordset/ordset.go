@@ -0,0 +1,224 @@
+// Package ordset defines a Set type that holds a set of elements
+// and remembers the order in which they were added.
+package ordset
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/cespare/next/container/ordmap"
+)
+
+// A Set is a set of elements of some comparable type that iterates over its
+// elements in the order they were first added. Adding an element that is
+// already present does not change its position.
+//
+// Sets are implemented using ordmap.Map, and have similar performance
+// characteristics. Unlike container/set.Set, a Set must not be copied after
+// first use; pass around a *Set instead.
+//
+// The zero value of a Set is an empty set ready to use.
+// As with maps, concurrent calls to functions and methods that read values,
+// including iterating over a set, are fine; concurrent calls to functions and
+// methods that write values are racy.
+type Set[E comparable] struct {
+	// Elements are added with Set only if they are not already present,
+	// so the map's UpdateOrder is the insertion order.
+	m ordmap.Map[E, struct{}]
+}
+
+// Of returns a new set containing the listed elements,
+// in the order they first appear in v.
+func Of[E comparable](v ...E) *Set[E] {
+	var s Set[E]
+	s.Add(v...)
+	return &s
+}
+
+// String returns a human-readable representation of the set.
+func (s *Set[E]) String() string {
+	vals := make([]string, 0, s.Len())
+	for v := range s.m.Keys() {
+		vals = append(vals, fmt.Sprint(v))
+	}
+	return fmt.Sprintf("ordset[%s]", strings.Join(vals, " "))
+}
+
+// GoString returns a Go syntax representation of the set.
+func (s *Set[E]) GoString() string {
+	var v E
+	typeName := fmt.Sprintf("%T", v)
+	vals := make([]string, 0, s.Len())
+	for v := range s.m.Keys() {
+		vals = append(vals, fmt.Sprintf("%#v", v))
+	}
+	return fmt.Sprintf("ordset.Of[%s](%s)", typeName, strings.Join(vals, ", "))
+}
+
+// Add adds elements to the end of a set.
+// Elements that are already present keep their position.
+func (s *Set[E]) Add(v ...E) {
+	for _, vv := range v {
+		s.add(vv)
+	}
+}
+
+func (s *Set[E]) add(v E) {
+	if _, ok := s.m.Peek(v); !ok {
+		s.m.Set(v, struct{}{})
+	}
+}
+
+// AddSet adds the elements of set s2 to the end of s, in the order of s2.
+// Elements that are already present in s keep their position.
+func (s *Set[E]) AddSet(s2 *Set[E]) {
+	if s == s2 {
+		return
+	}
+	for v2 := range s2.m.Keys() {
+		s.add(v2)
+	}
+}
+
+// Remove removes elements from a set.
+// Elements that are not present are ignored.
+func (s *Set[E]) Remove(v ...E) {
+	for _, vv := range v {
+		s.m.Delete(vv)
+	}
+}
+
+// RemoveSet removes the elements of set s2 from s.
+// Elements present in s2 but not s are ignored.
+func (s *Set[E]) RemoveSet(s2 *Set[E]) {
+	if s == s2 {
+		s.Clear()
+		return
+	}
+	for v2 := range s2.m.Keys() {
+		s.m.Delete(v2)
+	}
+}
+
+// Contains reports whether v is in the set.
+func (s *Set[E]) Contains(v E) bool {
+	_, ok := s.m.Peek(v)
+	return ok
+}
+
+// ContainsAny reports whether any of the elements in s2 are in s.
+func (s *Set[E]) ContainsAny(s2 *Set[E]) bool {
+	for v2 := range s2.m.Keys() {
+		if s.Contains(v2) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAll reports whether all of the elements in s2 are in s.
+func (s *Set[E]) ContainsAll(s2 *Set[E]) bool {
+	for v2 := range s2.m.Keys() {
+		if !s.Contains(v2) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether s and s2 contain the same elements,
+// regardless of their order.
+// To also compare the order, use EqualOrder.
+func (s *Set[E]) Equal(s2 *Set[E]) bool {
+	if s.Len() != s2.Len() {
+		return false
+	}
+	return s.ContainsAll(s2)
+}
+
+// EqualOrder reports whether s and s2 contain the same elements
+// in the same order.
+func (s *Set[E]) EqualOrder(s2 *Set[E]) bool {
+	return ordmap.Equal(&s.m, &s2.m)
+}
+
+// Clear removes all elements from s, leaving it empty.
+func (s *Set[E]) Clear() {
+	s.m.Clear()
+}
+
+// Clone returns a copy of s with the same elements in the same order.
+// The elements are copied using assignment,
+// so this is a shallow clone.
+func (s *Set[E]) Clone() *Set[E] {
+	var c Set[E]
+	for v := range s.m.Keys() {
+		c.m.Set(v, struct{}{})
+	}
+	return &c
+}
+
+// RemoveIf deletes any elements from s for which remove returns true.
+// The function is called for the elements in order.
+func (s *Set[E]) RemoveIf(remove func(E) bool) {
+	s.m.DeleteFunc(func(v E, _ struct{}) bool { return remove(v) })
+}
+
+// Len returns the number of elements in s.
+func (s *Set[E]) Len() int {
+	return s.m.Len()
+}
+
+// All returns an iterator over the elements in the set,
+// in the order they were added.
+// See ordmap.Map for the behavior when the set is modified during iteration.
+func (s *Set[E]) All() iter.Seq[E] {
+	return s.m.Keys()
+}
+
+// Backward returns an iterator over the elements in the set,
+// in the reverse of the order they were added.
+func (s *Set[E]) Backward() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for v := range s.m.Backward() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Union constructs a new set containing the union of s1 and s2.
+// The result holds the elements of s1, in order,
+// followed by the elements of s2 that are not in s1, in order.
+func Union[E comparable](s1, s2 *Set[E]) *Set[E] {
+	s := s1.Clone()
+	s.AddSet(s2)
+	return s
+}
+
+// Intersection constructs a new set containing the intersection of s1 and s2.
+// The elements of the result are in the order of s1.
+func Intersection[E comparable](s1, s2 *Set[E]) *Set[E] {
+	var s Set[E]
+	for v := range s1.m.Keys() {
+		if s2.Contains(v) {
+			s.m.Set(v, struct{}{})
+		}
+	}
+	return &s
+}
+
+// Difference constructs a new set containing the elements of s1 that
+// are not present in s2.
+// The elements of the result are in the order of s1.
+func Difference[E comparable](s1, s2 *Set[E]) *Set[E] {
+	var s Set[E]
+	for v := range s1.m.Keys() {
+		if !s2.Contains(v) {
+			s.m.Set(v, struct{}{})
+		}
+	}
+	return &s
+}
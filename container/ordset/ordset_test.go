@@ -0,0 +1,170 @@
+package ordset
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	check(t, Of[string]())
+	check(t, Of("b", "a", "b", "c", "a"), "b", "a", "c")
+}
+
+func TestString(t *testing.T) {
+	for _, tt := range []struct {
+		set          *Set[string]
+		want, goWant string
+	}{
+		{Of[string](), "ordset[]", "ordset.Of[string]()"},
+		{Of("c", "a", "b"), "ordset[c a b]", `ordset.Of[string]("c", "a", "b")`},
+	} {
+		if got := tt.set.String(); got != tt.want {
+			t.Errorf("String: got %q; want %q", got, tt.want)
+		}
+		if got := tt.set.GoString(); got != tt.goWant {
+			t.Errorf("GoString: got %q; want %q", got, tt.goWant)
+		}
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	var s Set[int]
+	check(t, &s)
+	s.Add()
+	check(t, &s)
+	s.Add(3, 1)
+	check(t, &s, 3, 1)
+	s.Add(1, 2, 3)
+	check(t, &s, 3, 1, 2)
+	s.Remove(1, 5)
+	check(t, &s, 3, 2)
+	s.Add(1)
+	check(t, &s, 3, 2, 1)
+
+	s.AddSet(Of(4, 2, 0))
+	check(t, &s, 3, 2, 1, 4, 0)
+	s.AddSet(&s)
+	check(t, &s, 3, 2, 1, 4, 0)
+	s.RemoveSet(Of(2, 4, 9))
+	check(t, &s, 3, 1, 0)
+
+	s.RemoveIf(func(v int) bool { return v == 1 })
+	check(t, &s, 3, 0)
+	s.RemoveSet(&s)
+	check(t, &s)
+	s.Add(7)
+	check(t, &s, 7)
+	s.Clear()
+	check(t, &s)
+}
+
+func TestContains(t *testing.T) {
+	s := Of(1, 2, 3)
+	if !s.Contains(2) || s.Contains(4) {
+		t.Fatal("Contains gave incorrect results")
+	}
+	if !s.ContainsAny(Of(5, 3)) || s.ContainsAny(Of(4, 5)) || s.ContainsAny(Of[int]()) {
+		t.Fatal("ContainsAny gave incorrect results")
+	}
+	if !s.ContainsAll(Of(3, 1)) || s.ContainsAll(Of(1, 4)) || !s.ContainsAll(Of[int]()) {
+		t.Fatal("ContainsAll gave incorrect results")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	for _, tt := range []struct {
+		s1, s2               *Set[int]
+		wantEqual, wantOrder bool
+	}{
+		{Of[int](), Of[int](), true, true},
+		{Of(1, 2), Of(1, 2), true, true},
+		{Of(1, 2), Of(2, 1), true, false},
+		{Of(1, 2), Of(1), false, false},
+		{Of(1, 2), Of(1, 3), false, false},
+	} {
+		if got := tt.s1.Equal(tt.s2); got != tt.wantEqual {
+			t.Errorf("%v.Equal(%v): got %t", tt.s1, tt.s2, got)
+		}
+		if got := tt.s1.EqualOrder(tt.s2); got != tt.wantOrder {
+			t.Errorf("%v.EqualOrder(%v): got %t", tt.s1, tt.s2, got)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	s := Of(3, 1, 2)
+	c := s.Clone()
+	s.Add(4)
+	c.Remove(1)
+	check(t, s, 3, 1, 2, 4)
+	check(t, c, 3, 2)
+}
+
+func TestBackward(t *testing.T) {
+	s := Of(3, 1, 2)
+	if got, want := slices.Collect(s.Backward()), []int{2, 1, 3}; !slices.Equal(got, want) {
+		t.Fatalf("Backward: got %v; want %v", got, want)
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	s1 := Of(3, 1, 2, 5, 4)
+	s2 := Of(1, 2, 6)
+	// Run with -race: reading methods must not modify the set.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				_ = s1.String() + s1.GoString()
+				for range s1.All() {
+				}
+				s1.ContainsAny(s2)
+				s1.ContainsAll(s2)
+				s1.Equal(s2)
+				s1.EqualOrder(s1)
+				s1.Clone()
+				Union(s1, s2)
+				Intersection(s1, s2)
+			}
+		}()
+	}
+	wg.Wait()
+	check(t, s1, 3, 1, 2, 5, 4)
+}
+
+func TestSetOps(t *testing.T) {
+	s1 := Of(5, 1, 4, 2)
+	s2 := Of(3, 2, 6, 5)
+	check(t, Union(s1, s2), 5, 1, 4, 2, 3, 6)
+	check(t, Union(s2, s1), 3, 2, 6, 5, 1, 4)
+	check(t, Intersection(s1, s2), 5, 2)
+	check(t, Intersection(s2, s1), 2, 5)
+	check(t, Difference(s1, s2), 1, 4)
+	check(t, Difference(s2, s1), 3, 6)
+	check(t, Union(s1, Of[int]()), 5, 1, 4, 2)
+	check(t, Intersection(s1, Of[int]()))
+	check(t, Difference(s1, s1))
+
+	// The inputs are unchanged.
+	check(t, s1, 5, 1, 4, 2)
+	check(t, s2, 3, 2, 6, 5)
+}
+
+func check[E comparable](t *testing.T, s *Set[E], want ...E) {
+	t.Helper()
+	got := slices.Collect(s.All())
+	if !slices.Equal(got, want) {
+		t.Fatalf("got elements %v; want %v", got, want)
+	}
+	if s.Len() != len(want) {
+		t.Fatalf("Len: got %d; want %d", s.Len(), len(want))
+	}
+	for _, v := range want {
+		if !s.Contains(v) {
+			t.Fatalf("Contains(%v): got false", v)
+		}
+	}
+}
@@ -0,0 +1,663 @@
+package roaring
+
+import (
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+// A kind identifies the representation of a container.
+// The values are part of the binary format.
+type kind uint8
+
+const (
+	arrayKind kind = iota + 1
+	bitmapKind
+	runKind
+)
+
+const (
+	// arrayMax is the largest number of elements held in an array container.
+	// At this size, the array takes as much space as a bitmap.
+	arrayMax = 4096
+	// bitmapWords is the number of words in a bitmap container.
+	bitmapWords = 1 << 16 / 64
+	// maxRuns is the largest number of runs that Add and Remove leave in a
+	// run container before converting it to another kind. At this size, the
+	// runs take as much space as a bitmap.
+	maxRuns = 2048
+)
+
+// An interval is a run of consecutive elements from start to last, inclusive.
+type interval struct {
+	start, last uint16
+}
+
+// A container holds the low 16 bits of the elements of a Set that share
+// the same high 16 bits. Exactly one of array, bitmap, and runs is used,
+// according to kind:
+//
+//   - an array container holds its elements in a sorted slice;
+//   - a bitmap container holds them as a 65536-bit vector; and
+//   - a run container holds a sorted list of disjoint, non-adjacent runs.
+//
+// A container is never empty.
+type container struct {
+	kind   kind
+	n      int // number of elements, from 1 to 65536
+	array  []uint16
+	bitmap []uint64
+	runs   []interval
+	// shared reports whether the slice in use refers to memory that the
+	// container does not own, such as the data passed to View.
+	// Such a container is copied before it is modified.
+	shared bool
+}
+
+func newArray(a []uint16) *container {
+	if len(a) == 0 {
+		return nil
+	}
+	c := &container{kind: arrayKind, n: len(a), array: a}
+	c.normalize()
+	return c
+}
+
+func newBitmap(w []uint64) *container {
+	n := 0
+	for _, x := range w {
+		n += bits.OnesCount64(x)
+	}
+	if n == 0 {
+		return nil
+	}
+	c := &container{kind: bitmapKind, n: n, bitmap: w}
+	c.normalize()
+	return c
+}
+
+func newRuns(runs []interval) *container {
+	if len(runs) == 0 {
+		return nil
+	}
+	n := 0
+	for _, r := range runs {
+		n += int(r.last-r.start) + 1
+	}
+	c := &container{kind: runKind, n: n, runs: runs}
+	c.normalize()
+	return c
+}
+
+func (c *container) clone() *container {
+	return &container{
+		kind:   c.kind,
+		n:      c.n,
+		array:  slices.Clone(c.array),
+		bitmap: slices.Clone(c.bitmap),
+		runs:   slices.Clone(c.runs),
+	}
+}
+
+// own copies c's elements, if necessary, so that c may be modified.
+func (c *container) own() {
+	if c.shared {
+		*c = *c.clone()
+	}
+}
+
+// normalize converts c to whichever kind takes the least space.
+func (c *container) normalize() {
+	k, size := bitmapKind, 8*bitmapWords
+	if c.n <= arrayMax {
+		k, size = arrayKind, 2*c.n
+	}
+	if 4*c.countRuns() < size {
+		k = runKind
+	}
+	c.convert(k)
+}
+
+// countRuns returns the number of runs needed to represent c.
+func (c *container) countRuns() int {
+	switch c.kind {
+	case arrayKind:
+		n := 1
+		for i := 1; i < len(c.array); i++ {
+			if c.array[i] != c.array[i-1]+1 {
+				n++
+			}
+		}
+		return n
+	case bitmapKind:
+		// Count the bits that start a run:
+		// those that are set and whose predecessor is not.
+		n := 0
+		var carry uint64
+		for _, x := range c.bitmap {
+			n += bits.OnesCount64(x &^ (x<<1 | carry))
+			carry = x >> 63
+		}
+		return n
+	default:
+		return len(c.runs)
+	}
+}
+
+// convert changes the representation of c to k.
+func (c *container) convert(k kind) {
+	if c.kind == k {
+		return
+	}
+	switch k {
+	case arrayKind:
+		a := make([]uint16, 0, c.n)
+		c.each(func(x uint16) bool {
+			a = append(a, x)
+			return true
+		})
+		*c = container{kind: arrayKind, n: c.n, array: a}
+	case bitmapKind:
+		*c = container{kind: bitmapKind, n: c.n, bitmap: c.words()}
+	case runKind:
+		runs := make([]interval, 0, c.countRuns())
+		c.each(func(x uint16) bool {
+			if n := len(runs); n > 0 && runs[n-1].last+1 == x {
+				runs[n-1].last = x
+			} else {
+				runs = append(runs, interval{x, x})
+			}
+			return true
+		})
+		*c = container{kind: runKind, n: c.n, runs: runs}
+	}
+}
+
+// words returns the elements of c as a bitmap. If c is a bitmap container,
+// words returns c.bitmap itself, which the caller must not modify.
+func (c *container) words() []uint64 {
+	if c.kind == bitmapKind {
+		return c.bitmap
+	}
+	w := make([]uint64, bitmapWords)
+	switch c.kind {
+	case arrayKind:
+		for _, x := range c.array {
+			w[x/64] |= 1 << (x % 64)
+		}
+	case runKind:
+		for _, r := range c.runs {
+			setRange(w, r.start, r.last)
+		}
+	}
+	return w
+}
+
+// setRange sets bits lo through hi, inclusive, of w.
+func setRange(w []uint64, lo, hi uint16) {
+	i, j := int(lo), int(hi)+1
+	for i < j {
+		n := min(64-i%64, j-i)
+		w[i/64] |= (1<<n - 1) << (i % 64)
+		i += n
+	}
+}
+
+// searchRuns returns the index of the first run that ends at or after x,
+// or len(c.runs) if there is none.
+func (c *container) searchRuns(x uint16) int {
+	return sort.Search(len(c.runs), func(i int) bool { return c.runs[i].last >= x })
+}
+
+func (c *container) contains(x uint16) bool {
+	switch c.kind {
+	case arrayKind:
+		_, ok := slices.BinarySearch(c.array, x)
+		return ok
+	case bitmapKind:
+		return c.bitmap[x/64]&(1<<(x%64)) != 0
+	default:
+		i := c.searchRuns(x)
+		return i < len(c.runs) && c.runs[i].start <= x
+	}
+}
+
+// add adds x to c.
+func (c *container) add(x uint16) {
+	switch c.kind {
+	case arrayKind:
+		i, ok := slices.BinarySearch(c.array, x)
+		if ok {
+			return
+		}
+		if c.n == arrayMax {
+			c.convert(bitmapKind)
+			c.add(x)
+			return
+		}
+		c.own()
+		c.array = slices.Insert(c.array, i, x)
+	case bitmapKind:
+		if c.bitmap[x/64]&(1<<(x%64)) != 0 {
+			return
+		}
+		c.own()
+		c.bitmap[x/64] |= 1 << (x % 64)
+	case runKind:
+		i := c.searchRuns(x)
+		if i < len(c.runs) && c.runs[i].start <= x {
+			return
+		}
+		c.own()
+		// x lies between runs i-1 and i.
+		joinPrev := i > 0 && c.runs[i-1].last+1 == x
+		joinNext := i < len(c.runs) && c.runs[i].start-1 == x
+		switch {
+		case joinPrev && joinNext:
+			c.runs[i-1].last = c.runs[i].last
+			c.runs = slices.Delete(c.runs, i, i+1)
+		case joinPrev:
+			c.runs[i-1].last = x
+		case joinNext:
+			c.runs[i].start = x
+		default:
+			c.runs = slices.Insert(c.runs, i, interval{x, x})
+		}
+	}
+	c.n++
+	if c.kind == runKind && len(c.runs) > maxRuns {
+		c.normalize()
+	}
+}
+
+// remove removes x from c. The caller must discard c if it becomes empty.
+func (c *container) remove(x uint16) {
+	switch c.kind {
+	case arrayKind:
+		i, ok := slices.BinarySearch(c.array, x)
+		if !ok {
+			return
+		}
+		c.own()
+		c.array = slices.Delete(c.array, i, i+1)
+	case bitmapKind:
+		if c.bitmap[x/64]&(1<<(x%64)) == 0 {
+			return
+		}
+		c.own()
+		c.bitmap[x/64] &^= 1 << (x % 64)
+	case runKind:
+		i := c.searchRuns(x)
+		if i == len(c.runs) || c.runs[i].start > x {
+			return
+		}
+		c.own()
+		r := &c.runs[i]
+		switch {
+		case r.start == r.last:
+			c.runs = slices.Delete(c.runs, i, i+1)
+		case x == r.start:
+			r.start++
+		case x == r.last:
+			r.last--
+		default:
+			// Split the run around x.
+			last := r.last
+			r.last = x - 1
+			c.runs = slices.Insert(c.runs, i+1, interval{x + 1, last})
+		}
+	}
+	c.n--
+	switch {
+	case c.n == 0:
+	case c.kind == bitmapKind && c.n <= arrayMax:
+		c.convert(arrayKind)
+	case c.kind == runKind && len(c.runs) > maxRuns:
+		c.normalize()
+	}
+}
+
+// each calls f for each element of c in ascending order
+// until f returns false.
+func (c *container) each(f func(uint16) bool) bool {
+	return c.eachFrom(0, f)
+}
+
+// eachFrom calls f for each element of c that is at least lo, in ascending
+// order, until f returns false. It reports whether f always returned true.
+func (c *container) eachFrom(lo uint16, f func(uint16) bool) bool {
+	switch c.kind {
+	case arrayKind:
+		i, _ := slices.BinarySearch(c.array, lo)
+		for _, x := range c.array[i:] {
+			if !f(x) {
+				return false
+			}
+		}
+	case bitmapKind:
+		for i := int(lo / 64); i < bitmapWords; i++ {
+			w := c.bitmap[i]
+			if i == int(lo/64) {
+				w &= ^uint64(0) << (lo % 64)
+			}
+			for w != 0 {
+				if !f(uint16(i*64 + bits.TrailingZeros64(w))) {
+					return false
+				}
+				w &= w - 1
+			}
+		}
+	case runKind:
+		for _, r := range c.runs[c.searchRuns(lo):] {
+			for x := max(r.start, lo); ; x++ {
+				if !f(x) {
+					return false
+				}
+				if x == r.last {
+					break
+				}
+			}
+		}
+	}
+	return true
+}
+
+// eachBackFrom calls f for each element of c that is at most hi, in
+// descending order, until f returns false. It reports whether f always
+// returned true.
+func (c *container) eachBackFrom(hi uint16, f func(uint16) bool) bool {
+	switch c.kind {
+	case arrayKind:
+		i, ok := slices.BinarySearch(c.array, hi)
+		if ok {
+			i++
+		}
+		for j := i - 1; j >= 0; j-- {
+			if !f(c.array[j]) {
+				return false
+			}
+		}
+	case bitmapKind:
+		for i := int(hi / 64); i >= 0; i-- {
+			w := c.bitmap[i]
+			if i == int(hi/64) {
+				w &= ^uint64(0) >> (63 - hi%64)
+			}
+			for w != 0 {
+				b := 63 - bits.LeadingZeros64(w)
+				if !f(uint16(i*64 + b)) {
+					return false
+				}
+				w &^= 1 << b
+			}
+		}
+	case runKind:
+		for j := min(c.searchRuns(hi), len(c.runs)-1); j >= 0; j-- {
+			r := c.runs[j]
+			if r.start > hi {
+				continue
+			}
+			for x := min(r.last, hi); ; x-- {
+				if !f(x) {
+					return false
+				}
+				if x == r.start {
+					break
+				}
+			}
+		}
+	}
+	return true
+}
+
+// rank returns the number of elements of c that are less than x.
+func (c *container) rank(x uint16) int {
+	switch c.kind {
+	case arrayKind:
+		i, _ := slices.BinarySearch(c.array, x)
+		return i
+	case bitmapKind:
+		n := 0
+		for _, w := range c.bitmap[:x/64] {
+			n += bits.OnesCount64(w)
+		}
+		return n + bits.OnesCount64(c.bitmap[x/64]&(1<<(x%64)-1))
+	default:
+		n := 0
+		for _, r := range c.runs {
+			if r.start >= x {
+				break
+			}
+			n += int(min(r.last, x-1)-r.start) + 1
+		}
+		return n
+	}
+}
+
+// sel returns the element of c with rank i, which must be less than c.n.
+func (c *container) sel(i int) uint16 {
+	switch c.kind {
+	case arrayKind:
+		return c.array[i]
+	case bitmapKind:
+		for j, w := range c.bitmap {
+			n := bits.OnesCount64(w)
+			if i >= n {
+				i -= n
+				continue
+			}
+			for range i {
+				w &= w - 1
+			}
+			return uint16(j*64 + bits.TrailingZeros64(w))
+		}
+	default:
+		for _, r := range c.runs {
+			n := int(r.last-r.start) + 1
+			if i < n {
+				return r.start + uint16(i)
+			}
+			i -= n
+		}
+	}
+	panic("unreachable")
+}
+
+// The following functions combine two containers into a new one, returning
+// nil if the result is empty. They do not modify their arguments, and the
+// result does not share memory with them.
+
+func and(a, b *container) *container {
+	if b.kind == arrayKind {
+		a, b = b, a
+	}
+	switch {
+	case a.kind == arrayKind:
+		out := make([]uint16, 0, min(a.n, b.n))
+		for _, x := range a.array {
+			if b.contains(x) {
+				out = append(out, x)
+			}
+		}
+		return newArray(out)
+	case a.kind == runKind && b.kind == runKind:
+		var out []interval
+		for i, j := 0, 0; i < len(a.runs) && j < len(b.runs); {
+			ra, rb := a.runs[i], b.runs[j]
+			if lo, hi := max(ra.start, rb.start), min(ra.last, rb.last); lo <= hi {
+				out = append(out, interval{lo, hi})
+			}
+			if ra.last < rb.last {
+				i++
+			} else {
+				j++
+			}
+		}
+		return newRuns(out)
+	}
+	wa, wb := a.words(), b.words()
+	out := make([]uint64, bitmapWords)
+	for i := range out {
+		out[i] = wa[i] & wb[i]
+	}
+	return newBitmap(out)
+}
+
+func or(a, b *container) *container {
+	switch {
+	case a.kind == arrayKind && b.kind == arrayKind:
+		out := make([]uint16, 0, a.n+b.n)
+		i, j := 0, 0
+		for i < len(a.array) && j < len(b.array) {
+			x, y := a.array[i], b.array[j]
+			out = append(out, min(x, y))
+			if x <= y {
+				i++
+			}
+			if y <= x {
+				j++
+			}
+		}
+		out = append(out, a.array[i:]...)
+		out = append(out, b.array[j:]...)
+		return newArray(out)
+	case a.kind == runKind && b.kind == runKind:
+		out := make([]interval, 0, len(a.runs)+len(b.runs))
+		for i, j := 0, 0; i < len(a.runs) || j < len(b.runs); {
+			var r interval
+			if j == len(b.runs) || i < len(a.runs) && a.runs[i].start <= b.runs[j].start {
+				r = a.runs[i]
+				i++
+			} else {
+				r = b.runs[j]
+				j++
+			}
+			if n := len(out); n > 0 && int(r.start) <= int(out[n-1].last)+1 {
+				out[n-1].last = max(out[n-1].last, r.last)
+			} else {
+				out = append(out, r)
+			}
+		}
+		return newRuns(out)
+	}
+	wa, wb := a.words(), b.words()
+	out := make([]uint64, bitmapWords)
+	for i := range out {
+		out[i] = wa[i] | wb[i]
+	}
+	return newBitmap(out)
+}
+
+func andNot(a, b *container) *container {
+	if a.kind == arrayKind {
+		out := make([]uint16, 0, a.n)
+		for _, x := range a.array {
+			if !b.contains(x) {
+				out = append(out, x)
+			}
+		}
+		return newArray(out)
+	}
+	wa, wb := a.words(), b.words()
+	out := make([]uint64, bitmapWords)
+	for i := range out {
+		out[i] = wa[i] &^ wb[i]
+	}
+	return newBitmap(out)
+}
+
+func xor(a, b *container) *container {
+	if a.kind == arrayKind && b.kind == arrayKind {
+		out := make([]uint16, 0, a.n+b.n)
+		i, j := 0, 0
+		for i < len(a.array) && j < len(b.array) {
+			switch x, y := a.array[i], b.array[j]; {
+			case x < y:
+				out = append(out, x)
+				i++
+			case y < x:
+				out = append(out, y)
+				j++
+			default:
+				i++
+				j++
+			}
+		}
+		out = append(out, a.array[i:]...)
+		out = append(out, b.array[j:]...)
+		return newArray(out)
+	}
+	wa, wb := a.words(), b.words()
+	out := make([]uint64, bitmapWords)
+	for i := range out {
+		out[i] = wa[i] ^ wb[i]
+	}
+	return newBitmap(out)
+}
+
+// intersects reports whether a and b have any elements in common.
+func intersects(a, b *container) bool {
+	if b.kind == arrayKind {
+		a, b = b, a
+	}
+	switch {
+	case a.kind == arrayKind:
+		for _, x := range a.array {
+			if b.contains(x) {
+				return true
+			}
+		}
+		return false
+	case a.kind == runKind && b.kind == runKind:
+		for i, j := 0, 0; i < len(a.runs) && j < len(b.runs); {
+			ra, rb := a.runs[i], b.runs[j]
+			if max(ra.start, rb.start) <= min(ra.last, rb.last) {
+				return true
+			}
+			if ra.last < rb.last {
+				i++
+			} else {
+				j++
+			}
+		}
+		return false
+	}
+	wa, wb := a.words(), b.words()
+	for i := range wa {
+		if wa[i]&wb[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// subset reports whether every element of a is in b.
+func subset(a, b *container) bool {
+	if a.n > b.n {
+		return false
+	}
+	switch {
+	case a.kind == arrayKind:
+		for _, x := range a.array {
+			if !b.contains(x) {
+				return false
+			}
+		}
+		return true
+	case a.kind == runKind && b.kind == runKind:
+		for _, r := range a.runs {
+			j := b.searchRuns(r.start)
+			if j == len(b.runs) || b.runs[j].start > r.start || b.runs[j].last < r.last {
+				return false
+			}
+		}
+		return true
+	}
+	wa, wb := a.words(), b.words()
+	for i := range wa {
+		if wa[i]&^wb[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package roaring
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"unsafe"
+)
+
+// The binary encoding of a Set, produced by MarshalBinary and AppendBinary,
+// is stable: later versions of this package will continue to read it.
+// All integers are little-endian. The encoding begins with a 12-byte header:
+//
+//	magic   [4]byte // "RSET"
+//	version uint32  // 1
+//	count   uint32  // number of containers
+//
+// This is followed by count 16-byte container descriptions, in ascending
+// order of key:
+//
+//	key    uint16 // high 16 bits of the container's elements
+//	kind   uint8  // 1 for array, 2 for bitmap, 3 for runs
+//	_      uint8  // reserved; must be zero
+//	n      uint32 // number of elements, from 1 to 65536
+//	size   uint32 // number of items in the payload
+//	offset uint32 // offset of the payload from the start of the encoding
+//
+// Each container's payload starts at an offset that is a multiple of 8,
+// with zero padding between payloads. An array payload holds size uint16
+// values in ascending order, where size is at most 4096. A bitmap payload
+// holds 1024 uint64 words (so size is 1024), in which bit i%64 of word i/64
+// is set if i is present. A run payload holds size pairs of uint16 values,
+// start and last, each describing the run of elements from start to last
+// inclusive; the runs are in ascending order and neither overlap nor touch,
+// and size is at most 2048.
+//
+// The alignment of the payloads allows View to use them in place.
+
+const (
+	magic         = "RSET"
+	formatVersion = 1
+	headerSize    = 12
+	descSize      = 16
+)
+
+var errInvalid = errors.New("roaring: invalid encoding")
+
+// AppendBinary implements encoding.BinaryAppender.
+// It appends the binary encoding of s to b.
+func (s *Set) AppendBinary(b []byte) ([]byte, error) {
+	start := len(b)
+	b = append(b, magic...)
+	b = binary.LittleEndian.AppendUint32(b, formatVersion)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s.keys)))
+	off := align8(headerSize + descSize*len(s.keys))
+	for i, c := range s.cs {
+		b = binary.LittleEndian.AppendUint16(b, s.keys[i])
+		b = append(b, byte(c.kind), 0)
+		b = binary.LittleEndian.AppendUint32(b, uint32(c.n))
+		size, nbytes := c.payloadSize()
+		b = binary.LittleEndian.AppendUint32(b, uint32(size))
+		b = binary.LittleEndian.AppendUint32(b, uint32(off))
+		off = align8(off + nbytes)
+	}
+	for _, c := range s.cs {
+		for (len(b)-start)%8 != 0 {
+			b = append(b, 0)
+		}
+		switch c.kind {
+		case arrayKind:
+			for _, x := range c.array {
+				b = binary.LittleEndian.AppendUint16(b, x)
+			}
+		case bitmapKind:
+			for _, w := range c.bitmap {
+				b = binary.LittleEndian.AppendUint64(b, w)
+			}
+		case runKind:
+			for _, r := range c.runs {
+				b = binary.LittleEndian.AppendUint16(b, r.start)
+				b = binary.LittleEndian.AppendUint16(b, r.last)
+			}
+		}
+	}
+	return b, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// See AppendBinary.
+func (s *Set) MarshalBinary() ([]byte, error) {
+	return s.AppendBinary(nil)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces the contents of s with the elements decoded from data,
+// which must have been produced by MarshalBinary or AppendBinary.
+// The Set does not retain data.
+func (s *Set) UnmarshalBinary(data []byte) error {
+	s1, err := decode(data, false)
+	if err != nil {
+		return err
+	}
+	s.keys, s.cs = s1.keys, s1.cs
+	s.mod++
+	return nil
+}
+
+// View returns a Set holding the elements encoded in data, which must have
+// been produced by MarshalBinary or AppendBinary.
+//
+// Where possible, the returned Set refers to the contents of data rather
+// than copying them: this is the case on little-endian machines when data
+// is 8-byte aligned, as is memory obtained from mmap. The caller must not
+// modify data while the Set is in use. The Set may still be modified: it
+// copies the part of data that holds a container before modifying that
+// container, and it never writes to data.
+//
+// View checks that data is a valid encoding, which takes time
+// proportional to its length.
+func View(data []byte) (*Set, error) {
+	return decode(data, true)
+}
+
+func decode(data []byte, view bool) (*Set, error) {
+	if len(data) < headerSize || string(data[:4]) != magic {
+		return nil, errInvalid
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != formatVersion {
+		return nil, fmt.Errorf("roaring: unsupported encoding version %d", v)
+	}
+	count := uint64(binary.LittleEndian.Uint32(data[8:]))
+	if count > 1<<16 || headerSize+descSize*count > uint64(len(data)) {
+		return nil, errInvalid
+	}
+	s := &Set{
+		keys: make([]uint16, count),
+		cs:   make([]*container, count),
+	}
+	for i := range s.cs {
+		d := data[headerSize+descSize*i:]
+		key := binary.LittleEndian.Uint16(d)
+		c := &container{
+			kind: kind(d[2]),
+			n:    int(binary.LittleEndian.Uint32(d[4:])),
+		}
+		size := uint64(binary.LittleEndian.Uint32(d[8:]))
+		off := uint64(binary.LittleEndian.Uint32(d[12:]))
+		if i > 0 && key <= s.keys[i-1] || d[3] != 0 || c.n < 1 || c.n > 1<<16 || off%8 != 0 {
+			return nil, errInvalid
+		}
+		var itemSize uint64
+		switch c.kind {
+		case arrayKind:
+			itemSize = 2
+		case bitmapKind:
+			itemSize = 8
+		case runKind:
+			itemSize = 4
+		default:
+			return nil, errInvalid
+		}
+		if off+size*itemSize > uint64(len(data)) {
+			return nil, errInvalid
+		}
+		p := data[off : off+size*itemSize]
+		var ok bool
+		switch c.kind {
+		case arrayKind:
+			c.array, c.shared = decodeSlice[uint16](p, view)
+			ok = validArray(c)
+		case bitmapKind:
+			c.bitmap, c.shared = decodeSlice[uint64](p, view)
+			ok = validBitmap(c)
+		case runKind:
+			c.runs, c.shared = decodeSlice[interval](p, view)
+			ok = validRuns(c)
+		}
+		if !ok {
+			return nil, errInvalid
+		}
+		s.keys[i], s.cs[i] = key, c
+	}
+	return s, nil
+}
+
+var littleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+// decodeSlice decodes the little-endian values in p, which is a whole
+// number of values long. If view is set and the machine's byte order and
+// p's alignment permit, the result refers to p itself and decodeSlice
+// reports true.
+func decodeSlice[T uint16 | uint64 | interval](p []byte, view bool) ([]T, bool) {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	n := len(p) / size
+	if n == 0 {
+		return nil, false
+	}
+	if view && littleEndian && uintptr(unsafe.Pointer(&p[0]))%unsafe.Alignof(zero) == 0 {
+		return unsafe.Slice((*T)(unsafe.Pointer(&p[0])), n), true
+	}
+	s := make([]T, n)
+	switch s := any(s).(type) {
+	case []uint16:
+		for i := range s {
+			s[i] = binary.LittleEndian.Uint16(p[2*i:])
+		}
+	case []uint64:
+		for i := range s {
+			s[i] = binary.LittleEndian.Uint64(p[8*i:])
+		}
+	case []interval:
+		for i := range s {
+			s[i] = interval{
+				start: binary.LittleEndian.Uint16(p[4*i:]),
+				last:  binary.LittleEndian.Uint16(p[4*i+2:]),
+			}
+		}
+	}
+	return s, false
+}
+
+// validArray reports whether c is a well-formed array container.
+// Add relies on an array never exceeding arrayMax elements, as
+// validRuns does on maxRuns, to keep each operation cheap.
+func validArray(c *container) bool {
+	if len(c.array) != c.n || c.n > arrayMax {
+		return false
+	}
+	for i := 1; i < len(c.array); i++ {
+		if c.array[i] <= c.array[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+func validBitmap(c *container) bool {
+	if len(c.bitmap) != bitmapWords {
+		return false
+	}
+	n := 0
+	for _, w := range c.bitmap {
+		n += bits.OnesCount64(w)
+	}
+	return n == c.n
+}
+
+func validRuns(c *container) bool {
+	if len(c.runs) == 0 || len(c.runs) > maxRuns {
+		return false
+	}
+	n := 0
+	for i, r := range c.runs {
+		if r.last < r.start || i > 0 && int(r.start) <= int(c.runs[i-1].last)+1 {
+			return false
+		}
+		n += int(r.last-r.start) + 1
+	}
+	return n == c.n
+}
+
+// payloadSize returns the number of items in c's payload and its length
+// in bytes.
+func (c *container) payloadSize() (size, nbytes int) {
+	switch c.kind {
+	case arrayKind:
+		return len(c.array), 2 * len(c.array)
+	case bitmapKind:
+		return len(c.bitmap), 8 * len(c.bitmap)
+	default:
+		return len(c.runs), 4 * len(c.runs)
+	}
+}
+
+func align8(n int) int {
+	return (n + 7) &^ 7
+}
@@ -0,0 +1,222 @@
+package roaring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestEncodingGolden(t *testing.T) {
+	// The encoding is stable, so this must never change.
+	s := Of(1, 3, 1<<16|7)
+	s.AddRange(2<<16, 2<<16+10)
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"52534554", "01000000", "03000000", // header
+		"0000", "01", "00", "02000000", "02000000", "40000000", // key 0: array
+		"0100", "01", "00", "01000000", "01000000", "48000000", // key 1: array
+		"0200", "03", "00", "0a000000", "01000000", "50000000", // key 2: runs
+		"00000000",                 // padding
+		"0100", "0300", "00000000", // key 0 payload and padding
+		"0700", "000000000000", // key 1 payload and padding
+		"0000", "0900", // key 2 payload
+	}, "")
+	if got := hex.EncodeToString(b); got != want {
+		t.Errorf("MarshalBinary:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	g := gen{rand.New(rand.NewPCG(3, 4))}
+	for range 50 {
+		s, _ := g.sets()
+		b, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Set
+		got.Add(1, 2, 3)
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		checkInvariants(t, &got)
+		if !got.Equal(s) {
+			t.Fatalf("UnmarshalBinary: got %d elements; want %d", got.Len(), s.Len())
+		}
+		v, err := View(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkInvariants(t, v)
+		if !v.Equal(s) {
+			t.Fatalf("View: got %d elements; want %d", v.Len(), s.Len())
+		}
+		prefix := []byte("xyz")
+		b2, err := s.AppendBinary(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b2[:3], prefix) || !bytes.Equal(b2[3:], b) {
+			t.Fatal("AppendBinary did not append the same encoding as MarshalBinary")
+		}
+	}
+}
+
+// aligned returns a copy of b that starts at an 8-byte boundary.
+func aligned(b []byte) []byte {
+	w := make([]uint64, (len(b)+7)/8)
+	p := unsafe.Slice((*byte)(unsafe.Pointer(&w[0])), len(w)*8)[:len(b)]
+	copy(p, b)
+	return p
+}
+
+func TestView(t *testing.T) {
+	s := Of(1, 3, 1<<16|7)
+	for i := uint32(0); i < 10000; i += 2 {
+		s.Add(3<<16 | i)
+	}
+	s.AddRange(2<<16, 2<<16+10)
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := aligned(b)
+	v, err := View(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if littleEndian {
+		for i, c := range v.cs {
+			if !c.shared {
+				t.Errorf("container %d of view of aligned data is not shared", v.keys[i])
+			}
+		}
+	}
+
+	// Modifying the view leaves data alone.
+	v.Add(2, 1<<16|8, 2<<16+20, 3<<16|1)
+	v.Remove(1, 2<<16+5, 3<<16)
+	v.AddRange(0, 5)
+	if !bytes.Equal(data, b) {
+		t.Fatal("modifying a view modified its data")
+	}
+	want := s.Clone()
+	want.Add(2, 1<<16|8, 2<<16+20, 3<<16|1)
+	want.Remove(1, 2<<16+5, 3<<16)
+	want.AddRange(0, 5)
+	if !v.Equal(want) {
+		t.Fatalf("after modifying view: got %s; want %s", v, want)
+	}
+	if !s.Equal(mustView(t, data)) {
+		t.Fatal("second view of data differs from original set")
+	}
+
+	// Misaligned data is copied.
+	data = append([]byte{0}, b...)[1:]
+	if uintptr(unsafe.Pointer(&data[0]))%8 == 0 {
+		t.Fatal("test data is unexpectedly aligned")
+	}
+	v = mustView(t, data)
+	for i, c := range v.cs {
+		if c.kind == bitmapKind && c.shared {
+			t.Errorf("bitmap container %d of view of misaligned data is shared", v.keys[i])
+		}
+	}
+	if !v.Equal(s) {
+		t.Fatal("view of misaligned data differs from original set")
+	}
+}
+
+func mustView(t *testing.T, data []byte) *Set {
+	t.Helper()
+	v, err := View(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestEncodingEmpty(t *testing.T) {
+	b, err := new(Set).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := View(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, v)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	s := Of(1, 3, 1<<16|7)
+	s.AddRange(2<<16, 2<<16+10)
+	for i := uint32(0); i < 10000; i += 2 {
+		s.Add(3<<16 | i)
+	}
+	good, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := func(f func(b []byte)) []byte {
+		b := bytes.Clone(good)
+		f(b)
+		return b
+	}
+	desc := func(i int) int { return headerSize + descSize*i }
+	// encode encodes a set holding the single container c, which need not
+	// be one that Add and Remove would produce.
+	encode := func(c *container) []byte {
+		b, err := (&Set{keys: []uint16{0}, cs: []*container{c}}).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	bigArray := make([]uint16, arrayMax+1)
+	for i := range bigArray {
+		bigArray[i] = uint16(i)
+	}
+	manyRuns := make([]interval, maxRuns+1)
+	for i := range manyRuns {
+		manyRuns[i] = interval{uint16(2 * i), uint16(2 * i)}
+	}
+	for _, tt := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short", good[:headerSize-1]},
+		{"magic", corrupt(func(b []byte) { b[0] = 'X' })},
+		{"version", corrupt(func(b []byte) { b[4] = 2 })},
+		{"count", corrupt(func(b []byte) { b[8] = 200 })},
+		{"truncated", good[:len(good)-1]},
+		{"key order", corrupt(func(b []byte) { b[desc(1)] = 0 })},
+		{"kind", corrupt(func(b []byte) { b[desc(0)+2] = 9 })},
+		{"reserved", corrupt(func(b []byte) { b[desc(1)+3] = 1 })},
+		{"zero n", corrupt(func(b []byte) { copy(b[desc(0)+4:], []byte{0, 0, 0, 0}) })},
+		{"array n", corrupt(func(b []byte) { b[desc(0)+4] = 3 })},
+		{"array order", corrupt(func(b []byte) { b[binary.LittleEndian.Uint32(b[desc(0)+12:])] = 3 })},
+		{"bitmap count", corrupt(func(b []byte) { b[desc(3)+4]++ })},
+		{"run count", corrupt(func(b []byte) { b[desc(2)+4] = 11 })},
+		{"offset alignment", corrupt(func(b []byte) { b[desc(0)+12]++ })},
+		{"offset range", corrupt(func(b []byte) { b[desc(3)+15] = 0xff })},
+		{"array too large", encode(&container{kind: arrayKind, n: len(bigArray), array: bigArray})},
+		{"too many runs", encode(&container{kind: runKind, n: len(manyRuns), runs: manyRuns})},
+	} {
+		if _, err := View(tt.data); err == nil {
+			t.Errorf("%s: View gave nil error", tt.name)
+		}
+		var s Set
+		if err := s.UnmarshalBinary(tt.data); err == nil {
+			t.Errorf("%s: UnmarshalBinary gave nil error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,522 @@
+// Package roaring defines a Set type that holds a compressed set of uint32
+// values, using the roaring bitmap representation.
+//
+// A Set divides the uint32 space into chunks of 65536 values that share
+// the same high 16 bits and stores the low 16 bits of the elements in each
+// non-empty chunk in a container of one of three kinds: a sorted array
+// (for sparse chunks), a bitmap (for dense chunks), or a list of runs of
+// consecutive values. This keeps the memory used by a Set proportional to
+// its number of elements, or smaller, however they are spread out.
+// See https://roaringbitmap.org for background.
+package roaring
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"strings"
+)
+
+// A Set is a set of uint32 values.
+// It has the same methods as set.Set, plus methods for ranges of values and
+// for finding elements by rank.
+//
+// Assigning one Set to another shares the containers between them, so a
+// change to either one can show up in part in the other and leave it
+// inconsistent. Use Clone to copy a Set.
+// The zero value of a Set is an empty set ready to use.
+// As with maps, concurrent calls to functions and methods that read values
+// are fine; concurrent calls to functions and methods that write values are
+// racy.
+type Set struct {
+	// keys holds the high 16 bits of the elements in each container of cs,
+	// in ascending order.
+	keys []uint16
+	cs   []*container
+	// mod is incremented by every modification, so that iterators can
+	// tell whether yield modified the set.
+	mod uint64
+}
+
+// Of returns a new set containing the listed elements.
+func Of(v ...uint32) *Set {
+	s := new(Set)
+	s.Add(v...)
+	return s
+}
+
+// String returns a human-readable representation of the set,
+// listing the elements in ascending order.
+func (s *Set) String() string {
+	var b strings.Builder
+	b.WriteString("roaring[")
+	sep := ""
+	for v := range s.All() {
+		fmt.Fprint(&b, sep, v)
+		sep = " "
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// GoString returns a Go syntax representation of the set.
+func (s *Set) GoString() string {
+	var vals []string
+	for v := range s.All() {
+		vals = append(vals, fmt.Sprint(v))
+	}
+	return fmt.Sprintf("roaring.Of(%s)", strings.Join(vals, ", "))
+}
+
+func split(v uint32) (hi, lo uint16) {
+	return uint16(v >> 16), uint16(v)
+}
+
+// Add adds elements to a set.
+func (s *Set) Add(v ...uint32) {
+	for _, vv := range v {
+		hi, lo := split(vv)
+		i, ok := slices.BinarySearch(s.keys, hi)
+		if !ok {
+			s.keys = slices.Insert(s.keys, i, hi)
+			s.cs = slices.Insert(s.cs, i, &container{kind: arrayKind, n: 1, array: []uint16{lo}})
+			continue
+		}
+		s.cs[i].add(lo)
+	}
+	s.mod++
+}
+
+// AddRange adds the elements lo, lo+1, ..., hi-1 to a set.
+// If hi <= lo, AddRange does nothing.
+// It panics if hi is greater than 1<<32.
+func (s *Set) AddRange(lo, hi uint64) {
+	if hi > 1<<32 {
+		panic(fmt.Sprintf("roaring: range end %d out of range", hi))
+	}
+	if hi <= lo {
+		return
+	}
+	s.mod++
+	for k := lo >> 16; k <= (hi-1)>>16; k++ {
+		r := chunkRange(k, lo, hi)
+		i, ok := slices.BinarySearch(s.keys, uint16(k))
+		if !ok {
+			s.keys = slices.Insert(s.keys, i, uint16(k))
+			s.cs = slices.Insert(s.cs, i, r)
+			continue
+		}
+		s.cs[i] = or(s.cs[i], r)
+	}
+}
+
+// chunkRange returns a run container holding the part of the range [lo, hi)
+// whose high 16 bits are k.
+func chunkRange(k, lo, hi uint64) *container {
+	start := uint16(max(lo, k<<16))
+	last := uint16(min(hi-1, k<<16|0xffff))
+	return &container{
+		kind: runKind,
+		n:    int(last-start) + 1,
+		runs: []interval{{start, last}},
+	}
+}
+
+// AddSet adds the elements of set s2 to s.
+func (s *Set) AddSet(s2 *Set) {
+	s.keys, s.cs = merge(s, s2, unionOp, false)
+	s.mod++
+}
+
+// Remove removes elements from a set.
+// Elements that are not present are ignored.
+func (s *Set) Remove(v ...uint32) {
+	for _, vv := range v {
+		hi, lo := split(vv)
+		i, ok := slices.BinarySearch(s.keys, hi)
+		if !ok {
+			continue
+		}
+		s.cs[i].remove(lo)
+		if s.cs[i].n == 0 {
+			s.removeAt(i)
+		}
+	}
+	s.mod++
+}
+
+// RemoveRange removes the elements lo, lo+1, ..., hi-1 from a set.
+// Elements that are not present are ignored.
+func (s *Set) RemoveRange(lo, hi uint64) {
+	hi = min(hi, 1<<32)
+	if hi <= lo {
+		return
+	}
+	s.mod++
+	i, _ := slices.BinarySearch(s.keys, uint16(lo>>16))
+	for i < len(s.keys) && uint64(s.keys[i]) <= (hi-1)>>16 {
+		if c := andNot(s.cs[i], chunkRange(uint64(s.keys[i]), lo, hi)); c != nil {
+			s.cs[i] = c
+			i++
+		} else {
+			s.removeAt(i)
+		}
+	}
+}
+
+// RemoveSet removes the elements of set s2 from s.
+// Elements present in s2 but not s are ignored.
+func (s *Set) RemoveSet(s2 *Set) {
+	s.keys, s.cs = merge(s, s2, differenceOp, false)
+	s.mod++
+}
+
+// RetainSet removes the elements of s that are not present in s2,
+// leaving s as the intersection of s and s2.
+func (s *Set) RetainSet(s2 *Set) {
+	s.keys, s.cs = merge(s, s2, intersectionOp, false)
+	s.mod++
+}
+
+// XorSet removes the elements of s that are present in s2 and adds the
+// elements of s2 that are not present in s, leaving s as the symmetric
+// difference of s and s2.
+func (s *Set) XorSet(s2 *Set) {
+	s.keys, s.cs = merge(s, s2, xorOp, false)
+	s.mod++
+}
+
+func (s *Set) removeAt(i int) {
+	s.keys = slices.Delete(s.keys, i, i+1)
+	s.cs = slices.Delete(s.cs, i, i+1)
+}
+
+// Contains reports whether v is in the set.
+func (s *Set) Contains(v uint32) bool {
+	hi, lo := split(v)
+	i, ok := slices.BinarySearch(s.keys, hi)
+	return ok && s.cs[i].contains(lo)
+}
+
+// ContainsAny reports whether any of the elements in s2 are in s.
+func (s *Set) ContainsAny(s2 *Set) bool {
+	for i, j := 0, 0; i < len(s.keys) && j < len(s2.keys); {
+		switch {
+		case s.keys[i] < s2.keys[j]:
+			i++
+		case s2.keys[j] < s.keys[i]:
+			j++
+		default:
+			if intersects(s.cs[i], s2.cs[j]) {
+				return true
+			}
+			i++
+			j++
+		}
+	}
+	return false
+}
+
+// ContainsAll reports whether all of the elements in s2 are in s.
+func (s *Set) ContainsAll(s2 *Set) bool {
+	i := 0
+	for j, k := range s2.keys {
+		for i < len(s.keys) && s.keys[i] < k {
+			i++
+		}
+		if i == len(s.keys) || s.keys[i] != k || !subset(s2.cs[j], s.cs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether s and s2 contain the same elements.
+func (s *Set) Equal(s2 *Set) bool {
+	if !slices.Equal(s.keys, s2.keys) {
+		return false
+	}
+	for i, c := range s.cs {
+		if c2 := s2.cs[i]; c.n != c2.n || !subset(c, c2) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubset reports whether every element of s is in s2.
+func (s *Set) IsSubset(s2 *Set) bool {
+	return s2.ContainsAll(s)
+}
+
+// IsSuperset reports whether every element of s2 is in s.
+// It is the same as ContainsAll.
+func (s *Set) IsSuperset(s2 *Set) bool {
+	return s.ContainsAll(s2)
+}
+
+// IsProperSubset reports whether s is a subset of s2 and s2 has elements
+// that are not in s.
+func (s *Set) IsProperSubset(s2 *Set) bool {
+	return s.Len() < s2.Len() && s2.ContainsAll(s)
+}
+
+// IsDisjoint reports whether s and s2 have no elements in common.
+func (s *Set) IsDisjoint(s2 *Set) bool {
+	return !s.ContainsAny(s2)
+}
+
+// Clear removes all elements from s, leaving it empty.
+func (s *Set) Clear() {
+	s.keys = nil
+	s.cs = nil
+	s.mod++
+}
+
+// Clone returns a copy of s.
+func (s *Set) Clone() *Set {
+	s1 := &Set{
+		keys: slices.Clone(s.keys),
+		cs:   make([]*container, len(s.cs)),
+	}
+	for i, c := range s.cs {
+		s1.cs[i] = c.clone()
+	}
+	return s1
+}
+
+// Optimize converts each of the containers in s to whichever kind takes
+// the least space. Set operations choose the best kinds for their results,
+// but Add and Remove change the kinds of containers only when they must,
+// so Optimize can make a set built one element at a time smaller.
+func (s *Set) Optimize() {
+	for _, c := range s.cs {
+		c.normalize()
+	}
+	s.mod++
+}
+
+// RemoveIf deletes any elements from s for which remove returns true.
+func (s *Set) RemoveIf(remove func(uint32) bool) {
+	s.mod++
+	n := 0
+	for i, c := range s.cs {
+		k := s.keys[i]
+		kept := make([]uint16, 0, c.n)
+		c.each(func(x uint16) bool {
+			if !remove(uint32(k)<<16 | uint32(x)) {
+				kept = append(kept, x)
+			}
+			return true
+		})
+		if len(kept) < c.n {
+			c = newArray(kept)
+		}
+		if c != nil {
+			s.keys[n], s.cs[n] = k, c
+			n++
+		}
+	}
+	clear(s.cs[n:])
+	s.keys, s.cs = s.keys[:n], s.cs[:n]
+}
+
+// Len returns the number of elements in s.
+func (s *Set) Len() int {
+	n := 0
+	for _, c := range s.cs {
+		n += c.n
+	}
+	return n
+}
+
+// All returns an iterator over the elements in the set in ascending order.
+// If the set is modified during iteration, the iterator produces each
+// element at most once, and elements greater than the most recently
+// produced one are produced if they are present when they are reached.
+func (s *Set) All() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		// All elements less than next have been considered.
+		var next uint64
+		for next <= math.MaxUint32 {
+			hi, lo := split(uint32(next))
+			i, ok := slices.BinarySearch(s.keys, hi)
+			if i == len(s.keys) {
+				return
+			}
+			if !ok {
+				lo = 0
+			}
+			k := s.keys[i]
+			mod := s.mod
+			stop := false
+			if s.cs[i].eachFrom(lo, func(x uint16) bool {
+				v := uint32(k)<<16 | uint32(x)
+				if !yield(v) {
+					stop = true
+					return false
+				}
+				next = uint64(v) + 1
+				// If yield modified the set, the container may have
+				// changed; find our place again.
+				return s.mod == mod
+			}) {
+				next = (uint64(k) + 1) << 16
+			}
+			if stop {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the elements in the set in descending
+// order. Its behavior under modification mirrors that of All.
+func (s *Set) Backward() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		// All elements greater than prev have been considered.
+		prev := int64(math.MaxUint32)
+		for prev >= 0 {
+			hi, lo := split(uint32(prev))
+			i, ok := slices.BinarySearch(s.keys, hi)
+			if !ok {
+				i--
+				lo = math.MaxUint16
+			}
+			if i < 0 {
+				return
+			}
+			k := s.keys[i]
+			mod := s.mod
+			stop := false
+			if s.cs[i].eachBackFrom(lo, func(x uint16) bool {
+				v := uint32(k)<<16 | uint32(x)
+				if !yield(v) {
+					stop = true
+					return false
+				}
+				prev = int64(v) - 1
+				return s.mod == mod
+			}) {
+				prev = int64(k)<<16 - 1
+			}
+			if stop {
+				return
+			}
+		}
+	}
+}
+
+// Rank returns the number of elements in s that are less than v.
+func (s *Set) Rank(v uint32) int {
+	hi, lo := split(v)
+	n := 0
+	for i, k := range s.keys {
+		if k >= hi {
+			if k == hi {
+				n += s.cs[i].rank(lo)
+			}
+			break
+		}
+		n += s.cs[i].n
+	}
+	return n
+}
+
+// Select returns the element of s with rank i; that is, the element that
+// is greater than exactly i other elements.
+// Select panics if i is negative or is not less than s.Len().
+func (s *Set) Select(i int) uint32 {
+	if i >= 0 {
+		j := i
+		for ci, c := range s.cs {
+			if j < c.n {
+				return uint32(s.keys[ci])<<16 | uint32(c.sel(j))
+			}
+			j -= c.n
+		}
+	}
+	panic(fmt.Sprintf("roaring: index %d out of range [0:%d]", i, s.Len()))
+}
+
+// Union constructs a new set containing the union of s1 and s2.
+func Union(s1, s2 *Set) *Set {
+	return newMerged(s1, s2, unionOp)
+}
+
+// Intersection constructs a new set containing the intersection of s1 and s2.
+func Intersection(s1, s2 *Set) *Set {
+	return newMerged(s1, s2, intersectionOp)
+}
+
+// Difference constructs a new set containing the elements of s1 that
+// are not present in s2.
+func Difference(s1, s2 *Set) *Set {
+	return newMerged(s1, s2, differenceOp)
+}
+
+// SymmetricDifference constructs a new set containing the elements that are
+// present in exactly one of s1 and s2.
+func SymmetricDifference(s1, s2 *Set) *Set {
+	return newMerged(s1, s2, xorOp)
+}
+
+// A setOp describes how to combine two sets, container by container.
+type setOp struct {
+	// both combines two containers with the same key.
+	both func(a, b *container) *container
+	// keep1 and keep2 report whether to keep a container whose key is
+	// present only in the first or second set.
+	keep1, keep2 bool
+}
+
+var (
+	unionOp        = setOp{or, true, true}
+	intersectionOp = setOp{and, false, false}
+	differenceOp   = setOp{andNot, true, false}
+	xorOp          = setOp{xor, true, true}
+)
+
+func newMerged(s1, s2 *Set, op setOp) *Set {
+	keys, cs := merge(s1, s2, op, true)
+	return &Set{keys: keys, cs: cs}
+}
+
+// merge combines s1 and s2 according to op and returns the keys and
+// containers of the result. Containers kept from s2 are copied; those kept
+// from s1 are copied if copy1 is set.
+func merge(s1, s2 *Set, op setOp, copy1 bool) ([]uint16, []*container) {
+	var keys []uint16
+	var cs []*container
+	add := func(k uint16, c *container) {
+		if c != nil {
+			keys = append(keys, k)
+			cs = append(cs, c)
+		}
+	}
+	i, j := 0, 0
+	for i < len(s1.keys) || j < len(s2.keys) {
+		switch {
+		case j == len(s2.keys) || i < len(s1.keys) && s1.keys[i] < s2.keys[j]:
+			if op.keep1 {
+				c := s1.cs[i]
+				if copy1 {
+					c = c.clone()
+				}
+				add(s1.keys[i], c)
+			}
+			i++
+		case i == len(s1.keys) || s2.keys[j] < s1.keys[i]:
+			if op.keep2 {
+				add(s2.keys[j], s2.cs[j].clone())
+			}
+			j++
+		default:
+			add(s1.keys[i], op.both(s1.cs[i], s2.cs[j]))
+			i++
+			j++
+		}
+	}
+	return keys, cs
+}
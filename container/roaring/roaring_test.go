@@ -0,0 +1,495 @@
+package roaring
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/cespare/next/container/set"
+)
+
+func TestBasic(t *testing.T) {
+	var s Set
+	check(t, &s)
+	s.Add(5, 1<<20, 3, 5, math.MaxUint32)
+	check(t, &s, 3, 5, 1<<20, math.MaxUint32)
+	if !s.Contains(1<<20) || s.Contains(4) || s.Contains(1<<20+1) {
+		t.Fatal("Contains gave wrong result")
+	}
+	s.Remove(5, 6, 1<<20)
+	check(t, &s, 3, math.MaxUint32)
+	if len(s.keys) != 2 {
+		t.Errorf("after removing the only element of a container, got %d containers; want 2", len(s.keys))
+	}
+	if got, want := fmt.Sprintf("%v %#v", &s, &s), "roaring[3 4294967295] roaring.Of(3, 4294967295)"; got != want {
+		t.Errorf("Sprintf: got %q; want %q", got, want)
+	}
+	s.Clear()
+	check(t, &s)
+}
+
+func TestContainerKinds(t *testing.T) {
+	var s Set
+	for i := range uint32(arrayMax) {
+		s.Add(2 * i)
+	}
+	checkKind(t, &s, 0, arrayKind)
+	s.Add(1)
+	checkKind(t, &s, 0, bitmapKind)
+	s.Remove(1)
+	checkKind(t, &s, 0, arrayKind)
+
+	s.Clear()
+	s.AddRange(100, 60000)
+	checkKind(t, &s, 0, runKind)
+	if got := s.Len(); got != 59900 {
+		t.Fatalf("Len after AddRange: got %d; want 59900", got)
+	}
+	// Punch enough holes that runs take more space than a bitmap.
+	for i := uint32(200); i < 200+2*maxRuns; i += 2 {
+		s.Remove(i)
+	}
+	checkKind(t, &s, 0, bitmapKind)
+	s.RemoveRange(0, 1<<16)
+	if len(s.keys) != 0 {
+		t.Fatalf("after removing everything, got %d containers", len(s.keys))
+	}
+
+	// Optimize turns a dense array into runs.
+	s.Clear()
+	for i := range uint32(1000) {
+		s.Add(i)
+	}
+	checkKind(t, &s, 0, arrayKind)
+	s.Optimize()
+	checkKind(t, &s, 0, runKind)
+	check(t, &s, seq(0, 1000)...)
+
+	s.Clear()
+	s.AddRange(0, 1<<32)
+	if got := s.Len(); got != 1<<32 {
+		t.Fatalf("Len after adding every value: got %d", got)
+	}
+	if len(s.keys) != 1<<16 || s.cs[0].kind != runKind || len(s.cs[0].runs) != 1 {
+		t.Fatal("adding every value did not give full run containers")
+	}
+}
+
+// TestOpKinds checks that the functions that combine sets choose the
+// right kind of container for each result, whatever the kinds of their
+// operands.
+func TestOpKinds(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	random := func(n int) *Set {
+		s := new(Set)
+		for s.Len() < n {
+			s.Add(uint32(r.IntN(1 << 16)))
+		}
+		return s
+	}
+	span := func(lo, hi uint64) *Set {
+		s := new(Set)
+		s.AddRange(lo, hi)
+		return s
+	}
+	a1, a2 := random(3000), random(3000)
+	b1, b2 := random(10000), random(10000)
+	for _, tt := range []struct {
+		name string
+		s    *Set
+		want kind // 0 for no container
+	}{
+		{"Union(array, array)", Union(a1, a2), bitmapKind},
+		{"Union(array, run)", Union(Of(5), span(0, 1000)), runKind},
+		{"Union(run, run)", Union(span(0, 1000), span(500, 2000)), runKind},
+		{"Union(bitmap, run)", Union(b1, span(0, 1<<16)), runKind},
+		{"Intersection(bitmap, bitmap)", Intersection(b1, b2), arrayKind},
+		{"Intersection(array, run)", Intersection(a1, span(0, 1<<16)), arrayKind},
+		{"Intersection(bitmap, run)", Intersection(b1, span(0, 40000)), bitmapKind},
+		{"Intersection(run, run)", Intersection(span(0, 1000), span(500, 2000)), runKind},
+		{"Difference(bitmap, bitmap)", Difference(b1, Difference(b1, Of(b1.Select(0), b1.Select(9)))), arrayKind},
+		{"Difference(run, array)", Difference(span(0, 1000), Of(500)), runKind},
+		{"Difference(run, bitmap)", Difference(span(0, 1<<16), b1), bitmapKind},
+		{"SymmetricDifference(run, run)", SymmetricDifference(span(0, 1000), span(500, 2000)), runKind},
+		{"SymmetricDifference(bitmap, bitmap)", SymmetricDifference(b1, b1.Clone()), 0},
+		{"SymmetricDifference(array, bitmap)", SymmetricDifference(a1, Union(a1, random(1500))), arrayKind},
+	} {
+		checkInvariants(t, tt.s)
+		if tt.want == 0 {
+			if len(tt.s.keys) != 0 {
+				t.Errorf("%s: got %d containers; want none", tt.name, len(tt.s.keys))
+			}
+			continue
+		}
+		if len(tt.s.keys) != 1 {
+			t.Errorf("%s: got %d containers; want 1", tt.name, len(tt.s.keys))
+		} else if got := tt.s.cs[0].kind; got != tt.want {
+			t.Errorf("%s: got kind %d; want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func checkKind(t *testing.T, s *Set, key uint16, want kind) {
+	t.Helper()
+	i, ok := slices.BinarySearch(s.keys, key)
+	if !ok {
+		t.Fatalf("no container for key %d", key)
+	}
+	if got := s.cs[i].kind; got != want {
+		t.Fatalf("container %d: got kind %d; want %d", key, got, want)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	for _, tt := range []struct {
+		lo, hi uint64
+	}{
+		{0, 0}, {5, 3}, {0, 1}, {3, 10}, {65530, 65540}, {100, 200000}, {1<<32 - 3, 1 << 32},
+	} {
+		s := new(Set)
+		s.AddRange(tt.lo, tt.hi)
+		if got, want := uint64(s.Len()), tt.hi-min(tt.lo, tt.hi); got != want {
+			t.Errorf("AddRange(%d, %d): got Len = %d; want %d", tt.lo, tt.hi, got, want)
+		}
+		if tt.hi > tt.lo {
+			if v, _ := s.first(); uint64(v) != tt.lo {
+				t.Errorf("AddRange(%d, %d): got first element %d", tt.lo, tt.hi, v)
+			}
+			if v := s.Select(s.Len() - 1); uint64(v) != tt.hi-1 {
+				t.Errorf("AddRange(%d, %d): got last element %d", tt.lo, tt.hi, v)
+			}
+		}
+		s.RemoveRange(tt.lo, tt.hi)
+		check(t, s)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("AddRange past 1<<32 did not panic")
+			}
+		}()
+		new(Set).AddRange(0, 1<<32+1)
+	}()
+}
+
+func (s *Set) first() (uint32, bool) {
+	for v := range s.All() {
+		return v, true
+	}
+	return 0, false
+}
+
+func TestRankSelect(t *testing.T) {
+	s := Of(3, 10, 1<<16, 1<<16+5, 1<<30)
+	s.AddRange(1<<20, 1<<20+100)
+	want := slices.Collect(s.All())
+	for i, v := range want {
+		if got := s.Select(i); got != v {
+			t.Errorf("Select(%d): got %d; want %d", i, got, v)
+		}
+		if got := s.Rank(v); got != i {
+			t.Errorf("Rank(%d): got %d; want %d", v, got, i)
+		}
+		if got := s.Rank(v + 1); got != i+1 {
+			t.Errorf("Rank(%d): got %d; want %d", v+1, got, i+1)
+		}
+	}
+	if got := s.Rank(0); got != 0 {
+		t.Errorf("Rank(0): got %d", got)
+	}
+	if got := s.Rank(math.MaxUint32); got != len(want) {
+		t.Errorf("Rank(MaxUint32): got %d; want %d", got, len(want))
+	}
+	for _, i := range []int{-1, len(want)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Select(%d) did not panic", i)
+				}
+			}()
+			s.Select(i)
+		}()
+	}
+}
+
+func TestIterate(t *testing.T) {
+	s := Of(1, 5, 1<<16, 1<<16+1, math.MaxUint32)
+	if got := slices.Collect(s.Backward()); !slices.Equal(got, []uint32{math.MaxUint32, 1<<16 + 1, 1 << 16, 5, 1}) {
+		t.Errorf("Backward: got %v", got)
+	}
+	for v := range s.All() {
+		if v != 1 {
+			t.Fatalf("first element: got %d", v)
+		}
+		break
+	}
+
+	// Elements added ahead of the iterator are produced;
+	// removed ones are not.
+	var got []uint32
+	for v := range s.All() {
+		got = append(got, v)
+		switch v {
+		case 1:
+			s.Add(2, 0)
+			s.Remove(1 << 16)
+		case 5:
+			s.Remove(1<<16 + 1)
+			s.Add(1 << 17)
+		case 1 << 17:
+			s.Remove(math.MaxUint32)
+		}
+	}
+	if !slices.Equal(got, []uint32{1, 2, 5, 1 << 17}) {
+		t.Errorf("All with modification: got %v", got)
+	}
+
+	s = Of(1, 5, 1<<16, 1<<16+1, math.MaxUint32)
+	got = nil
+	for v := range s.Backward() {
+		got = append(got, v)
+		switch v {
+		case math.MaxUint32:
+			s.Remove(1<<16 + 1)
+			s.Add(1<<16 - 1)
+		case 1<<16 - 1:
+			s.Clear()
+		}
+	}
+	if !slices.Equal(got, []uint32{math.MaxUint32, 1 << 16, 1<<16 - 1}) {
+		t.Errorf("Backward with modification: got %v", got)
+	}
+}
+
+func TestRemoveIf(t *testing.T) {
+	s := new(Set)
+	s.AddRange(0, 200000)
+	s.RemoveIf(func(v uint32) bool { return v%3 != 0 || v >= 70000 })
+	var want []uint32
+	for i := uint32(0); i < 70000; i += 3 {
+		want = append(want, i)
+	}
+	check(t, s, want...)
+}
+
+func TestCloneIndependent(t *testing.T) {
+	s := Of(1, 2, 1<<20)
+	c := s.Clone()
+	c.Add(3)
+	c.Remove(1 << 20)
+	check(t, s, 1, 2, 1<<20)
+	check(t, c, 1, 2, 3)
+}
+
+// gen generates random sets whose containers are a mix of all kinds.
+type gen struct {
+	r *rand.Rand
+}
+
+func (g gen) value() uint32 {
+	// Concentrate values in a few chunks so that containers overlap.
+	return uint32(g.r.IntN(4))<<16 | uint32(g.r.IntN(1<<16))
+}
+
+func (g gen) sets() (*Set, *set.Set[uint32]) {
+	s, m := new(Set), new(set.Set[uint32])
+	for range g.r.IntN(4) {
+		switch g.r.IntN(3) {
+		case 0: // sparse
+			for range g.r.IntN(100) {
+				v := g.value()
+				s.Add(v)
+				m.Add(v)
+			}
+		case 1: // dense
+			base := g.value() &^ 0xffff
+			for range 5000 + g.r.IntN(5000) {
+				v := base | uint32(g.r.IntN(1<<14))
+				s.Add(v)
+				m.Add(v)
+			}
+		case 2: // runs
+			for range g.r.IntN(10) {
+				lo := uint64(g.value())
+				hi := lo + uint64(g.r.IntN(2000))
+				s.AddRange(lo, hi)
+				for v := lo; v < hi; v++ {
+					m.Add(uint32(v))
+				}
+			}
+		}
+	}
+	if g.r.IntN(2) == 0 {
+		s.Optimize()
+	}
+	return s, m
+}
+
+func TestRandom(t *testing.T) {
+	g := gen{rand.New(rand.NewPCG(1, 2))}
+	for range 100 {
+		s1, m1 := g.sets()
+		s2, m2 := g.sets()
+		checkModel(t, s1, m1)
+		checkModel(t, Union(s1, s2), set.Union(m1, m2))
+		checkModel(t, Intersection(s1, s2), set.Intersection(m1, m2))
+		checkModel(t, Difference(s1, s2), set.Difference(m1, m2))
+		checkModel(t, SymmetricDifference(s1, s2), set.SymmetricDifference(m1, m2))
+		for _, f := range []struct {
+			name      string
+			got, want bool
+		}{
+			{"ContainsAll", s1.ContainsAll(s2), m1.ContainsAll(m2)},
+			{"ContainsAll(intersection)", s1.ContainsAll(Intersection(s1, s2)), true},
+			{"ContainsAny", s1.ContainsAny(s2), m1.ContainsAny(m2)},
+			{"Equal", s1.Equal(s2), m1.Equal(m2)},
+			{"Equal(clone)", s1.Equal(s1.Clone()), true},
+		} {
+			if f.got != f.want {
+				t.Fatalf("%s: got %t; want %t", f.name, f.got, f.want)
+			}
+		}
+
+		// In-place operations.
+		for _, op := range []struct {
+			f func(s, s2 *Set)
+			g func(m, m2 *set.Set[uint32])
+		}{
+			{(*Set).AddSet, (*set.Set[uint32]).AddSet},
+			{(*Set).RetainSet, (*set.Set[uint32]).RetainSet},
+			{(*Set).RemoveSet, (*set.Set[uint32]).RemoveSet},
+			{(*Set).XorSet, (*set.Set[uint32]).XorSet},
+		} {
+			s, m := s1.Clone(), m1.Clone()
+			op.f(s, s2)
+			op.g(m, m2)
+			checkModel(t, s, m)
+		}
+		checkModel(t, s2, m2)
+
+		// Single-element changes.
+		for range 500 {
+			v := g.value()
+			if g.r.IntN(2) == 0 {
+				s1.Add(v)
+				m1.Add(v)
+			} else {
+				s1.Remove(v)
+				m1.Remove(v)
+			}
+		}
+		checkModel(t, s1, m1)
+		lo := uint64(g.value())
+		hi := lo + uint64(g.r.IntN(100000))
+		s1.RemoveRange(lo, hi)
+		m1.RemoveIf(func(v uint32) bool { return uint64(v) >= lo && uint64(v) < hi })
+		checkModel(t, s1, m1)
+	}
+}
+
+func checkModel(t *testing.T, s *Set, m *set.Set[uint32]) {
+	t.Helper()
+	checkInvariants(t, s)
+	want := slices.Sorted(m.All())
+	got := slices.Collect(s.All())
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %d elements; want %d (first difference at %d)", len(got), len(want), firstDiff(got, want))
+	}
+	if s.Len() != len(want) {
+		t.Fatalf("Len: got %d; want %d", s.Len(), len(want))
+	}
+	for range 20 {
+		if len(want) == 0 {
+			break
+		}
+		i := rand.IntN(len(want))
+		if got := s.Select(i); got != want[i] {
+			t.Fatalf("Select(%d): got %d; want %d", i, got, want[i])
+		}
+		if got := s.Rank(want[i]); got != i {
+			t.Fatalf("Rank(%d): got %d; want %d", want[i], got, i)
+		}
+		if !s.Contains(want[i]) || s.Contains(want[i]^1) != m.Contains(want[i]^1) {
+			t.Fatalf("Contains gave wrong result near %d", want[i])
+		}
+	}
+}
+
+func checkInvariants(t *testing.T, s *Set) {
+	t.Helper()
+	if len(s.keys) != len(s.cs) {
+		t.Fatalf("%d keys but %d containers", len(s.keys), len(s.cs))
+	}
+	for i, c := range s.cs {
+		if i > 0 && s.keys[i] <= s.keys[i-1] {
+			t.Fatalf("keys out of order: %d after %d", s.keys[i], s.keys[i-1])
+		}
+		var ok bool
+		switch c.kind {
+		case arrayKind:
+			ok = validArray(c)
+		case bitmapKind:
+			ok = validBitmap(c)
+		case runKind:
+			ok = validRuns(c)
+		}
+		if !ok {
+			t.Fatalf("container %d (kind %d, n = %d) is invalid", s.keys[i], c.kind, c.n)
+		}
+	}
+}
+
+func firstDiff(a, b []uint32) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
+
+func check(t *testing.T, s *Set, want ...uint32) {
+	t.Helper()
+	checkInvariants(t, s)
+	if got := slices.Collect(s.All()); !slices.Equal(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+	if got := s.Len(); got != len(want) {
+		t.Fatalf("Len: got %d; want %d", got, len(want))
+	}
+}
+
+func seq(lo, hi uint32) []uint32 {
+	var s []uint32
+	for v := lo; v < hi; v++ {
+		s = append(s, v)
+	}
+	return s
+}
+
+func BenchmarkUnion(b *testing.B) {
+	r := rand.New(rand.NewPCG(1, 2))
+	s1, s2 := new(Set), new(Set)
+	m1, m2 := new(set.Set[uint32]), new(set.Set[uint32])
+	for range 100_000 {
+		v1, v2 := r.Uint32()>>8, r.Uint32()>>8
+		s1.Add(v1)
+		s2.Add(v2)
+		m1.Add(v1)
+		m2.Add(v2)
+	}
+	b.Run("impl=roaring", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			Union(s1, s2)
+		}
+	})
+	b.Run("impl=map", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			set.Union(m1, m2)
+		}
+	})
+}
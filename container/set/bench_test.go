@@ -0,0 +1,65 @@
+package set
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchSizes lists the operand sizes for the benchmarks of two-set
+// operations, including skewed pairs in which one operand is much smaller.
+var benchSizes = [][2]int{
+	{10, 10},
+	{1000, 1000},
+	{10, 100_000},
+	{100_000, 10},
+	{1000, 100_000},
+	{100_000, 1000},
+	{100_000, 100_000},
+}
+
+// benchSets returns a pair of sets of the given sizes in which half of the
+// elements of the smaller set are also in the larger one.
+func benchSets(n1, n2 int) (s1, s2 *Set[int]) {
+	overlap := min(n1, n2) / 2
+	s1, s2 = new(Set[int]), new(Set[int])
+	for i := range n1 {
+		s1.Add(i)
+	}
+	for i := range n2 {
+		s2.Add(n1 - overlap + i)
+	}
+	return s1, s2
+}
+
+func runBench(b *testing.B, f func(s1, s2 *Set[int])) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("n1=%d/n2=%d", size[0], size[1]), func(b *testing.B) {
+			s1, s2 := benchSets(size[0], size[1])
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				f(s1, s2)
+			}
+		})
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	runBench(b, func(s1, s2 *Set[int]) { Union(s1, s2) })
+}
+
+func BenchmarkIntersection(b *testing.B) {
+	runBench(b, func(s1, s2 *Set[int]) { Intersection(s1, s2) })
+}
+
+func BenchmarkDifference(b *testing.B) {
+	runBench(b, func(s1, s2 *Set[int]) { Difference(s1, s2) })
+}
+
+func BenchmarkSymmetricDifference(b *testing.B) {
+	runBench(b, func(s1, s2 *Set[int]) { SymmetricDifference(s1, s2) })
+}
+
+func BenchmarkContainsAny(b *testing.B) {
+	runBench(b, func(s1, s2 *Set[int]) { s1.ContainsAny(s2) })
+}
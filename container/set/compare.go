@@ -0,0 +1,148 @@
+package set
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+)
+
+// sortedElems returns the elements of s in a deterministic order.
+// Elements of ordered types (numbers and strings) are sorted in their natural
+// order, with NaNs first. Other types are ordered by compareValues.
+func sortedElems[E comparable](s *Set[E]) []E {
+	elems := make([]E, 0, s.Len())
+	for v := range s.All() {
+		elems = append(elems, v)
+	}
+	slices.SortFunc(elems, compareFunc[E]())
+	return elems
+}
+
+// compareFunc returns a function that compares elements of type E in the
+// order defined by compareValues. For the predeclared number and string
+// types, it is cmp.Compare, which avoids the cost of reflection.
+func compareFunc[E comparable]() func(a, b E) int {
+	var f any
+	switch any(*new(E)).(type) {
+	case int:
+		f = cmp.Compare[int]
+	case int8:
+		f = cmp.Compare[int8]
+	case int16:
+		f = cmp.Compare[int16]
+	case int32:
+		f = cmp.Compare[int32]
+	case int64:
+		f = cmp.Compare[int64]
+	case uint:
+		f = cmp.Compare[uint]
+	case uint8:
+		f = cmp.Compare[uint8]
+	case uint16:
+		f = cmp.Compare[uint16]
+	case uint32:
+		f = cmp.Compare[uint32]
+	case uint64:
+		f = cmp.Compare[uint64]
+	case uintptr:
+		f = cmp.Compare[uintptr]
+	case float32:
+		f = cmp.Compare[float32]
+	case float64:
+		f = cmp.Compare[float64]
+	case string:
+		f = cmp.Compare[string]
+	default:
+		return compareElems[E]
+	}
+	return f.(func(a, b E) int)
+}
+
+func compareElems[E comparable](a, b E) int {
+	return compareValues(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+}
+
+// compareValues compares two values of the same comparable type.
+//
+// Booleans, numbers, and strings compare in their natural order (false before
+// true; NaNs before other floats, as with cmp.Compare; complex numbers by real
+// and then imaginary part). Arrays and structs compare lexicographically by
+// element or field. Pointers compare by the values they point to, with nil
+// first; interfaces compare by dynamic type name and then by value, with nil
+// first. Values of other kinds (channels and unsafe pointers) compare equal.
+//
+// The order is total for types that contain no pointers, interfaces, or
+// channels. Otherwise, distinct values may compare equal, but such values
+// are indistinguishable to encodings that follow pointers.
+func compareValues(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case a.Bool():
+			return 1
+		default:
+			return -1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		ac, bc := a.Complex(), b.Complex()
+		if c := cmp.Compare(real(ac), real(bc)); c != 0 {
+			return c
+		}
+		return cmp.Compare(imag(ac), imag(bc))
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Array:
+		for i := range a.Len() {
+			if c := compareValues(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if c := compareValues(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Pointer, reflect.Interface:
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		if a.Kind() == reflect.Pointer && a.Pointer() == b.Pointer() {
+			return 0
+		}
+		ae, be := a.Elem(), b.Elem()
+		if a.Kind() == reflect.Interface && ae.Type() != be.Type() {
+			return cmp.Compare(ae.Type().String(), be.Type().String())
+		}
+		return compareValues(ae, be)
+	default:
+		return 0
+	}
+}
+
+// isOrdered reports whether t is a number or string type,
+// which compareValues sorts in their natural order.
+func isOrdered(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return false
+}
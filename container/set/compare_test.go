@@ -0,0 +1,79 @@
+package set
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCompareValues(t *testing.T) {
+	one, two := 1, 2
+	for _, tt := range []struct {
+		a, b any // of the same type
+		want int
+	}{
+		{false, true, -1},
+		{true, true, 0},
+		{-3, 2, -1},
+		{uint8(200), uint8(100), 1},
+		{math.NaN(), math.Inf(-1), -1},
+		{math.NaN(), math.NaN(), 0},
+		{-0.0, 0.0, 0},
+		{complex(1, 2), complex(1, 1), 1},
+		{"ab", "b", -1},
+		{[2]int{1, 2}, [2]int{1, 3}, -1},
+		{point{2, 1}, point{1, 9}, 1},
+		{struct{ x, y string }{"a", "b"}, struct{ x, y string }{"a", "b"}, 0},
+		{(*int)(nil), &one, -1},
+		{&two, &one, 1},
+		{&one, &one, 0},
+	} {
+		got := compareValues(reflect.ValueOf(tt.a), reflect.ValueOf(tt.b))
+		if got != tt.want {
+			t.Errorf("compareValues(%#v, %#v): got %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// Interfaces compare by type name, then value.
+	ifaces := []any{nil, 1, "a", 2}
+	v := reflect.ValueOf(ifaces)
+	for _, tt := range []struct {
+		i, j int
+		want int
+	}{
+		{0, 1, -1},
+		{1, 0, 1},
+		{1, 2, -1}, // "int" < "string"
+		{1, 3, -1},
+		{3, 3, 0},
+	} {
+		if got := compareValues(v.Index(tt.i), v.Index(tt.j)); got != tt.want {
+			t.Errorf("compareValues(%#v, %#v): got %d; want %d", ifaces[tt.i], ifaces[tt.j], got, tt.want)
+		}
+	}
+}
+
+func TestCompareFunc(t *testing.T) {
+	// The fast paths for ordered types agree with compareValues.
+	testCompareFunc(t, []int{-3, 0, 2, 2})
+	testCompareFunc(t, []uint8{0, 200, 100})
+	testCompareFunc(t, []float64{math.NaN(), math.Inf(-1), -0.0, 0, 1.5, math.NaN()})
+	testCompareFunc(t, []float32{1, float32(math.NaN()), -2})
+	testCompareFunc(t, []string{"", "b", "ab", "B"})
+	type myInt int
+	testCompareFunc(t, []myInt{2, -1, 2})
+}
+
+func testCompareFunc[E comparable](t *testing.T, vals []E) {
+	t.Helper()
+	f := compareFunc[E]()
+	for _, a := range vals {
+		for _, b := range vals {
+			got := f(a, b)
+			want := compareValues(reflect.ValueOf(a), reflect.ValueOf(b))
+			if got != want {
+				t.Errorf("compareFunc[%T](%v, %v): got %d; want %d", a, a, b, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The encoding does not depend on the process that produced it, and equal
+// sets produce identical encodings. It consists of a version byte (1), a
+// description of the element type, the number of elements as a uvarint, and
+// the elements in sorted order: booleans with false first, numbers and
+// strings in their natural order, and arrays and structs element by element
+// or field by field. The elements are encoded as follows:
+//
+//   - booleans are a single byte, 0 or 1;
+//   - signed integers are zigzag varints and unsigned integers are uvarints
+//     (as written by binary.AppendVarint and binary.AppendUvarint);
+//   - floating-point numbers are their IEEE 754 bits in big-endian order,
+//     with -0 written as 0 and every NaN written as the same quiet NaN;
+//   - complex numbers are their real and imaginary parts;
+//   - strings are their length as a uvarint followed by their bytes;
+//   - arrays and structs are their elements or fields, in order.
+//
+// MarshalBinary returns an error for element types built from other kinds,
+// such as pointers, interfaces, and structs with unexported fields.
+func (s *Set[E]) MarshalBinary() ([]byte, error) {
+	b, err := appendTypeDesc([]byte{binaryVersion}, reflect.TypeFor[E]())
+	if err != nil {
+		return nil, err
+	}
+	elems := sortedElems(s)
+	b = binary.AppendUvarint(b, uint64(len(elems)))
+	for i := range elems {
+		b = appendValue(b, reflect.ValueOf(&elems[i]).Elem())
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces the contents of s with the elements decoded from data,
+// which must have been produced by MarshalBinary for a set with the same
+// element type (or one with the same structure).
+func (s *Set[E]) UnmarshalBinary(data []byte) error {
+	t := reflect.TypeFor[E]()
+	desc, err := appendTypeDesc([]byte{binaryVersion}, t)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || data[0] != binaryVersion {
+		return errors.New("set: unknown binary encoding version")
+	}
+	if !bytes.HasPrefix(data, desc) {
+		return fmt.Errorf("set: binary encoding is not of a set of %s", t)
+	}
+	d := decoder{data[len(desc):]}
+	n, err := d.uvarint()
+	if err != nil {
+		return err
+	}
+	// Check n before allocating. Every element of a type that encodes to
+	// zero bytes is the same element, so there can be at most one.
+	if size := minSize(t); size == 0 && n > 1 || size > 0 && n > uint64(len(d.data)/size) {
+		return errBadBinary
+	}
+	elems := make([]E, n)
+	for i := range elems {
+		if err := d.value(reflect.ValueOf(&elems[i]).Elem()); err != nil {
+			return err
+		}
+	}
+	if len(d.data) > 0 {
+		return errBadBinary
+	}
+	s.Clear()
+	s.Add(elems...)
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder.
+// If MarshalBinary supports the element type, GobEncode uses the same
+// encoding. Otherwise, it encodes the sorted elements using encoding/gob,
+// whose output may differ between processes.
+func (s *Set[E]) GobEncode() ([]byte, error) {
+	if _, err := appendTypeDesc(nil, reflect.TypeFor[E]()); err == nil {
+		return s.MarshalBinary()
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(sortedElems(s)); err != nil {
+		return nil, fmt.Errorf("set: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder.
+// It decodes data produced by GobEncode.
+func (s *Set[E]) GobDecode(data []byte) error {
+	if _, err := appendTypeDesc(nil, reflect.TypeFor[E]()); err == nil {
+		return s.UnmarshalBinary(data)
+	}
+	var elems []E
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&elems); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	s.Clear()
+	s.Add(elems...)
+	return nil
+}
+
+const binaryVersion = 1
+
+// Codes in the description of the element type in the binary encoding.
+// The codes for numbers are followed by the size of the type in bytes
+// (8 for int, uint, and uintptr, whatever their size on the platform).
+const (
+	codeBool    = 'b'
+	codeInt     = 'i'
+	codeUint    = 'u'
+	codeFloat   = 'f'
+	codeComplex = 'c'
+	codeString  = 's'
+	codeArray   = 'a' // followed by the length as a uvarint and the element type
+	codeStruct  = 't' // followed by the number of fields as a uvarint and their types
+)
+
+var errBadBinary = errors.New("set: invalid binary encoding")
+
+// appendTypeDesc appends the description of t to b.
+func appendTypeDesc(b []byte, t reflect.Type) ([]byte, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return append(b, codeBool), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return append(b, codeInt, numSize(t)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return append(b, codeUint, numSize(t)), nil
+	case reflect.Float32, reflect.Float64:
+		return append(b, codeFloat, numSize(t)), nil
+	case reflect.Complex64, reflect.Complex128:
+		return append(b, codeComplex, numSize(t)), nil
+	case reflect.String:
+		return append(b, codeString), nil
+	case reflect.Array:
+		b = append(b, codeArray)
+		b = binary.AppendUvarint(b, uint64(t.Len()))
+		return appendTypeDesc(b, t.Elem())
+	case reflect.Struct:
+		b = append(b, codeStruct)
+		b = binary.AppendUvarint(b, uint64(t.NumField()))
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				return nil, fmt.Errorf("set: binary encoding of %s: field %s is unexported", t, f.Name)
+			}
+			var err error
+			if b, err = appendTypeDesc(b, f.Type); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("set: binary encoding of %s is not supported", t)
+	}
+}
+
+func numSize(t reflect.Type) byte {
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		return 8
+	}
+	return byte(t.Size())
+}
+
+// minSize returns the smallest number of bytes in the encoding of a value
+// of type t, which must be supported by appendTypeDesc.
+func minSize(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return int(t.Size())
+	case reflect.Array:
+		return t.Len() * minSize(t.Elem())
+	case reflect.Struct:
+		n := 0
+		for i := range t.NumField() {
+			n += minSize(t.Field(i).Type)
+		}
+		return n
+	default:
+		return 1
+	}
+}
+
+// appendValue appends the encoding of v, whose type must be supported by
+// appendTypeDesc, to b.
+func appendValue(b []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.AppendVarint(b, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.AppendUvarint(b, v.Uint())
+	case reflect.Float32:
+		return appendFloat32(b, v.Float())
+	case reflect.Float64:
+		return appendFloat64(b, v.Float())
+	case reflect.Complex64:
+		c := v.Complex()
+		return appendFloat32(appendFloat32(b, real(c)), imag(c))
+	case reflect.Complex128:
+		c := v.Complex()
+		return appendFloat64(appendFloat64(b, real(c)), imag(c))
+	case reflect.String:
+		b = binary.AppendUvarint(b, uint64(v.Len()))
+		return append(b, v.String()...)
+	case reflect.Array:
+		for i := range v.Len() {
+			b = appendValue(b, v.Index(i))
+		}
+		return b
+	case reflect.Struct:
+		for i := range v.NumField() {
+			b = appendValue(b, v.Field(i))
+		}
+		return b
+	default:
+		panic("unreachable")
+	}
+}
+
+func appendFloat32(b []byte, f float64) []byte {
+	bits := math.Float32bits(float32(f))
+	switch {
+	case f == 0:
+		bits = 0
+	case math.IsNaN(f):
+		bits = 0x7fc00000
+	}
+	return binary.BigEndian.AppendUint32(b, bits)
+}
+
+func appendFloat64(b []byte, f float64) []byte {
+	bits := math.Float64bits(f)
+	switch {
+	case f == 0:
+		bits = 0
+	case math.IsNaN(f):
+		bits = 0x7ff8000000000000
+	}
+	return binary.BigEndian.AppendUint64(b, bits)
+}
+
+// A decoder decodes values written by appendValue.
+type decoder struct {
+	data []byte
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, errBadBinary
+	}
+	d.data = d.data[n:]
+	return x, nil
+}
+
+func (d *decoder) varint() (int64, error) {
+	x, n := binary.Varint(d.data)
+	if n <= 0 {
+		return 0, errBadBinary
+	}
+	d.data = d.data[n:]
+	return x, nil
+}
+
+func (d *decoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, errBadBinary
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *decoder) float(size uint64) (float64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	if size == 4 {
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+// value decodes a value into v, which must be settable and of a type
+// supported by appendTypeDesc.
+func (d *decoder) value(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := d.next(1)
+		if err != nil {
+			return err
+		}
+		if b[0] > 1 {
+			return errBadBinary
+		}
+		v.SetBool(b[0] == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := d.varint()
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(x) {
+			return fmt.Errorf("set: binary encoding: value %d overflows %s", x, v.Type())
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(x) {
+			return fmt.Errorf("set: binary encoding: value %d overflows %s", x, v.Type())
+		}
+		v.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		f, err := d.float(uint64(v.Type().Size()))
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		size := uint64(v.Type().Size() / 2)
+		re, err := d.float(size)
+		if err != nil {
+			return err
+		}
+		im, err := d.float(size)
+		if err != nil {
+			return err
+		}
+		v.SetComplex(complex(re, im))
+	case reflect.String:
+		n, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		b, err := d.next(n)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	case reflect.Array:
+		for i := range v.Len() {
+			if err := d.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if err := d.value(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		panic("unreachable")
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+// The set is encoded as a JSON array of its elements, each encoded using
+// json.Marshal.
+//
+// The elements are sorted so that equal sets produce identical encodings:
+// numbers are sorted numerically and strings lexically; elements of other
+// types are sorted by their JSON encodings.
+func (s *Set[E]) MarshalJSON() ([]byte, error) {
+	var zero E
+	ordered := isOrdered(reflect.TypeOf(&zero).Elem())
+	var elems []E
+	if ordered {
+		elems = sortedElems(s)
+	} else {
+		elems = slices.Collect(s.All())
+	}
+	encs := make([][]byte, len(elems))
+	for i, v := range elems {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		encs[i] = b
+	}
+	if !ordered {
+		slices.SortFunc(encs, bytes.Compare)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, b := range encs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// The data must be a JSON array or null. The elements of the array are
+// decoded using encoding/json and replace the contents of s.
+// Repeated elements are added once; to treat them as an error,
+// unmarshal using RejectDuplicates.
+// A JSON null leaves the set unchanged.
+func (s *Set[E]) UnmarshalJSON(data []byte) error {
+	return s.unmarshalJSON(data, false)
+}
+
+// RejectDuplicates returns a json.Unmarshaler that decodes into s as
+// s.UnmarshalJSON does, except that it returns an error, leaving s unchanged,
+// if the JSON array contains repeated elements. For example:
+//
+//	err := json.Unmarshal(data, set.RejectDuplicates(s))
+func RejectDuplicates[E comparable](s *Set[E]) json.Unmarshaler {
+	return &rejectDuplicates[E]{s}
+}
+
+type rejectDuplicates[E comparable] struct {
+	s *Set[E]
+}
+
+func (r *rejectDuplicates[E]) UnmarshalJSON(data []byte) error {
+	return r.s.unmarshalJSON(data, true)
+}
+
+func (s *Set[E]) unmarshalJSON(data []byte, strict bool) error {
+	var elems []E
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return err
+	}
+	if elems == nil && bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	var s1 Set[E]
+	for _, v := range elems {
+		if strict && s1.Contains(v) {
+			return fmt.Errorf("set: duplicate element %v in JSON array", v)
+		}
+		s1.Add(v)
+	}
+	s.Clear()
+	s.AddSet(&s1)
+	return nil
+}
@@ -0,0 +1,315 @@
+package set
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+type point struct {
+	X, Y int
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	testBinaryRoundTrip(t, Of[int]())
+	testBinaryRoundTrip(t, Of(3, -1, 2))
+	testBinaryRoundTrip(t, Of("b", "a", ""))
+	testBinaryRoundTrip(t, Of(point{1, 2}, point{-1, 5}, point{1, 1}))
+	testBinaryRoundTrip(t, Of([2]string{"a", "b"}, [2]string{"a", "a"}))
+	testBinaryRoundTrip(t, Of(1.5, math.Inf(-1), 0))
+}
+
+func testBinaryRoundTrip[E comparable](t *testing.T, s *Set[E]) {
+	t.Helper()
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%s.MarshalBinary: %s", s.debug(), err)
+	}
+	got := Of[E]()
+	got.Add(*new(E)) // UnmarshalBinary replaces existing elements
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary: %s", err)
+	}
+	if !got.Equal(s) {
+		t.Fatalf("round trip of %s gave %s", s.debug(), got.debug())
+	}
+}
+
+func TestBinaryDeterministic(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	vals := make([]point, 200)
+	for i := range vals {
+		vals[i] = point{r.IntN(10), r.IntN(1000)}
+	}
+	var first []byte
+	for i := range 10 {
+		r.Shuffle(len(vals), func(i, j int) { vals[i], vals[j] = vals[j], vals[i] })
+		b, err := Of(vals...).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = b
+		} else if !bytes.Equal(b, first) {
+			t.Fatalf("encoding %d differs from the first", i)
+		}
+	}
+}
+
+func TestBinaryNaN(t *testing.T) {
+	s := Of(math.NaN(), 1.0, math.NaN())
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Set[float64]
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.Len() != 2 || !got.Contains(math.NaN()) || !got.Contains(1) {
+		t.Fatalf("round trip of %s gave %s", s, &got)
+	}
+}
+
+func TestBinaryFormat(t *testing.T) {
+	for _, tt := range []struct {
+		s    interface{ MarshalBinary() ([]byte, error) }
+		want []byte
+	}{
+		{Of[int](), []byte{1, 'i', 8, 0}},
+		{Of(3, -1, 2), []byte{1, 'i', 8, 3, 1, 4, 6}},
+		{Of(uint16(300)), []byte{1, 'u', 2, 1, 0xac, 0x02}},
+		{Of("b", "a", ""), []byte{1, 's', 3, 0, 1, 'a', 1, 'b'}},
+		{Of(true, false), []byte{1, 'b', 2, 0, 1}},
+		{Of(float32(-1.5)), []byte{1, 'f', 4, 1, 0xbf, 0xc0, 0, 0}},
+		{Of(math.Copysign(0, -1)), []byte{1, 'f', 8, 1, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{Of(math.NaN()), []byte{1, 'f', 8, 1, 0x7f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{Of(point{1, 2}, point{-1, 0}), []byte{1, 't', 2, 'i', 8, 'i', 8, 2, 1, 0, 2, 4}},
+		{Of([2]bool{true, false}), []byte{1, 'a', 2, 'b', 1, 1, 0}},
+	} {
+		got, err := tt.s.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%v.MarshalBinary: %s", tt.s, err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%v.MarshalBinary: got %v; want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestBinaryErrors(t *testing.T) {
+	if _, err := Of(make(chan int)).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary of set of channels: got nil error")
+	}
+	var s Set[int]
+	if err := s.UnmarshalBinary([]byte("garbage")); err == nil {
+		t.Error("UnmarshalBinary of garbage: got nil error")
+	}
+	b, err := Of("a").MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UnmarshalBinary(b); err == nil {
+		t.Error("UnmarshalBinary of set of strings into set of ints: got nil error")
+	}
+	type hidden struct {
+		X int
+		y int
+	}
+	if _, err := Of(hidden{}).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary of set of structs with unexported fields: got nil error")
+	}
+
+	// Corrupt encodings are rejected and leave the set unchanged.
+	s.Add(7)
+	for _, data := range [][]byte{
+		{},
+		{2, 'i', 8, 0},          // unknown version
+		{1, 'i', 8},             // missing count
+		{1, 'i', 8, 2, 2},       // missing element
+		{1, 'i', 8, 1, 2, 4},    // trailing data
+		{1, 'i', 8, 1, 0x80},    // truncated varint
+		{1, 'i', 8, 0xff, 0x01}, // count exceeds data
+	} {
+		if err := s.UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary(%v): got nil error", data)
+		}
+	}
+	if s.Len() != 1 || !s.Contains(7) {
+		t.Errorf("after failed UnmarshalBinary calls, got %s", &s)
+	}
+	var bs Set[bool]
+	if err := bs.UnmarshalBinary([]byte{1, 'b', 1, 2}); err == nil {
+		t.Error("UnmarshalBinary of invalid boolean: got nil error")
+	}
+	var es Set[struct{}]
+	if err := es.UnmarshalBinary([]byte{1, 't', 0, 2}); err == nil {
+		t.Error("UnmarshalBinary of two empty structs: got nil error")
+	}
+	if err := es.UnmarshalBinary([]byte{1, 't', 0, 1}); err != nil || es.Len() != 1 {
+		t.Errorf("UnmarshalBinary of one empty struct: got %s, %v", &es, err)
+	}
+}
+
+func TestGob(t *testing.T) {
+	type T struct {
+		Name  string
+		Ints  *Set[int]
+		Strs  Set[string]
+		Empty *Set[int]
+	}
+	in := T{
+		Name:  "x",
+		Ints:  Of(3, 1, 2),
+		Strs:  *Of("a", "b"),
+		Empty: Of[int](),
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	var out T
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "x" || !out.Ints.Equal(in.Ints) || !out.Strs.Equal(&in.Strs) {
+		t.Fatalf("gob round trip: got %+v", out)
+	}
+	if out.Empty == nil || out.Empty.Len() != 0 {
+		t.Fatalf("gob round trip of empty set: got %v", out.Empty)
+	}
+}
+
+func TestGobFallback(t *testing.T) {
+	// MarshalBinary doesn't support pointers, but gob does.
+	one, two := 1, 2
+	in := Of(&two, &one)
+	if _, err := in.MarshalBinary(); err == nil {
+		t.Fatal("MarshalBinary of set of pointers: got nil error")
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out *Set[*int]
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for p := range out.All() {
+		got = append(got, *p)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("gob round trip of set of pointers: got %v", got)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	checkMarshalJSON(t, Of[int](), `[]`)
+	checkMarshalJSON(t, emptyOf[int](), `[]`)
+	checkMarshalJSON(t, Of(10, -2, 9, 100), `[-2,9,10,100]`)
+	checkMarshalJSON(t, Of(uint8(200), 3), `[3,200]`)
+	checkMarshalJSON(t, Of(2.5, -1e10, 0.125), `[-10000000000,0.125,2.5]`)
+	checkMarshalJSON(t, Of("b", "B", "a", ""), `["","B","a","b"]`)
+	// Other types are sorted by their encodings.
+	checkMarshalJSON(t, Of(point{10, 1}, point{9, 2}, point{-1, 0}), `[{"X":-1,"Y":0},{"X":10,"Y":1},{"X":9,"Y":2}]`)
+	checkMarshalJSON(t, Of(true, false), `[false,true]`)
+	checkMarshalJSON(t, Of([2]int{1, 2}, [2]int{1, 10}), `[[1,10],[1,2]]`)
+
+	var nilSet *Set[int]
+	checkMarshalJSON(t, nilSet, `null`)
+	checkMarshalJSON(t, struct{ S *Set[string] }{Of("x")}, `{"S":["x"]}`)
+
+	if b, err := json.Marshal(Of(math.NaN())); err == nil {
+		t.Errorf("Marshal of set containing NaN: got %s; want error", b)
+	}
+}
+
+func checkMarshalJSON(t *testing.T, v any, want string) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	if string(b) != want {
+		t.Fatalf("Marshal: got %s; want %s", b, want)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	s := Of(100)
+	if err := json.Unmarshal([]byte(`[3, 1, 2, 1]`), s); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, []int{1, 2, 3})
+
+	// null is a no-op.
+	if err := json.Unmarshal([]byte(` null `), s); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, []int{1, 2, 3})
+
+	if err := json.Unmarshal([]byte(`[]`), s); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, []int{})
+
+	// As with the other decoders, copies of the set see the decoded elements.
+	c := *s
+	if err := json.Unmarshal([]byte(`[4, 5]`), s); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &c, []int{4, 5})
+	if err := json.Unmarshal([]byte(`[6]`), RejectDuplicates(&c)); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, []int{6})
+
+	var v struct{ P *Set[point] }
+	if err := json.Unmarshal([]byte(`{"P": [{"X": 1}, {"Y": 2}]}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	check(t, v.P, []point{{1, 0}, {0, 2}})
+
+	// Round trip.
+	s1 := Of("x", "y", "z")
+	b, err := json.Marshal(s1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s2 Set[string]
+	if err := json.Unmarshal(b, &s2); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &s2, []string{"x", "y", "z"})
+
+	for _, doc := range []string{`{}`, `3`, `["a"]`, `[1,]`} {
+		if err := json.Unmarshal([]byte(doc), new(Set[int])); err == nil {
+			t.Errorf("Unmarshal(%s): got nil error", doc)
+		}
+	}
+}
+
+func TestRejectDuplicates(t *testing.T) {
+	s := Of(100)
+	if err := json.Unmarshal([]byte(`[3, 1, 2]`), RejectDuplicates(s)); err != nil {
+		t.Fatal(err)
+	}
+	check(t, s, []int{1, 2, 3})
+	if err := json.Unmarshal([]byte(`[3, 1, 3]`), RejectDuplicates(s)); err == nil {
+		t.Error("Unmarshal with RejectDuplicates: got nil error")
+	}
+	check(t, s, []int{1, 2, 3})
+
+	// Elements are compared after decoding.
+	var s2 Set[string]
+	if err := json.Unmarshal([]byte(`["a", "\u0061"]`), RejectDuplicates(&s2)); err == nil {
+		t.Error("Unmarshal with RejectDuplicates: got nil error")
+	}
+	check(t, &s2, nil)
+}
@@ -1,12 +1,14 @@
 // Package set defines a Set type that holds a set of elements.
 package set
 
-// TODO(caleb): NaNs?
 // TODO(caleb): We probably need to get rid of the dependencies.
 
 import (
 	"fmt"
+	"io"
 	"iter"
+	"maps"
+	"reflect"
 	"sort"
 	"strings"
 )
@@ -19,47 +21,185 @@ import (
 // Unlike maps, the zero value of a Set is usable; there is no equivalent to make.
 // As with maps, concurrent calls to functions and methods that read values are fine;
 // concurrent calls to functions and methods that write values are racy.
+//
+// Unlike maps, Sets treat all floating-point and complex NaN values (those
+// for which v != v) as a single element. Adding a NaN to a set that already
+// contains one has no effect, Contains reports whether the set has a NaN,
+// and Remove removes it. This also applies to NaNs held in interface
+// elements, but not to other element types that contain NaNs, such as structs
+// with float fields: as with map keys, each such value is a distinct element.
 type Set[E comparable] struct {
 	m map[E]struct{}
+	// nan holds the set's NaN element, which is stored separately because
+	// it can't be found in m. If E can hold NaNs, nan is allocated along
+	// with m, so that copies of the Set share it as they share m.
+	nan *nanSlot[E]
 }
 
-// Of returns a new set containing the listed elements.
-func Of[E comparable](v ...E) *Set[E] {
-	if len(v) == 0 {
-		return &Set[E]{nil}
+// A nanSlot holds the NaN element of a Set, if ok is set.
+// It is the first NaN that was added.
+type nanSlot[E any] struct {
+	v  E
+	ok bool
+}
+
+// init sets s's map to m, which must not be nil, and allocates the storage
+// for its NaN element.
+func (s *Set[E]) init(m map[E]struct{}) {
+	s.m = m
+	if mayHoldNaN[E]() {
+		s.nan = new(nanSlot[E])
 	}
-	m := make(map[E]struct{})
-	for _, vv := range v {
-		m[vv] = struct{}{}
+}
+
+// getNaN returns the NaN element of s, if it has one.
+func (s *Set[E]) getNaN() (v E, ok bool) {
+	if s.nan == nil {
+		return v, false
+	}
+	return s.nan.v, s.nan.ok
+}
+
+func (s *Set[E]) hasNaN() bool {
+	return s.nan != nil && s.nan.ok
+}
+
+// addNaN adds v, which must be a NaN, to s.
+func (s *Set[E]) addNaN(v E) {
+	if s.m == nil {
+		s.init(make(map[E]struct{}))
+	}
+	if !s.nan.ok {
+		*s.nan = nanSlot[E]{v, true}
+	}
+}
+
+func (s *Set[E]) removeNaN() {
+	if s.nan != nil {
+		*s.nan = nanSlot[E]{}
 	}
-	return &Set[E]{m}
+}
+
+// isNaN reports whether v is a floating-point or complex NaN,
+// or an interface value holding one.
+func isNaN[E comparable](v E) bool {
+	if v == v {
+		return false
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// mayHoldNaN reports whether values of type E can be NaNs.
+func mayHoldNaN[E comparable]() bool {
+	switch reflect.TypeFor[E]().Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// Of returns a new set containing the listed elements.
+func Of[E comparable](v ...E) *Set[E] {
+	s := new(Set[E])
+	s.Add(v...)
+	return s
 }
 
 // String returns a human-readable representation of the set.
+// The elements are listed in sorted order: numbers and strings in their
+// natural order (with NaNs first), and elements of other types in the
+// lexical order of their string representations.
 func (s *Set[E]) String() string {
-	// Print it out in some deterministic order for now.
-	// Better would be to sort numbers numerically.
-	vals := make([]string, 0, s.Len())
-	for v := range s.m {
-		vals = append(vals, fmt.Sprint(v))
-	}
-	sort.Strings(vals)
-	return fmt.Sprintf("set[%s]", strings.Join(vals, " "))
+	vals, more := s.formatElems("%v", -1)
+	return formatSet(vals, more)
 }
 
 // GoString returns a Go syntax representation of the set.
+// The elements are listed in the same order as by String.
 func (s *Set[E]) GoString() string {
+	vals, more := s.formatElems("%#v", -1)
+	return s.goString(vals, more)
+}
+
+// Format implements fmt.Formatter. The verbs %v and %s print the set as
+// String does, %#v prints it as GoString does, and other verbs are applied
+// to each element. Flags such as + and # are applied to each element as well:
+// for example, %+v prints struct elements with their field names.
+//
+// A precision limits the number of elements printed: for example,
+// %.3v prints at most the first three elements (in sorted order),
+// followed by a count of the elements that were omitted.
+func (s *Set[E]) Format(f fmt.State, verb rune) {
+	limit, ok := f.Precision()
+	if !ok {
+		limit = -1
+	}
+	if verb == 'v' && f.Flag('#') {
+		vals, more := s.formatElems("%#v", limit)
+		io.WriteString(f, s.goString(vals, more))
+		return
+	}
+	if verb == 's' {
+		verb = 'v'
+	}
+	format := "%"
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			format += string(flag)
+		}
+	}
+	format += string(verb)
+	vals, more := s.formatElems(format, limit)
+	io.WriteString(f, formatSet(vals, more))
+}
+
+func formatSet(vals []string, more int) string {
+	if more > 0 {
+		vals = append(vals, fmt.Sprintf("...(%d more)", more))
+	}
+	return fmt.Sprintf("set[%s]", strings.Join(vals, " "))
+}
+
+func (s *Set[E]) goString(vals []string, more int) string {
 	var v E
 	typeName := fmt.Sprintf("%T", v)
-	vals := make([]string, 0, s.Len())
-	for v := range s.m {
-		vals = append(vals, fmt.Sprintf("%#v", v))
+	elems := strings.Join(vals, ", ")
+	if more > 0 {
+		elems += fmt.Sprintf(" /* %d more */", more)
 	}
-	sort.Strings(vals)
 	// TODO(caleb): Technically this is slightly misleading in the case that
 	// s.m != nil && len(s.m) == 0 because the result does not yield the
 	// same exact thing when interpreted literally as Go code.
-	return fmt.Sprintf("set.Of[%s](%s)", typeName, strings.Join(vals, ", "))
+	return fmt.Sprintf("set.Of[%s](%s)", typeName, elems)
+}
+
+// formatElems formats the elements of s using format, in the order described
+// by String. If limit is non-negative, it formats at most limit elements and
+// also returns the number of elements omitted.
+func (s *Set[E]) formatElems(format string, limit int) (vals []string, more int) {
+	n := s.Len()
+	if limit < 0 || limit > n {
+		limit = n
+	}
+	var zero E
+	if isOrdered(reflect.TypeOf(&zero).Elem()) {
+		elems := sortedElems(s)[:limit]
+		vals = make([]string, len(elems))
+		for i, v := range elems {
+			vals[i] = fmt.Sprintf(format, v)
+		}
+		return vals, n - limit
+	}
+	vals = make([]string, 0, n)
+	for v := range s.All() {
+		vals = append(vals, fmt.Sprintf(format, v))
+	}
+	sort.Strings(vals)
+	return vals[:limit], n - limit
 }
 
 // Add adds elements to a set.
@@ -68,20 +208,27 @@ func (s *Set[E]) Add(v ...E) {
 		return
 	}
 	if s.m == nil {
-		s.m = make(map[E]struct{})
+		s.init(make(map[E]struct{}))
 	}
 	for _, vv := range v {
+		if isNaN(vv) {
+			s.addNaN(vv)
+			continue
+		}
 		s.m[vv] = struct{}{}
 	}
 }
 
 // AddSet adds the elements of set s2 to s.
 func (s *Set[E]) AddSet(s2 *Set[E]) {
+	if v, ok := s2.getNaN(); ok {
+		s.addNaN(v)
+	}
 	if len(s2.m) == 0 {
 		return
 	}
 	if s.m == nil {
-		s.m = make(map[E]struct{})
+		s.init(make(map[E]struct{}))
 	}
 	for v2 := range s2.m {
 		s.m[v2] = struct{}{}
@@ -92,6 +239,10 @@ func (s *Set[E]) AddSet(s2 *Set[E]) {
 // Elements that are not present are ignored.
 func (s *Set[E]) Remove(v ...E) {
 	for _, vv := range v {
+		if isNaN(vv) {
+			s.removeNaN()
+			continue
+		}
 		delete(s.m, vv)
 	}
 }
@@ -99,6 +250,9 @@ func (s *Set[E]) Remove(v ...E) {
 // RemoveSet removes the elements of set s2 from s.
 // Elements present in s2 but not s are ignored.
 func (s *Set[E]) RemoveSet(s2 *Set[E]) {
+	if s2.hasNaN() {
+		s.removeNaN()
+	}
 	for v2 := range s2.m {
 		delete(s.m, v2)
 	}
@@ -106,14 +260,21 @@ func (s *Set[E]) RemoveSet(s2 *Set[E]) {
 
 // Contains reports whether v is in the set.
 func (s *Set[E]) Contains(v E) bool {
+	if isNaN(v) {
+		return s.hasNaN()
+	}
 	_, ok := s.m[v]
 	return ok
 }
 
 // ContainsAny reports whether any of the elements in s2 are in s.
 func (s *Set[E]) ContainsAny(s2 *Set[E]) bool {
-	for v2 := range s2.m {
-		if _, ok := s.m[v2]; ok {
+	if s.hasNaN() && s2.hasNaN() {
+		return true
+	}
+	small, large := smallLarge(s, s2)
+	for v := range small.m {
+		if _, ok := large.m[v]; ok {
 			return true
 		}
 	}
@@ -122,6 +283,9 @@ func (s *Set[E]) ContainsAny(s2 *Set[E]) bool {
 
 // ContainsAll reports whether all of the elements in s2 are in s.
 func (s *Set[E]) ContainsAll(s2 *Set[E]) bool {
+	if !s.hasNaN() && s2.hasNaN() {
+		return false
+	}
 	for v2 := range s2.m {
 		if _, ok := s.m[v2]; !ok {
 			return false
@@ -132,7 +296,7 @@ func (s *Set[E]) ContainsAll(s2 *Set[E]) bool {
 
 // Equal reports whether s and s2 contain the same elements.
 func (s *Set[E]) Equal(s2 *Set[E]) bool {
-	if len(s.m) != len(s2.m) {
+	if len(s.m) != len(s2.m) || s.hasNaN() != s2.hasNaN() {
 		return false
 	}
 	for v := range s.m {
@@ -145,6 +309,7 @@ func (s *Set[E]) Equal(s2 *Set[E]) bool {
 
 // Clear removes all elements from s, leaving it empty.
 func (s *Set[E]) Clear() {
+	s.removeNaN()
 	for v := range s.m {
 		delete(s.m, v)
 	}
@@ -154,18 +319,38 @@ func (s *Set[E]) Clear() {
 // The elements are copied using assignment,
 // so this is a shallow clone.
 func (s *Set[E]) Clone() *Set[E] {
-	if len(s.m) == 0 {
-		return &Set[E]{nil}
+	s1 := new(Set[E])
+	if len(s.m) > 0 {
+		m := make(map[E]struct{}, len(s.m))
+		for v := range s.m {
+			m[v] = struct{}{}
+		}
+		s1.init(m)
 	}
-	m := make(map[E]struct{}, len(s.m))
-	for v := range s.m {
-		m[v] = struct{}{}
+	if v, ok := s.getNaN(); ok {
+		s1.addNaN(v)
 	}
-	return &Set[E]{m}
+	return s1
+}
+
+// duplicate is like Clone, but it copies the map wholesale and keeps a nil map
+// nil and an empty one empty.
+func (s *Set[E]) duplicate() *Set[E] {
+	s1 := new(Set[E])
+	if s.m != nil {
+		s1.init(maps.Clone(s.m))
+	}
+	if v, ok := s.getNaN(); ok {
+		s1.addNaN(v)
+	}
+	return s1
 }
 
 // RemoveIf deletes any elements from s for which remove returns true.
 func (s *Set[E]) RemoveIf(remove func(E) bool) {
+	if v, ok := s.getNaN(); ok && remove(v) {
+		s.removeNaN()
+	}
 	for v := range s.m {
 		if remove(v) {
 			delete(s.m, v)
@@ -175,7 +360,11 @@ func (s *Set[E]) RemoveIf(remove func(E) bool) {
 
 // Len returns the number of elements in s.
 func (s *Set[E]) Len() int {
-	return len(s.m)
+	n := len(s.m)
+	if s.hasNaN() {
+		n++
+	}
+	return n
 }
 
 // All returns an iterator over the elements in the set.
@@ -188,38 +377,200 @@ func (s *Set[E]) All() iter.Seq[E] {
 				return
 			}
 		}
+		if v, ok := s.getNaN(); ok {
+			yield(v)
+		}
 	}
 }
 
+// smallLarge returns s1 and s2 ordered by the sizes of their maps.
+func smallLarge[E comparable](s1, s2 *Set[E]) (small, large *Set[E]) {
+	if len(s2.m) < len(s1.m) {
+		return s2, s1
+	}
+	return s1, s2
+}
+
 // Union constructs a new set containing the union of s1 and s2.
 func Union[E comparable](s1, s2 *Set[E]) *Set[E] {
-	// TODO(caleb): Presize?
-	s := s1.Clone()
-	s.AddSet(s2)
+	s := new(Set[E])
+	if len(s1.m)+len(s2.m) > 0 {
+		// Copy the larger set wholesale, which is much faster than inserting
+		// its elements one by one, and add the elements of the smaller one.
+		small, large := smallLarge(s1, s2)
+		s.init(maps.Clone(large.m))
+		for v := range small.m {
+			s.m[v] = struct{}{}
+		}
+	}
+	if v, ok := s1.getNaN(); ok {
+		s.addNaN(v)
+	} else if v, ok := s2.getNaN(); ok {
+		s.addNaN(v)
+	}
 	return s
 }
 
 // Intersection constructs a new set containing the intersection of s1 and s2.
 func Intersection[E comparable](s1, s2 *Set[E]) *Set[E] {
-	// TODO(caleb): Presize?
 	var s Set[E]
-	for v := range s1.m {
-		if _, ok := s2.m[v]; ok {
-			s.Add(v)
+	// Look up the elements of the smaller set in the larger one. The result
+	// can be no larger than the smaller set.
+	small, large := smallLarge(s1, s2)
+	for v := range small.m {
+		if _, ok := large.m[v]; ok {
+			if s.m == nil {
+				s.init(make(map[E]struct{}, len(small.m)))
+			}
+			s.m[v] = struct{}{}
 		}
 	}
+	if v, ok := s1.getNaN(); ok && s2.hasNaN() {
+		s.addNaN(v)
+	}
 	return &s
 }
 
 // Difference constructs a new set containing the elements of s1 that
 // are not present in s2.
 func Difference[E comparable](s1, s2 *Set[E]) *Set[E] {
-	// TODO(caleb): Presize?
 	var s Set[E]
-	for v := range s1.m {
-		if _, ok := s2.m[v]; !ok {
-			s.Add(v)
+	if len(s2.m) < len(s1.m) {
+		// Copy s1 wholesale and delete the elements of s2 from the copy.
+		s.init(maps.Clone(s1.m))
+		for v := range s2.m {
+			delete(s.m, v)
+		}
+	} else {
+		for v := range s1.m {
+			if _, ok := s2.m[v]; !ok {
+				if s.m == nil {
+					s.init(make(map[E]struct{}, len(s1.m)))
+				}
+				s.m[v] = struct{}{}
+			}
 		}
 	}
+	if v, ok := s1.getNaN(); ok && !s2.hasNaN() {
+		s.addNaN(v)
+	}
 	return &s
 }
+
+// SymmetricDifference constructs a new set containing the elements that are
+// present in exactly one of s1 and s2.
+func SymmetricDifference[E comparable](s1, s2 *Set[E]) *Set[E] {
+	small, large := smallLarge(s1, s2)
+	s := large.duplicate()
+	s.XorSet(small)
+	return s
+}
+
+// UnionAll constructs a new set containing the union of all the given sets.
+// If no sets are given, the result is empty.
+func UnionAll[E comparable](sets ...*Set[E]) *Set[E] {
+	if len(sets) == 0 {
+		return new(Set[E])
+	}
+	// As in Union, start with a copy of the largest set.
+	large := sets[0]
+	for _, s2 := range sets[1:] {
+		if len(s2.m) > len(large.m) {
+			large = s2
+		}
+	}
+	s := large.duplicate()
+	for _, s2 := range sets {
+		if s2 != large {
+			s.AddSet(s2)
+		}
+	}
+	return s
+}
+
+// IntersectAll constructs a new set containing the intersection of all the
+// given sets. If no sets are given, the result is empty.
+func IntersectAll[E comparable](sets ...*Set[E]) *Set[E] {
+	if len(sets) == 0 {
+		return new(Set[E])
+	}
+	// Start with a copy of the smallest set, since the result can be no
+	// larger, and RetainSet iterates over the set being modified.
+	small := sets[0]
+	for _, s2 := range sets[1:] {
+		if len(s2.m) < len(small.m) {
+			small = s2
+		}
+	}
+	s := small.Clone()
+	for _, s2 := range sets {
+		if s.Len() == 0 {
+			break
+		}
+		if s2 != small {
+			s.RetainSet(s2)
+		}
+	}
+	return s
+}
+
+// RetainSet removes the elements of s that are not present in s2,
+// leaving s as the intersection of s and s2.
+func (s *Set[E]) RetainSet(s2 *Set[E]) {
+	if !s2.hasNaN() {
+		s.removeNaN()
+	}
+	for v := range s.m {
+		if _, ok := s2.m[v]; !ok {
+			delete(s.m, v)
+		}
+	}
+}
+
+// XorSet removes the elements of s that are present in s2 and adds the
+// elements of s2 that are not present in s, leaving s as the symmetric
+// difference of s and s2.
+func (s *Set[E]) XorSet(s2 *Set[E]) {
+	if v, ok := s2.getNaN(); ok {
+		if s.hasNaN() {
+			s.removeNaN()
+		} else {
+			s.addNaN(v)
+		}
+	}
+	if len(s2.m) == 0 {
+		return
+	}
+	if s.m == nil {
+		s.init(make(map[E]struct{}))
+	}
+	for v2 := range s2.m {
+		if _, ok := s.m[v2]; ok {
+			delete(s.m, v2)
+		} else {
+			s.m[v2] = struct{}{}
+		}
+	}
+}
+
+// IsSubset reports whether every element of s is in s2.
+func (s *Set[E]) IsSubset(s2 *Set[E]) bool {
+	return s2.ContainsAll(s)
+}
+
+// IsSuperset reports whether every element of s2 is in s.
+// It is the same as ContainsAll.
+func (s *Set[E]) IsSuperset(s2 *Set[E]) bool {
+	return s.ContainsAll(s2)
+}
+
+// IsProperSubset reports whether s is a subset of s2 and s2 has elements
+// that are not in s.
+func (s *Set[E]) IsProperSubset(s2 *Set[E]) bool {
+	return s.Len() < s2.Len() && s2.ContainsAll(s)
+}
+
+// IsDisjoint reports whether s and s2 have no elements in common.
+func (s *Set[E]) IsDisjoint(s2 *Set[E]) bool {
+	return !s.ContainsAny(s2)
+}
@@ -3,8 +3,10 @@ package set
 import (
 	"cmp"
 	"fmt"
+	"math"
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -61,11 +63,72 @@ func TestGoString(t *testing.T) {
 	}
 }
 
+func TestStringOrder(t *testing.T) {
+	for _, tt := range []struct {
+		s            fmt.Stringer
+		want, wantGo string
+	}{
+		{Of(2, 10, -1), "set[-1 2 10]", "set.Of[int](-1, 2, 10)"},
+		{Of(uint8(20), 3), "set[3 20]", "set.Of[uint8](0x3, 0x14)"},
+		{
+			Of(2.5, math.NaN(), -1, math.Inf(1)),
+			"set[NaN -1 2.5 +Inf]",
+			"set.Of[float64](NaN, -1, 2.5, +Inf)",
+		},
+		{Of("b", "B", "a"), "set[B a b]", `set.Of[string]("B", "a", "b")`},
+		// Other types are sorted by their string representations.
+		{Of(point{10, 1}, point{9, 2}), "set[{10 1} {9 2}]", "set.Of[set.point](set.point{X:10, Y:1}, set.point{X:9, Y:2})"},
+	} {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("String: got %q; want %q", got, tt.want)
+		}
+		if got := tt.s.(fmt.GoStringer).GoString(); got != tt.wantGo {
+			t.Errorf("GoString: got %q; want %q", got, tt.wantGo)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	ints := Of(3, 10, 2, 7)
+	for _, tt := range []struct {
+		format string
+		arg    any
+		want   string
+	}{
+		{"%v", ints, "set[2 3 7 10]"},
+		{"%s", ints, "set[2 3 7 10]"},
+		{"%d", ints, "set[2 3 7 10]"},
+		{"%x", ints, "set[2 3 7 a]"},
+		{"%#x", ints, "set[0x2 0x3 0x7 0xa]"},
+		{"%+d", ints, "set[+2 +3 +7 +10]"},
+		{"%#v", ints, "set.Of[int](2, 3, 7, 10)"},
+		{"%.2v", ints, "set[2 3 ...(2 more)]"},
+		{"%.0v", ints, "set[...(4 more)]"},
+		{"%.4v", ints, "set[2 3 7 10]"},
+		{"%.9v", ints, "set[2 3 7 10]"},
+		{"%.1d", ints, "set[2 ...(3 more)]"},
+		{"%#.2v", ints, "set.Of[int](2, 3 /* 2 more */)"},
+		{"%q", Of("b", "a"), `set["a" "b"]`},
+		{"%v", Of(point{1, 2}), "set[{1 2}]"},
+		{"%+v", Of(point{1, 2}), "set[{X:1 Y:2}]"},
+		{"%.1v", Of(point{1, 2}, point{0, 0}), "set[{0 0} ...(1 more)]"},
+		{"%v", Of[int](), "set[]"},
+		{"%.3v", Of[int](), "set[]"},
+		{"%v", []*Set[int]{Of(1)}, "[set[1]]"},
+	} {
+		if got := fmt.Sprintf(tt.format, tt.arg); got != tt.want {
+			t.Errorf("Sprintf(%q, %s): got %q; want %q", tt.format, tt.arg, got, tt.want)
+		}
+	}
+}
+
 // emptyOf returns a set with a non-nil, empty map.
 // We don't provide a direct constructor for this state but we want to cover it
 // in tests.
 func emptyOf[E comparable]() *Set[E] {
-	return &Set[E]{make(map[E]struct{})}
+	s := new(Set[E])
+	s.init(make(map[E]struct{}))
+	return s
 }
 
 func TestAdd(t *testing.T) {
@@ -481,6 +544,309 @@ func TestDifference(t *testing.T) {
 	}
 }
 
+func TestResultsDoNotAlias(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		f    func(s1, s2 *Set[int]) *Set[int]
+	}{
+		{"Union", Union[int]},
+		{"Intersection", Intersection[int]},
+		{"Difference", Difference[int]},
+		{"SymmetricDifference", SymmetricDifference[int]},
+		{"UnionAll", func(s1, s2 *Set[int]) *Set[int] { return UnionAll(s1, s2) }},
+		{"IntersectAll", func(s1, s2 *Set[int]) *Set[int] { return IntersectAll(s1, s2) }},
+	} {
+		for _, sets := range [][2]*Set[int]{
+			{Of(1, 2, 3), Of[int]()},
+			{Of[int](), Of(1, 2, 3)},
+			{Of(1, 2, 3), Of(1, 2, 3)},
+			{Of(1, 2, 3), Of(2)},
+		} {
+			s1, s2 := sets[0], sets[1]
+			old1, old2 := s1.Clone(), s2.Clone()
+			got := tt.f(s1, s2)
+			got.Add(4)
+			got.Remove(1, 2, 3)
+			if !s1.Equal(old1) || !s2.Equal(old2) {
+				t.Errorf("modifying result of %s(%s, %s) modified an argument", tt.name, old1, old2)
+			}
+		}
+	}
+}
+
+func TestSymmetricDifference(t *testing.T) {
+	for _, tt := range []struct {
+		s1   *Set[int]
+		s2   *Set[int]
+		want *Set[int]
+	}{
+		{Of[int](), Of[int](), Of[int]()},
+		{Of[int](), emptyOf[int](), Of[int]()},
+		{emptyOf[int](), Of(3), Of(3)},
+		{Of(3), Of[int](), Of(3)},
+		{Of(3), Of(3), Of[int]()},
+		{Of(3), Of(4), Of(3, 4)},
+		{Of(3, 4), Of(3), Of(4)},
+		{Of(3), Of(3, 4), Of(4)},
+		{Of(3, 4), Of(3, 5), Of(4, 5)},
+		{Of(3, 4, 5), Of(3, 4, 5), Of[int]()},
+	} {
+		oldS1 := clone(tt.s1)
+		oldS2 := clone(tt.s2)
+		got := SymmetricDifference(tt.s1, tt.s2)
+		if !got.Equal(tt.want) {
+			t.Errorf(
+				"SymmetricDifference(%s, %s): got %s; want %s",
+				tt.s1.debug(), tt.s2.debug(), got.debug(), tt.want.debug(),
+			)
+		}
+		if !equal(tt.s1, oldS1) || !equal(tt.s2, oldS2) {
+			t.Errorf(
+				"SymmetricDifference(%s, %s) mutated its args",
+				oldS1.debug(), oldS2.debug(),
+			)
+		}
+
+		s := clone(tt.s1)
+		s.XorSet(tt.s2)
+		if !s.Equal(tt.want) {
+			t.Errorf(
+				"%s.XorSet(%s): got %s; want %s",
+				tt.s1.debug(), tt.s2.debug(), s.debug(), tt.want.debug(),
+			)
+		}
+	}
+
+	s := Of(1, 2)
+	s.XorSet(s)
+	check(t, s, []int{})
+}
+
+func TestRetainSet(t *testing.T) {
+	for _, tt := range []struct {
+		s1   *Set[int]
+		s2   *Set[int]
+		want []int
+	}{
+		{Of[int](), Of[int](), nil},
+		{Of[int](), Of(3), nil},
+		{emptyOf[int](), Of(3), []int{}},
+		{Of(3), Of[int](), []int{}},
+		{Of(3), Of(3), []int{3}},
+		{Of(3), Of(4), []int{}},
+		{Of(3, 4), Of(3), []int{3}},
+		{Of(3), Of(3, 4), []int{3}},
+		{Of(3, 4, 5), Of(3, 5, 6), []int{3, 5}},
+	} {
+		s := clone(tt.s1)
+		s.RetainSet(tt.s2)
+		check(t, s, tt.want)
+	}
+}
+
+func TestUnionAll(t *testing.T) {
+	check(t, UnionAll[int](), nil)
+	check(t, UnionAll(Of[int]()), nil)
+	check(t, UnionAll(Of(1, 2)), []int{1, 2})
+	check(t, UnionAll(Of(1, 2), Of[int](), Of(2, 3), Of(5)), []int{1, 2, 3, 5})
+
+	s := Of(1)
+	got := UnionAll(s, Of(2))
+	got.Add(3)
+	check(t, s, []int{1})
+}
+
+func TestIntersectAll(t *testing.T) {
+	if got := IntersectAll[int](); got.Len() != 0 {
+		t.Errorf("IntersectAll(): got %s; want empty set", got)
+	}
+	for _, tt := range []struct {
+		sets []*Set[int]
+		want *Set[int]
+	}{
+		{[]*Set[int]{Of(1, 2)}, Of(1, 2)},
+		{[]*Set[int]{Of(1, 2), Of[int]()}, Of[int]()},
+		{[]*Set[int]{Of(1, 2, 3), Of(2, 3, 4)}, Of(2, 3)},
+		{[]*Set[int]{Of(1, 2, 3), Of(2, 3, 4), Of(3, 4, 5)}, Of(3)},
+		{[]*Set[int]{Of(1, 2), Of(3, 4), Of(1, 2)}, Of[int]()},
+	} {
+		got := IntersectAll(tt.sets...)
+		if !got.Equal(tt.want) {
+			t.Errorf("IntersectAll(%v): got %s; want %s", tt.sets, got, tt.want)
+		}
+	}
+
+	s := Of(1, 2)
+	got := IntersectAll(s)
+	got.Remove(1)
+	check(t, s, []int{1, 2})
+}
+
+func TestSubset(t *testing.T) {
+	for _, tt := range []struct {
+		s1       *Set[int]
+		s2       *Set[int]
+		subset   bool
+		superset bool
+		proper   bool
+		disjoint bool
+	}{
+		{Of[int](), Of[int](), true, true, false, true},
+		{Of[int](), emptyOf[int](), true, true, false, true},
+		{Of[int](), Of(3), true, false, true, true},
+		{Of(3), Of[int](), false, true, false, true},
+		{Of(3), Of(3), true, true, false, false},
+		{Of(3), Of(4), false, false, false, true},
+		{Of(3), Of(3, 4), true, false, true, false},
+		{Of(3, 4), Of(3), false, true, false, false},
+		{Of(3, 4), Of(4, 5), false, false, false, false},
+		{Of(1, 3, 5), Of(1, 2, 3, 4, 5), true, false, true, false},
+	} {
+		if got := tt.s1.IsSubset(tt.s2); got != tt.subset {
+			t.Errorf("%s.IsSubset(%s): got %t", tt.s1.debug(), tt.s2.debug(), got)
+		}
+		if got := tt.s1.IsSuperset(tt.s2); got != tt.superset {
+			t.Errorf("%s.IsSuperset(%s): got %t", tt.s1.debug(), tt.s2.debug(), got)
+		}
+		if got := tt.s1.IsProperSubset(tt.s2); got != tt.proper {
+			t.Errorf("%s.IsProperSubset(%s): got %t", tt.s1.debug(), tt.s2.debug(), got)
+		}
+		if got := tt.s1.IsDisjoint(tt.s2); got != tt.disjoint {
+			t.Errorf("%s.IsDisjoint(%s): got %t", tt.s1.debug(), tt.s2.debug(), got)
+		}
+	}
+}
+
+func TestNaN(t *testing.T) {
+	t.Run("float32", func(t *testing.T) { testNaN(t, float32(math.NaN()), 1) })
+	t.Run("float64", func(t *testing.T) { testNaN(t, math.NaN(), 1) })
+	t.Run("complex64", func(t *testing.T) { testNaN(t, complex64(complex(math.NaN(), 0)), 1) })
+	t.Run("complex128", func(t *testing.T) { testNaN(t, complex(0, math.NaN()), 1) })
+	t.Run("any", func(t *testing.T) { testNaN[any](t, math.NaN(), 1) })
+}
+
+// testNaN checks the behavior of sets of E, where nan is a NaN and x is
+// some other value.
+func testNaN[E comparable](t *testing.T, nan, x E) {
+	s := Of(nan, x, nan)
+	s.Add(nan)
+	if got := s.Len(); got != 2 {
+		t.Fatalf("after adding NaN three times, got Len() = %d; want 2", got)
+	}
+	if !s.Contains(nan) {
+		t.Fatalf("%v.Contains(NaN) = false", s)
+	}
+	if got := s.String(); strings.Count(got, "NaN") != 1 {
+		t.Errorf("String() = %q; want one NaN", got)
+	}
+	var n int
+	for v := range s.All() {
+		if v != v {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("All yielded %d NaNs; want 1", n)
+	}
+
+	onlyX := Of(x)
+	onlyNaN := Of(nan)
+	if !s.Equal(Of(x, nan)) || s.Equal(onlyX) || onlyX.Equal(s) {
+		t.Error("Equal gave wrong result for sets with NaNs")
+	}
+	if !s.ContainsAll(onlyNaN) || onlyX.ContainsAll(onlyNaN) {
+		t.Error("ContainsAll gave wrong result for sets with NaNs")
+	}
+	if !s.ContainsAny(onlyNaN) || onlyX.ContainsAny(onlyNaN) {
+		t.Error("ContainsAny gave wrong result for sets with NaNs")
+	}
+	if c := s.Clone(); !c.Equal(s) {
+		t.Errorf("Clone of %v gave %v", s, c)
+	}
+	if got := Union(onlyX, onlyNaN); !got.Equal(s) {
+		t.Errorf("Union(%v, %v) = %v; want %v", onlyX, onlyNaN, got, s)
+	}
+	if got := Union(s, onlyNaN); got.Len() != 2 {
+		t.Errorf("Union(%v, %v) = %v; want %v", s, onlyNaN, got, s)
+	}
+	if got := Intersection(s, onlyNaN); !got.Equal(onlyNaN) {
+		t.Errorf("Intersection(%v, %v) = %v; want %v", s, onlyNaN, got, onlyNaN)
+	}
+	if got := Intersection(onlyX, onlyNaN); got.Len() != 0 {
+		t.Errorf("Intersection(%v, %v) = %v; want empty", onlyX, onlyNaN, got)
+	}
+	if got := Difference(s, onlyNaN); !got.Equal(onlyX) {
+		t.Errorf("Difference(%v, %v) = %v; want %v", s, onlyNaN, got, onlyX)
+	}
+	if got := Difference(s, onlyX); !got.Equal(onlyNaN) {
+		t.Errorf("Difference(%v, %v) = %v; want %v", s, onlyX, got, onlyNaN)
+	}
+
+	if got := SymmetricDifference(s, onlyNaN); !got.Equal(onlyX) {
+		t.Errorf("SymmetricDifference(%v, %v) = %v; want %v", s, onlyNaN, got, onlyX)
+	}
+	if got := SymmetricDifference(onlyX, onlyNaN); !got.Equal(s) {
+		t.Errorf("SymmetricDifference(%v, %v) = %v; want %v", onlyX, onlyNaN, got, s)
+	}
+	if got := IntersectAll(s, Of(nan), s); !got.Equal(onlyNaN) {
+		t.Errorf("IntersectAll(%v, %v, %v) = %v; want %v", s, onlyNaN, s, got, onlyNaN)
+	}
+	if !onlyX.IsProperSubset(s) || !onlyNaN.IsSubset(s) || !onlyX.IsDisjoint(onlyNaN) {
+		t.Error("subset predicates gave wrong result for sets with NaNs")
+	}
+
+	c := s.Clone()
+	c.XorSet(onlyNaN)
+	if !c.Equal(onlyX) {
+		t.Errorf("after XorSet(NaN), got %v; want %v", c, onlyX)
+	}
+	c.XorSet(onlyNaN)
+	if !c.Equal(s) {
+		t.Errorf("after second XorSet(NaN), got %v; want %v", c, s)
+	}
+	c.RetainSet(onlyNaN)
+	if !c.Equal(onlyNaN) {
+		t.Errorf("after RetainSet(NaN), got %v; want %v", c, onlyNaN)
+	}
+	c = s.Clone()
+	c.RemoveSet(onlyNaN)
+	if !c.Equal(onlyX) {
+		t.Errorf("after RemoveSet(NaN), got %v; want %v", c, onlyX)
+	}
+	c = s.Clone()
+	c.RemoveIf(func(v E) bool { return v != v })
+	if !c.Equal(onlyX) {
+		t.Errorf("after RemoveIf(isNaN), got %v; want %v", c, onlyX)
+	}
+	s.Remove(nan)
+	if s.Contains(nan) || s.Len() != 1 {
+		t.Errorf("after Remove(NaN), got %v; want %v", s, onlyX)
+	}
+	s.Add(nan)
+	s.Clear()
+	if s.Len() != 0 {
+		t.Errorf("after Clear, got %v; want empty set", s)
+	}
+
+	// A copy of a Set shares its NaN, as it shares its other elements.
+	var a Set[E]
+	a.Add(x)
+	b := a
+	b.Add(nan)
+	if !a.Contains(nan) || a.Len() != 2 {
+		t.Errorf("after adding NaN to a copy, got %v; want %v", &a, &b)
+	}
+	b.Remove(nan)
+	if a.Contains(nan) || a.Len() != 1 {
+		t.Errorf("after removing NaN from a copy, got %v; want %v", &a, &b)
+	}
+	b.Add(nan)
+	a.Clear()
+	if b.Len() != 0 {
+		t.Errorf("after clearing the original, copy is %v; want empty set", &b)
+	}
+}
+
 func (s *Set[E]) debug() string {
 	var v E
 	typeName := fmt.Sprintf("%T", v)
@@ -505,7 +871,7 @@ func equal[E comparable](s1, s2 *Set[E]) bool {
 // clone is like Clone but preserves "empty" (non-nil, length-0 map) sets.
 func clone[E comparable](s *Set[E]) *Set[E] {
 	if s.m != nil && len(s.m) == 0 {
-		return &Set[E]{m: make(map[E]struct{})}
+		return emptyOf[E]()
 	}
 	return s.Clone()
 }
@@ -0,0 +1,545 @@
+package sortedset
+
+import "slices"
+
+// The sets in this package are B-trees of minimum degree degree: every node
+// other than the root holds between minItems and maxItems items, and every
+// internal node with k items has k+1 children.
+const (
+	degree   = 16
+	minItems = degree - 1
+	maxItems = 2*degree - 1
+)
+
+type node[E any] struct {
+	items    []E
+	children []*node[E] // nil for leaves
+}
+
+func (nd *node[E]) leaf() bool {
+	return nd.children == nil
+}
+
+// A tree is a B-tree ordered by a comparison function, which is passed to
+// each method that needs it.
+type tree[E any] struct {
+	root *node[E]
+	n    int
+	// mod is incremented by every operation that may restructure the tree,
+	// so that iterators can tell whether yield modified it.
+	mod uint64
+}
+
+type cmpFunc[E any] = func(a, b E) int
+
+func (t *tree[E]) contains(v E, cmp cmpFunc[E]) bool {
+	for nd := t.root; nd != nil; {
+		i, found := slices.BinarySearchFunc(nd.items, v, cmp)
+		if found {
+			return true
+		}
+		if nd.leaf() {
+			break
+		}
+		nd = nd.children[i]
+	}
+	return false
+}
+
+// insert adds v to t if it is not already present.
+func (t *tree[E]) insert(v E, cmp cmpFunc[E]) {
+	t.mod++
+	if t.root == nil {
+		t.root = &node[E]{items: []E{v}}
+		t.n++
+		return
+	}
+	// Split full nodes on the way down so that there is always room to
+	// add an item to the parent of a node that is split.
+	if len(t.root.items) == maxItems {
+		t.root = &node[E]{children: []*node[E]{t.root}}
+		t.root.splitChild(0)
+	}
+	nd := t.root
+	for {
+		i, found := slices.BinarySearchFunc(nd.items, v, cmp)
+		if found {
+			return
+		}
+		if nd.leaf() {
+			nd.items = slices.Insert(nd.items, i, v)
+			t.n++
+			return
+		}
+		if len(nd.children[i].items) == maxItems {
+			nd.splitChild(i)
+			switch c := cmp(v, nd.items[i]); {
+			case c == 0:
+				return
+			case c > 0:
+				i++
+			}
+		}
+		nd = nd.children[i]
+	}
+}
+
+// splitChild splits the full child i of nd in two around its middle item,
+// which moves up into nd.
+func (nd *node[E]) splitChild(i int) {
+	c := nd.children[i]
+	mid := c.items[minItems]
+	right := &node[E]{items: slices.Clone(c.items[minItems+1:])}
+	if !c.leaf() {
+		right.children = slices.Clone(c.children[minItems+1:])
+		c.children = slices.Delete(c.children, minItems+1, len(c.children))
+	}
+	c.items = slices.Delete(c.items, minItems, len(c.items))
+	nd.items = slices.Insert(nd.items, i, mid)
+	nd.children = slices.Insert(nd.children, i+1, right)
+}
+
+// delete removes v from t if it is present.
+func (t *tree[E]) delete(v E, cmp cmpFunc[E]) {
+	if t.root == nil {
+		return
+	}
+	t.mod++
+	if t.root.remove(v, cmp) {
+		t.n--
+	}
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+}
+
+// remove removes v from the subtree rooted at nd and reports whether it
+// was present. Before descending into a child, remove makes sure that the
+// child has more than minItems items, so that an item can be removed from
+// it without further rebalancing.
+func (nd *node[E]) remove(v E, cmp cmpFunc[E]) bool {
+	for {
+		i, found := slices.BinarySearchFunc(nd.items, v, cmp)
+		if nd.leaf() {
+			if found {
+				nd.items = slices.Delete(nd.items, i, i+1)
+			}
+			return found
+		}
+		if found {
+			// Replace v by its predecessor or successor, and remove that
+			// from the child it came from instead. If neither child can
+			// spare an item, merge them and remove v from the result.
+			switch {
+			case len(nd.children[i].items) > minItems:
+				v = nd.children[i].max()
+				nd.items[i] = v
+			case len(nd.children[i+1].items) > minItems:
+				v = nd.children[i+1].min()
+				nd.items[i] = v
+				i++
+			default:
+				nd.merge(i)
+			}
+			nd = nd.children[i]
+			continue
+		}
+		if len(nd.children[i].items) <= minItems {
+			i = nd.fill(i)
+		}
+		nd = nd.children[i]
+	}
+}
+
+// fill gives child i of nd an extra item, by taking one from a sibling or
+// by merging it with a sibling. It returns the new index of the child.
+func (nd *node[E]) fill(i int) int {
+	switch {
+	case i > 0 && len(nd.children[i-1].items) > minItems:
+		// Rotate an item from the left sibling through nd.
+		left, c := nd.children[i-1], nd.children[i]
+		c.items = slices.Insert(c.items, 0, nd.items[i-1])
+		nd.items[i-1] = left.items[len(left.items)-1]
+		left.items = slices.Delete(left.items, len(left.items)-1, len(left.items))
+		if !c.leaf() {
+			c.children = slices.Insert(c.children, 0, left.children[len(left.children)-1])
+			left.children = slices.Delete(left.children, len(left.children)-1, len(left.children))
+		}
+	case i < len(nd.items) && len(nd.children[i+1].items) > minItems:
+		// Rotate an item from the right sibling through nd.
+		c, right := nd.children[i], nd.children[i+1]
+		c.items = append(c.items, nd.items[i])
+		nd.items[i] = right.items[0]
+		right.items = slices.Delete(right.items, 0, 1)
+		if !c.leaf() {
+			c.children = append(c.children, right.children[0])
+			right.children = slices.Delete(right.children, 0, 1)
+		}
+	case i < len(nd.items):
+		nd.merge(i)
+	default:
+		nd.merge(i - 1)
+		i--
+	}
+	return i
+}
+
+// merge merges child i+1 of nd and the item between it and child i
+// into child i.
+func (nd *node[E]) merge(i int) {
+	c, right := nd.children[i], nd.children[i+1]
+	c.items = append(c.items, nd.items[i])
+	c.items = append(c.items, right.items...)
+	c.children = append(c.children, right.children...)
+	nd.items = slices.Delete(nd.items, i, i+1)
+	nd.children = slices.Delete(nd.children, i+1, i+2)
+}
+
+func (nd *node[E]) min() E {
+	for !nd.leaf() {
+		nd = nd.children[0]
+	}
+	return nd.items[0]
+}
+
+func (nd *node[E]) max() E {
+	for !nd.leaf() {
+		nd = nd.children[len(nd.children)-1]
+	}
+	return nd.items[len(nd.items)-1]
+}
+
+// floor returns the greatest element of t that is less than or equal to v.
+func (t *tree[E]) floor(v E, cmp cmpFunc[E]) (e E, ok bool) {
+	for nd := t.root; nd != nil; {
+		i, found := slices.BinarySearchFunc(nd.items, v, cmp)
+		if found {
+			return nd.items[i], true
+		}
+		if i > 0 {
+			e, ok = nd.items[i-1], true
+		}
+		if nd.leaf() {
+			break
+		}
+		nd = nd.children[i]
+	}
+	return e, ok
+}
+
+// ceiling returns the least element of t that is greater than or equal to v.
+func (t *tree[E]) ceiling(v E, cmp cmpFunc[E]) (e E, ok bool) {
+	for nd := t.root; nd != nil; {
+		i, found := slices.BinarySearchFunc(nd.items, v, cmp)
+		if found {
+			return nd.items[i], true
+		}
+		if i < len(nd.items) {
+			e, ok = nd.items[i], true
+		}
+		if nd.leaf() {
+			break
+		}
+		nd = nd.children[i]
+	}
+	return e, ok
+}
+
+// A bound is one end of a range of elements.
+type bound[E any] struct {
+	v  E
+	ok bool // if false, the range is unbounded at this end
+}
+
+// ascend calls yield for each element v of t with lo <= v < hi, in
+// ascending order, until yield returns false.
+//
+// If yield modifies t, ascend finds its place again by searching for the
+// element that it last produced, so it produces each element at most once
+// and produces any elements greater than that one that are present when
+// they are reached.
+func (t *tree[E]) ascend(lo, hi bound[E], cmp cmpFunc[E], yield func(E) bool) {
+	var c cursor[E]
+	if lo.ok {
+		c.seekAsc(t.root, lo.v, false, cmp)
+	} else {
+		c.first(t.root)
+	}
+	mod := t.mod
+	for {
+		v, ok := c.next()
+		if !ok || hi.ok && cmp(v, hi.v) >= 0 || !yield(v) {
+			return
+		}
+		if t.mod != mod {
+			mod = t.mod
+			c.seekAsc(t.root, v, true, cmp)
+		}
+	}
+}
+
+// descend is like ascend but produces the elements in descending order.
+func (t *tree[E]) descend(lo, hi bound[E], cmp cmpFunc[E], yield func(E) bool) {
+	var c cursor[E]
+	if hi.ok {
+		c.seekDesc(t.root, hi.v, cmp)
+	} else {
+		c.last(t.root)
+	}
+	mod := t.mod
+	for {
+		v, ok := c.prev()
+		if !ok || lo.ok && cmp(v, lo.v) < 0 || !yield(v) {
+			return
+		}
+		if t.mod != mod {
+			mod = t.mod
+			c.seekDesc(t.root, v, cmp)
+		}
+	}
+}
+
+// A cursor is a position between two elements of a tree, represented by
+// the path from the root to a leaf. Each frame on the path holds a node
+// and an index i: in a leaf, the cursor is just before items[i]; in an
+// internal node, it is within children[i].
+type cursor[E any] struct {
+	stack []frame[E]
+}
+
+type frame[E any] struct {
+	nd *node[E]
+	i  int
+}
+
+// first extends c's path to be just before the first element of the
+// subtree rooted at nd.
+func (c *cursor[E]) first(nd *node[E]) {
+	for ; nd != nil; nd = nd.children[0] {
+		c.stack = append(c.stack, frame[E]{nd, 0})
+		if nd.leaf() {
+			break
+		}
+	}
+}
+
+// last extends c's path to be just after the last element of the
+// subtree rooted at nd.
+func (c *cursor[E]) last(nd *node[E]) {
+	for ; nd != nil; nd = nd.children[len(nd.items)] {
+		c.stack = append(c.stack, frame[E]{nd, len(nd.items)})
+		if nd.leaf() {
+			break
+		}
+	}
+}
+
+// seekAsc positions c before the first element of the tree rooted at nd
+// that is greater than or equal to v (or strictly greater, if strict).
+func (c *cursor[E]) seekAsc(nd *node[E], v E, strict bool, cmp cmpFunc[E]) {
+	c.stack = c.stack[:0]
+	for nd != nil {
+		i, found := slices.BinarySearchFunc(nd.items, v, cmp)
+		if found {
+			if !strict {
+				// Stop here: the cursor is just before items[i].
+				c.stack = append(c.stack, frame[E]{nd, i})
+				return
+			}
+			i++
+		}
+		c.stack = append(c.stack, frame[E]{nd, i})
+		if nd.leaf() {
+			return
+		}
+		nd = nd.children[i]
+	}
+}
+
+// seekDesc positions c after the last element of the tree rooted at nd
+// that is less than v.
+func (c *cursor[E]) seekDesc(nd *node[E], v E, cmp cmpFunc[E]) {
+	c.stack = c.stack[:0]
+	for nd != nil {
+		i, _ := slices.BinarySearchFunc(nd.items, v, cmp)
+		c.stack = append(c.stack, frame[E]{nd, i})
+		if nd.leaf() {
+			return
+		}
+		nd = nd.children[i]
+	}
+}
+
+// next returns the element after c and moves c past it.
+func (c *cursor[E]) next() (v E, ok bool) {
+	for len(c.stack) > 0 {
+		f := &c.stack[len(c.stack)-1]
+		if f.i == len(f.nd.items) {
+			c.stack = c.stack[:len(c.stack)-1]
+			continue
+		}
+		v = f.nd.items[f.i]
+		f.i++
+		if !f.nd.leaf() {
+			c.first(f.nd.children[f.i])
+		}
+		return v, true
+	}
+	return v, false
+}
+
+// prev returns the element before c and moves c before it.
+func (c *cursor[E]) prev() (v E, ok bool) {
+	for len(c.stack) > 0 {
+		f := &c.stack[len(c.stack)-1]
+		if f.i == 0 {
+			c.stack = c.stack[:len(c.stack)-1]
+			continue
+		}
+		f.i--
+		v = f.nd.items[f.i]
+		if !f.nd.leaf() {
+			c.last(f.nd.children[f.i])
+		}
+		return v, true
+	}
+	return v, false
+}
+
+// items returns the elements of t in order.
+func (t *tree[E]) items() []E {
+	return t.root.appendItems(make([]E, 0, t.n))
+}
+
+func (nd *node[E]) appendItems(s []E) []E {
+	if nd == nil {
+		return s
+	}
+	if nd.leaf() {
+		return append(s, nd.items...)
+	}
+	for i, v := range nd.items {
+		s = nd.children[i].appendItems(s)
+		s = append(s, v)
+	}
+	return nd.children[len(nd.items)].appendItems(s)
+}
+
+// build returns a tree holding items, which must be sorted and distinct,
+// in time proportional to len(items).
+func build[E any](items []E) tree[E] {
+	if len(items) == 0 {
+		return tree[E]{}
+	}
+	// Divide the items evenly among as few leaves as possible,
+	// separated by single items that are added to the level above.
+	var nodes []*node[E]
+	var seps []E
+	g := ceilDiv(len(items)+1, maxItems+1)
+	total := len(items) - (g - 1)
+	for j, i := 0, 0; j < g; j++ {
+		n := total / g
+		if j < total%g {
+			n++
+		}
+		nodes = append(nodes, &node[E]{items: slices.Clone(items[i : i+n])})
+		i += n
+		if j < g-1 {
+			seps = append(seps, items[i])
+			i++
+		}
+	}
+	// Likewise divide each level's nodes evenly among as few parents as
+	// possible until there is only one.
+	for len(nodes) > 1 {
+		k := len(nodes)
+		p := ceilDiv(k, maxItems+1)
+		var parents []*node[E]
+		var up []E
+		for j, ni, si := 0, 0, 0; j < p; j++ {
+			n := k / p
+			if j < k%p {
+				n++
+			}
+			parents = append(parents, &node[E]{
+				items:    slices.Clone(seps[si : si+n-1]),
+				children: slices.Clone(nodes[ni : ni+n]),
+			})
+			ni += n
+			si += n - 1
+			if j < p-1 {
+				up = append(up, seps[si])
+				si++
+			}
+		}
+		nodes, seps = parents, up
+	}
+	return tree[E]{root: nodes[0], n: len(items)}
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+func (t *tree[E]) clone() tree[E] {
+	return tree[E]{root: t.root.clone(), n: t.n}
+}
+
+func (nd *node[E]) clone() *node[E] {
+	if nd == nil {
+		return nil
+	}
+	nd1 := &node[E]{items: slices.Clone(nd.items)}
+	if !nd.leaf() {
+		nd1.children = make([]*node[E], len(nd.children))
+		for i, c := range nd.children {
+			nd1.children[i] = c.clone()
+		}
+	}
+	return nd1
+}
+
+// mergeItems merges the sorted, distinct elements of a and b, keeping those
+// only in a if onlyA is set, those only in b if onlyB is set, and those in
+// both if both is set.
+func mergeItems[E any](a, b []E, cmp cmpFunc[E], onlyA, onlyB, both bool) []E {
+	var out []E
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch c := cmp(a[i], b[j]); {
+		case c < 0:
+			if onlyA {
+				out = append(out, a[i])
+			}
+			i++
+		case c > 0:
+			if onlyB {
+				out = append(out, b[j])
+			}
+			j++
+		default:
+			if both {
+				out = append(out, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	if onlyA {
+		out = append(out, a[i:]...)
+	}
+	if onlyB {
+		out = append(out, b[j:]...)
+	}
+	return out
+}
+
+// equalItems reports whether the sorted elements a and b are equal
+// according to cmp.
+func equalItems[E any](a, b []E, cmp cmpFunc[E]) bool {
+	return slices.EqualFunc(a, b, func(x, y E) bool { return cmp(x, y) == 0 })
+}
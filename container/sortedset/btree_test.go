@@ -0,0 +1,184 @@
+package sortedset
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestTreeRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	var tr tree[int]
+	var model []int // sorted
+	for i := range 20000 {
+		// Grow for a while, then shrink, so that both splits and
+		// merges happen at several levels.
+		v := r.IntN(5000)
+		insert := r.IntN(10) < 6
+		if i > 12000 {
+			insert = r.IntN(10) < 3
+		}
+		j, found := slices.BinarySearch(model, v)
+		if insert {
+			tr.insert(v, cmp.Compare[int])
+			if !found {
+				model = slices.Insert(model, j, v)
+			}
+		} else {
+			tr.delete(v, cmp.Compare[int])
+			if found {
+				model = slices.Delete(model, j, j+1)
+			}
+		}
+		if i%500 == 0 {
+			checkTree(t, &tr, model)
+		}
+	}
+	checkTree(t, &tr, model)
+	for len(model) > 0 {
+		j := r.IntN(len(model))
+		tr.delete(model[j], cmp.Compare[int])
+		model = slices.Delete(model, j, j+1)
+	}
+	checkTree(t, &tr, model)
+	if tr.root != nil {
+		t.Fatal("empty tree has a root")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	for _, n := range []int{0, 1, maxItems, maxItems + 1, 2*maxItems + 1, 1000, 50000} {
+		items := make([]int, n)
+		for i := range items {
+			items[i] = 2 * i
+		}
+		tr := build(items)
+		checkTree(t, &tr, items)
+		// The tree must remain valid as it changes.
+		for i := range n {
+			tr.insert(2*i+1, cmp.Compare[int])
+		}
+		for i := 0; i < 2*n; i += 3 {
+			tr.delete(i, cmp.Compare[int])
+		}
+		var want []int
+		for i := range 2 * n {
+			if i%3 != 0 {
+				want = append(want, i)
+			}
+		}
+		checkTree(t, &tr, want)
+	}
+}
+
+func TestTreeSearch(t *testing.T) {
+	var items []int
+	for i := range 3000 {
+		items = append(items, 10*i)
+	}
+	tr := build(items)
+	for v := -5; v < 30010; v += 5 {
+		j, found := slices.BinarySearch(items, v)
+		if got := tr.contains(v, cmp.Compare[int]); got != found {
+			t.Fatalf("contains(%d): got %t", v, got)
+		}
+		fj := j - 1
+		if found {
+			fj = j
+		}
+		checkFound(t, "floor", v, items, fj)(tr.floor(v, cmp.Compare[int]))
+		checkFound(t, "ceiling", v, items, j)(tr.ceiling(v, cmp.Compare[int]))
+
+		var got []int
+		tr.ascend(bound[int]{v, true}, bound[int]{v + 200, true}, cmp.Compare[int], func(e int) bool {
+			got = append(got, e)
+			return true
+		})
+		k, _ := slices.BinarySearch(items, v+200)
+		if want := items[j:k]; !slices.Equal(got, want) {
+			t.Fatalf("ascend [%d, %d): got %v; want %v", v, v+200, got, want)
+		}
+		got = got[:0]
+		tr.descend(bound[int]{v, true}, bound[int]{v + 200, true}, cmp.Compare[int], func(e int) bool {
+			got = append(got, e)
+			return true
+		})
+		want := slices.Clone(items[j:k])
+		slices.Reverse(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("descend [%d, %d): got %v; want %v", v, v+200, got, want)
+		}
+	}
+}
+
+func checkFound(t *testing.T, name string, v int, items []int, j int) func(int, bool) {
+	return func(got int, ok bool) {
+		t.Helper()
+		if j < 0 || j >= len(items) {
+			if ok {
+				t.Fatalf("%s(%d): got %d, true; want false", name, v, got)
+			}
+			return
+		}
+		if !ok || got != items[j] {
+			t.Fatalf("%s(%d): got %d, %t; want %d, true", name, v, got, ok, items[j])
+		}
+	}
+}
+
+// checkTree checks that tr holds exactly the elements of want, in order,
+// and that it satisfies the B-tree invariants.
+func checkTree(t *testing.T, tr *tree[int], want []int) {
+	t.Helper()
+	if got := tr.items(); !slices.Equal(got, want) {
+		t.Fatalf("tree has %d items; want %d", len(got), len(want))
+	}
+	if tr.n != len(want) {
+		t.Fatalf("tree has n = %d; want %d", tr.n, len(want))
+	}
+	var got []int
+	tr.ascend(bound[int]{}, bound[int]{}, cmp.Compare[int], func(v int) bool {
+		got = append(got, v)
+		return true
+	})
+	if !slices.Equal(got, want) {
+		t.Fatalf("ascend produced %d items; want %d", len(got), len(want))
+	}
+	got = got[:0]
+	tr.descend(bound[int]{}, bound[int]{}, cmp.Compare[int], func(v int) bool {
+		got = append(got, v)
+		return true
+	})
+	slices.Reverse(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("descend produced %d items; want %d", len(got), len(want))
+	}
+	leafDepth := -1
+	var walk func(nd *node[int], depth int)
+	walk = func(nd *node[int], depth int) {
+		if len(nd.items) > maxItems || nd != tr.root && len(nd.items) < minItems {
+			t.Fatalf("node at depth %d has %d items", depth, len(nd.items))
+		}
+		if nd.leaf() {
+			if leafDepth < 0 {
+				leafDepth = depth
+			} else if depth != leafDepth {
+				t.Fatalf("leaves at depths %d and %d", leafDepth, depth)
+			}
+			return
+		}
+		if len(nd.children) != len(nd.items)+1 {
+			t.Fatalf("node at depth %d has %d items and %d children", depth, len(nd.items), len(nd.children))
+		}
+		for _, c := range nd.children {
+			walk(c, depth+1)
+		}
+	}
+	if tr.root != nil {
+		if len(tr.root.items) == 0 {
+			t.Fatal("root has no items")
+		}
+		walk(tr.root, 0)
+	}
+}
@@ -0,0 +1,197 @@
+package sortedset
+
+import "iter"
+
+// A FuncSet is like a Set but orders its elements using a comparison
+// function, so that its elements may be of any type.
+//
+// Unlike a Set, the zero value of a FuncSet is not ready to use:
+// create FuncSets with NewFunc or set Compare before first use.
+type FuncSet[E any] struct {
+	t tree[E]
+	// Compare is the comparison function given to NewFunc.
+	// It returns a negative number when a < b, a positive number when
+	// a > b, and zero when a and b are the same element; it must be a
+	// strict weak ordering, as for slices.SortFunc.
+	// If a FuncSet is created without calling NewFunc,
+	// Compare must be set before the set is used.
+	// Compare should not be changed after the set has been used.
+	Compare func(a, b E) int
+}
+
+// NewFunc returns a new, empty set ordered by cmp.
+func NewFunc[E any](cmp func(a, b E) int) *FuncSet[E] {
+	return &FuncSet[E]{Compare: cmp}
+}
+
+// String returns a human-readable representation of the set,
+// listing the elements in ascending order.
+func (s *FuncSet[E]) String() string {
+	return formatSet(s.All())
+}
+
+// Add adds elements to a set.
+func (s *FuncSet[E]) Add(v ...E) {
+	for _, vv := range v {
+		s.t.insert(vv, s.Compare)
+	}
+}
+
+// AddSet adds the elements of set s2 to s.
+func (s *FuncSet[E]) AddSet(s2 *FuncSet[E]) {
+	for _, v := range s2.t.items() {
+		s.t.insert(v, s.Compare)
+	}
+}
+
+// Remove removes elements from a set.
+// Elements that are not present are ignored.
+func (s *FuncSet[E]) Remove(v ...E) {
+	for _, vv := range v {
+		s.t.delete(vv, s.Compare)
+	}
+}
+
+// RemoveSet removes the elements of set s2 from s.
+// Elements present in s2 but not s are ignored.
+func (s *FuncSet[E]) RemoveSet(s2 *FuncSet[E]) {
+	for _, v := range s2.t.items() {
+		s.t.delete(v, s.Compare)
+	}
+}
+
+// Contains reports whether v is in the set.
+func (s *FuncSet[E]) Contains(v E) bool {
+	return s.t.contains(v, s.Compare)
+}
+
+// Equal reports whether s and s2 contain the same elements,
+// according to s.Compare.
+func (s *FuncSet[E]) Equal(s2 *FuncSet[E]) bool {
+	return s.t.n == s2.t.n && equalItems(s.t.items(), s2.t.items(), s.Compare)
+}
+
+// Clear removes all elements from s, leaving it empty.
+func (s *FuncSet[E]) Clear() {
+	s.t = tree[E]{mod: s.t.mod + 1}
+}
+
+// Clone returns a copy of s with the same comparison function.
+// The elements are copied using assignment,
+// so this is a shallow clone.
+func (s *FuncSet[E]) Clone() *FuncSet[E] {
+	return &FuncSet[E]{t: s.t.clone(), Compare: s.Compare}
+}
+
+// RemoveIf deletes any elements from s for which remove returns true.
+func (s *FuncSet[E]) RemoveIf(remove func(E) bool) {
+	for v := range s.All() {
+		if remove(v) {
+			s.t.delete(v, s.Compare)
+		}
+	}
+}
+
+// Len returns the number of elements in s.
+func (s *FuncSet[E]) Len() int {
+	return s.t.n
+}
+
+// Min returns the smallest element of s.
+// If s is empty, it returns the zero value and false.
+func (s *FuncSet[E]) Min() (E, bool) {
+	if s.t.root == nil {
+		var zero E
+		return zero, false
+	}
+	return s.t.root.min(), true
+}
+
+// Max returns the largest element of s.
+// If s is empty, it returns the zero value and false.
+func (s *FuncSet[E]) Max() (E, bool) {
+	if s.t.root == nil {
+		var zero E
+		return zero, false
+	}
+	return s.t.root.max(), true
+}
+
+// Floor returns the largest element of s that is less than or equal to v.
+// If there is no such element, it returns the zero value and false.
+func (s *FuncSet[E]) Floor(v E) (E, bool) {
+	return s.t.floor(v, s.Compare)
+}
+
+// Ceiling returns the smallest element of s that is greater than or equal
+// to v. If there is no such element, it returns the zero value and false.
+func (s *FuncSet[E]) Ceiling(v E) (E, bool) {
+	return s.t.ceiling(v, s.Compare)
+}
+
+// All returns an iterator over the elements in the set in ascending order.
+// Its behavior under modification is the same as that of Set.All.
+func (s *FuncSet[E]) All() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.ascend(bound[E]{}, bound[E]{}, s.Compare, yield)
+	}
+}
+
+// Backward returns an iterator over the elements in the set in descending
+// order. Its behavior under modification mirrors that of All.
+func (s *FuncSet[E]) Backward() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.descend(bound[E]{}, bound[E]{}, s.Compare, yield)
+	}
+}
+
+// Range returns an iterator over the elements v of the set with
+// lo <= v < hi, in ascending order.
+// Its behavior under modification is the same as that of All.
+func (s *FuncSet[E]) Range(lo, hi E) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.ascend(bound[E]{lo, true}, bound[E]{hi, true}, s.Compare, yield)
+	}
+}
+
+// RangeBackward returns an iterator over the elements v of the set with
+// lo <= v < hi, in descending order.
+// Its behavior under modification mirrors that of All.
+func (s *FuncSet[E]) RangeBackward(lo, hi E) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.descend(bound[E]{lo, true}, bound[E]{hi, true}, s.Compare, yield)
+	}
+}
+
+// UnionFunc constructs a new set containing the union of s1 and s2,
+// which must be ordered by equivalent comparison functions.
+// The result uses s1.Compare.
+func UnionFunc[E any](s1, s2 *FuncSet[E]) *FuncSet[E] {
+	return s1.merged(s2, true, true, true)
+}
+
+// IntersectionFunc constructs a new set containing the intersection of
+// s1 and s2, which must be ordered by equivalent comparison functions.
+// The result uses s1.Compare.
+func IntersectionFunc[E any](s1, s2 *FuncSet[E]) *FuncSet[E] {
+	return s1.merged(s2, false, false, true)
+}
+
+// DifferenceFunc constructs a new set containing the elements of s1 that
+// are not present in s2, which must be ordered by equivalent comparison
+// functions. The result uses s1.Compare.
+func DifferenceFunc[E any](s1, s2 *FuncSet[E]) *FuncSet[E] {
+	return s1.merged(s2, true, false, false)
+}
+
+// SymmetricDifferenceFunc constructs a new set containing the elements that
+// are present in exactly one of s1 and s2, which must be ordered by
+// equivalent comparison functions. The result uses s1.Compare.
+func SymmetricDifferenceFunc[E any](s1, s2 *FuncSet[E]) *FuncSet[E] {
+	return s1.merged(s2, true, true, false)
+}
+
+func (s *FuncSet[E]) merged(s2 *FuncSet[E], onlyS, onlyS2, both bool) *FuncSet[E] {
+	items := mergeItems(s.t.items(), s2.t.items(), s.Compare, onlyS, onlyS2, both)
+	return &FuncSet[E]{t: build(items), Compare: s.Compare}
+}
@@ -0,0 +1,107 @@
+package sortedset
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type person struct {
+	name string
+	age  int
+}
+
+func byAge(a, b person) int {
+	return cmp.Compare(a.age, b.age)
+}
+
+func TestFuncSet(t *testing.T) {
+	s := NewFunc(byAge)
+	s.Add(person{"carol", 35}, person{"alice", 30}, person{"bob", 25})
+	// Elements that compare equal are the same element.
+	s.Add(person{"dave", 30})
+	want := []person{{"bob", 25}, {"alice", 30}, {"carol", 35}}
+	if got := slices.Collect(s.All()); !slices.Equal(got, want) {
+		t.Fatalf("All: got %v; want %v", got, want)
+	}
+	if got := s.String(); got != "sortedset[{bob 25} {alice 30} {carol 35}]" {
+		t.Errorf("String: got %q", got)
+	}
+	if !s.Contains(person{age: 35}) {
+		t.Error("Contains(age 35): got false")
+	}
+	if v, ok := s.Floor(person{age: 29}); !ok || v.name != "bob" {
+		t.Errorf("Floor(age 29): got %v, %t", v, ok)
+	}
+	if v, ok := s.Ceiling(person{age: 31}); !ok || v.name != "carol" {
+		t.Errorf("Ceiling(age 31): got %v, %t", v, ok)
+	}
+	if v, ok := s.Min(); !ok || v.name != "bob" {
+		t.Errorf("Min: got %v, %t", v, ok)
+	}
+	if v, ok := s.Max(); !ok || v.name != "carol" {
+		t.Errorf("Max: got %v, %t", v, ok)
+	}
+	got := slices.Collect(s.RangeBackward(person{age: 26}, person{age: 40}))
+	if want := []person{{"carol", 35}, {"alice", 30}}; !slices.Equal(got, want) {
+		t.Errorf("RangeBackward: got %v; want %v", got, want)
+	}
+
+	c := s.Clone()
+	s.Remove(person{age: 30})
+	if s.Len() != 2 || c.Len() != 3 {
+		t.Errorf("after Remove from original: got lengths %d and %d; want 2 and 3", s.Len(), c.Len())
+	}
+	if s.Equal(c) {
+		t.Error("Equal: got true after Remove")
+	}
+	c.RemoveIf(func(p person) bool { return p.age == 30 })
+	if !s.Equal(c) {
+		t.Errorf("Equal: got false for %v and %v", s, c)
+	}
+}
+
+func TestFuncSetZero(t *testing.T) {
+	s := FuncSet[string]{Compare: func(a, b string) int {
+		return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+	}}
+	s.Add("b", "A", "a", "C")
+	if got := slices.Collect(s.All()); !slices.Equal(got, []string{"A", "b", "C"}) {
+		t.Errorf("All: got %v", got)
+	}
+	s.Clear()
+	if s.Len() != 0 {
+		t.Errorf("after Clear: got %v", s.String())
+	}
+}
+
+func TestFuncSetOps(t *testing.T) {
+	s1, s2 := NewFunc(cmp.Compare[int]), NewFunc(cmp.Compare[int])
+	s1.Add(1, 2, 3)
+	s2.Add(2, 3, 4)
+	for _, tt := range []struct {
+		name string
+		s    *FuncSet[int]
+		want []int
+	}{
+		{"UnionFunc", UnionFunc(s1, s2), []int{1, 2, 3, 4}},
+		{"IntersectionFunc", IntersectionFunc(s1, s2), []int{2, 3}},
+		{"DifferenceFunc", DifferenceFunc(s1, s2), []int{1}},
+		{"SymmetricDifferenceFunc", SymmetricDifferenceFunc(s1, s2), []int{1, 4}},
+	} {
+		if got := slices.Collect(tt.s.All()); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v; want %v", tt.name, got, tt.want)
+		}
+		// The result has a comparison function and can be modified.
+		tt.s.Add(0)
+		if v, _ := tt.s.Min(); v != 0 {
+			t.Errorf("%s: after Add(0), Min is %d", tt.name, v)
+		}
+	}
+	s1.AddSet(s2)
+	s1.RemoveSet(s2)
+	if got := slices.Collect(s1.All()); !slices.Equal(got, []int{1}) {
+		t.Errorf("after AddSet and RemoveSet: got %v", got)
+	}
+}
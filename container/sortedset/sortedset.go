@@ -0,0 +1,221 @@
+// Package sortedset defines Set and FuncSet types that hold a set of
+// elements in sorted order.
+package sortedset
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// A Set is a set of ordered elements that iterates over its elements in
+// ascending order. Sets are implemented as B-trees: Add, Remove, Contains,
+// and the searches Floor and Ceiling take time logarithmic in the size of
+// the set, and Union and the other functions that combine two sets take
+// linear time.
+//
+// Elements are ordered as by cmp.Compare. In particular, all floating-point
+// NaNs are treated as a single element that is less than any other value.
+//
+// A copy of a non-empty Set shares its tree nodes but not its count of
+// elements, and modifying either one can restructure the other's tree out
+// from under it. Use Clone to copy a Set.
+// The zero value of a Set is an empty set ready to use.
+// As with maps, concurrent calls to functions and methods that read values
+// are fine; concurrent calls to functions and methods that write values are
+// racy.
+type Set[E cmp.Ordered] struct {
+	t tree[E]
+}
+
+// Of returns a new set containing the listed elements.
+func Of[E cmp.Ordered](v ...E) *Set[E] {
+	s := new(Set[E])
+	s.Add(v...)
+	return s
+}
+
+// String returns a human-readable representation of the set,
+// listing the elements in ascending order.
+func (s *Set[E]) String() string {
+	return formatSet(s.All())
+}
+
+func formatSet[E any](seq iter.Seq[E]) string {
+	var b strings.Builder
+	b.WriteString("sortedset[")
+	sep := ""
+	for v := range seq {
+		fmt.Fprint(&b, sep, v)
+		sep = " "
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// GoString returns a Go syntax representation of the set.
+func (s *Set[E]) GoString() string {
+	var vals []string
+	for v := range s.All() {
+		vals = append(vals, fmt.Sprintf("%#v", v))
+	}
+	var v E
+	return fmt.Sprintf("sortedset.Of[%T](%s)", v, strings.Join(vals, ", "))
+}
+
+// Add adds elements to a set.
+func (s *Set[E]) Add(v ...E) {
+	for _, vv := range v {
+		s.t.insert(vv, cmp.Compare[E])
+	}
+}
+
+// AddSet adds the elements of set s2 to s.
+func (s *Set[E]) AddSet(s2 *Set[E]) {
+	for _, v := range s2.t.items() {
+		s.t.insert(v, cmp.Compare[E])
+	}
+}
+
+// Remove removes elements from a set.
+// Elements that are not present are ignored.
+func (s *Set[E]) Remove(v ...E) {
+	for _, vv := range v {
+		s.t.delete(vv, cmp.Compare[E])
+	}
+}
+
+// RemoveSet removes the elements of set s2 from s.
+// Elements present in s2 but not s are ignored.
+func (s *Set[E]) RemoveSet(s2 *Set[E]) {
+	for _, v := range s2.t.items() {
+		s.t.delete(v, cmp.Compare[E])
+	}
+}
+
+// Contains reports whether v is in the set.
+func (s *Set[E]) Contains(v E) bool {
+	return s.t.contains(v, cmp.Compare[E])
+}
+
+// Equal reports whether s and s2 contain the same elements.
+func (s *Set[E]) Equal(s2 *Set[E]) bool {
+	return s.t.n == s2.t.n && equalItems(s.t.items(), s2.t.items(), cmp.Compare[E])
+}
+
+// Clear removes all elements from s, leaving it empty.
+func (s *Set[E]) Clear() {
+	s.t = tree[E]{mod: s.t.mod + 1}
+}
+
+// Clone returns a copy of s.
+// The elements are copied using assignment,
+// so this is a shallow clone.
+func (s *Set[E]) Clone() *Set[E] {
+	return &Set[E]{t: s.t.clone()}
+}
+
+// RemoveIf deletes any elements from s for which remove returns true.
+func (s *Set[E]) RemoveIf(remove func(E) bool) {
+	for v := range s.All() {
+		if remove(v) {
+			s.t.delete(v, cmp.Compare[E])
+		}
+	}
+}
+
+// Len returns the number of elements in s.
+func (s *Set[E]) Len() int {
+	return s.t.n
+}
+
+// Min returns the smallest element of s.
+// If s is empty, it returns the zero value and false.
+func (s *Set[E]) Min() (E, bool) {
+	if s.t.root == nil {
+		var zero E
+		return zero, false
+	}
+	return s.t.root.min(), true
+}
+
+// Max returns the largest element of s.
+// If s is empty, it returns the zero value and false.
+func (s *Set[E]) Max() (E, bool) {
+	if s.t.root == nil {
+		var zero E
+		return zero, false
+	}
+	return s.t.root.max(), true
+}
+
+// Floor returns the largest element of s that is less than or equal to v.
+// If there is no such element, it returns the zero value and false.
+func (s *Set[E]) Floor(v E) (E, bool) {
+	return s.t.floor(v, cmp.Compare[E])
+}
+
+// Ceiling returns the smallest element of s that is greater than or equal
+// to v. If there is no such element, it returns the zero value and false.
+func (s *Set[E]) Ceiling(v E) (E, bool) {
+	return s.t.ceiling(v, cmp.Compare[E])
+}
+
+// All returns an iterator over the elements in the set in ascending order.
+// If the set is modified during iteration, the iterator produces each
+// element at most once, and elements greater than the most recently
+// produced one are produced if they are present when they are reached.
+func (s *Set[E]) All() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.ascend(bound[E]{}, bound[E]{}, cmp.Compare[E], yield)
+	}
+}
+
+// Backward returns an iterator over the elements in the set in descending
+// order. Its behavior under modification mirrors that of All.
+func (s *Set[E]) Backward() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.descend(bound[E]{}, bound[E]{}, cmp.Compare[E], yield)
+	}
+}
+
+// Range returns an iterator over the elements v of the set with
+// lo <= v < hi, in ascending order.
+// Its behavior under modification is the same as that of All.
+func (s *Set[E]) Range(lo, hi E) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.ascend(bound[E]{lo, true}, bound[E]{hi, true}, cmp.Compare[E], yield)
+	}
+}
+
+// RangeBackward returns an iterator over the elements v of the set with
+// lo <= v < hi, in descending order.
+// Its behavior under modification mirrors that of All.
+func (s *Set[E]) RangeBackward(lo, hi E) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		s.t.descend(bound[E]{lo, true}, bound[E]{hi, true}, cmp.Compare[E], yield)
+	}
+}
+
+// Union constructs a new set containing the union of s1 and s2.
+func Union[E cmp.Ordered](s1, s2 *Set[E]) *Set[E] {
+	return &Set[E]{t: build(mergeItems(s1.t.items(), s2.t.items(), cmp.Compare[E], true, true, true))}
+}
+
+// Intersection constructs a new set containing the intersection of s1 and s2.
+func Intersection[E cmp.Ordered](s1, s2 *Set[E]) *Set[E] {
+	return &Set[E]{t: build(mergeItems(s1.t.items(), s2.t.items(), cmp.Compare[E], false, false, true))}
+}
+
+// Difference constructs a new set containing the elements of s1 that
+// are not present in s2.
+func Difference[E cmp.Ordered](s1, s2 *Set[E]) *Set[E] {
+	return &Set[E]{t: build(mergeItems(s1.t.items(), s2.t.items(), cmp.Compare[E], true, false, false))}
+}
+
+// SymmetricDifference constructs a new set containing the elements that are
+// present in exactly one of s1 and s2.
+func SymmetricDifference[E cmp.Ordered](s1, s2 *Set[E]) *Set[E] {
+	return &Set[E]{t: build(mergeItems(s1.t.items(), s2.t.items(), cmp.Compare[E], true, true, false))}
+}
@@ -0,0 +1,349 @@
+package sortedset
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/cespare/next/container/set"
+)
+
+func TestAddRemove(t *testing.T) {
+	check(t, Of(5, 1, 3, 1, -200), -200, 1, 3, 5)
+	if got, want := fmt.Sprintf("%v %#v", Of("b", "c", "a"), Of("b", "a")), `sortedset[a b c] sortedset.Of[string]("a", "b")`; got != want {
+		t.Errorf("Sprintf: got %q; want %q", got, want)
+	}
+
+	var s Set[int]
+	s.Add()
+	check(t, &s)
+	s.Add(3, 64, 3, 127)
+	check(t, &s, 3, 64, 127)
+	s.Remove(127, -1, 1000)
+	check(t, &s, 3, 64)
+	s.AddSet(Of(1, 3, 100))
+	check(t, &s, 1, 3, 64, 100)
+	s.RemoveSet(Of(3, 100, 200))
+	check(t, &s, 1, 64)
+	s.Remove(1, 64)
+	check(t, &s)
+}
+
+func TestMinMaxFloorCeiling(t *testing.T) {
+	var s Set[int]
+	if v, ok := s.Min(); ok {
+		t.Errorf("Min of empty set: got %d, true", v)
+	}
+	if v, ok := s.Max(); ok {
+		t.Errorf("Max of empty set: got %d, true", v)
+	}
+	if v, ok := s.Floor(3); ok {
+		t.Errorf("Floor(3) of empty set: got %d, true", v)
+	}
+	s.Add(10, 20, 30)
+	for _, tt := range []struct {
+		name   string
+		f      func() (int, bool)
+		want   int
+		wantOK bool
+	}{
+		{"Min", s.Min, 10, true},
+		{"Max", s.Max, 30, true},
+		{"Floor(9)", func() (int, bool) { return s.Floor(9) }, 0, false},
+		{"Floor(10)", func() (int, bool) { return s.Floor(10) }, 10, true},
+		{"Floor(25)", func() (int, bool) { return s.Floor(25) }, 20, true},
+		{"Floor(99)", func() (int, bool) { return s.Floor(99) }, 30, true},
+		{"Ceiling(0)", func() (int, bool) { return s.Ceiling(0) }, 10, true},
+		{"Ceiling(11)", func() (int, bool) { return s.Ceiling(11) }, 20, true},
+		{"Ceiling(30)", func() (int, bool) { return s.Ceiling(30) }, 30, true},
+		{"Ceiling(31)", func() (int, bool) { return s.Ceiling(31) }, 0, false},
+	} {
+		if got, ok := tt.f(); got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: got %d, %t; want %d, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	s := Of(1, 3, 5, 7, 9)
+	for _, tt := range []struct {
+		lo, hi int
+		want   []int
+	}{
+		{0, 10, []int{1, 3, 5, 7, 9}},
+		{3, 7, []int{3, 5}},
+		{2, 8, []int{3, 5, 7}},
+		{3, 4, []int{3}},
+		{4, 5, nil},
+		{5, 5, nil},
+		{7, 3, nil},
+		{10, 20, nil},
+	} {
+		if got := slices.Collect(s.Range(tt.lo, tt.hi)); !slices.Equal(got, tt.want) {
+			t.Errorf("Range(%d, %d): got %v; want %v", tt.lo, tt.hi, got, tt.want)
+		}
+		want := slices.Clone(tt.want)
+		slices.Reverse(want)
+		if got := slices.Collect(s.RangeBackward(tt.lo, tt.hi)); !slices.Equal(got, want) {
+			t.Errorf("RangeBackward(%d, %d): got %v; want %v", tt.lo, tt.hi, got, want)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	s := Of(1, 5, 63, 64, 300)
+	if got := slices.Collect(s.All()); !slices.Equal(got, []int{1, 5, 63, 64, 300}) {
+		t.Errorf("All: got %v", got)
+	}
+	if got := slices.Collect(s.Backward()); !slices.Equal(got, []int{300, 64, 63, 5, 1}) {
+		t.Errorf("Backward: got %v", got)
+	}
+	for v := range s.All() {
+		if v != 1 {
+			t.Fatalf("first element: got %d; want 1", v)
+		}
+		break
+	}
+
+	// Elements added ahead of the iterator are produced;
+	// removed ones are not.
+	var got []int
+	for v := range s.All() {
+		got = append(got, v)
+		switch v {
+		case 1:
+			s.Add(2, 0)
+			s.Remove(63)
+		case 5:
+			s.Remove(300)
+			s.Add(100)
+		case 64:
+			s.Remove(100)
+		}
+	}
+	if !slices.Equal(got, []int{1, 2, 5, 64}) {
+		t.Errorf("All with modification: got %v", got)
+	}
+
+	s = Of(1, 5, 63, 64, 300)
+	got = nil
+	for v := range s.Backward() {
+		got = append(got, v)
+		switch v {
+		case 300:
+			s.Remove(300, 64)
+			s.Add(4)
+		case 5:
+			s.Clear()
+		}
+	}
+	if !slices.Equal(got, []int{300, 63, 5}) {
+		t.Errorf("Backward with modification: got %v", got)
+	}
+
+	// Restructuring the tree while iterating, by removing many elements
+	// and adding many more, still visits each element at most once.
+	s = new(Set[int])
+	for i := range 1000 {
+		s.Add(2 * i)
+	}
+	got = nil
+	for v := range s.Range(0, 1000) {
+		got = append(got, v)
+		if v == 0 {
+			for i := range 1000 {
+				s.Add(2*i + 1)
+			}
+			s.RemoveIf(func(e int) bool { return e > v && e%4 == 0 })
+		}
+	}
+	var want []int
+	for v := 0; v < 1000; v++ {
+		if v == 0 || v%4 == 2 || v%2 == 1 {
+			want = append(want, v)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Range with restructuring: got %d elements; want %d", len(got), len(want))
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	for _, tt := range []struct {
+		s1, s2                       []int
+		union, inter, diff, symmDiff []int
+	}{
+		{nil, nil, nil, nil, nil, nil},
+		{[]int{1, 2}, nil, []int{1, 2}, nil, []int{1, 2}, []int{1, 2}},
+		{nil, []int{1, 2}, []int{1, 2}, nil, nil, []int{1, 2}},
+		{[]int{1, 2, 3}, []int{2, 3, 4}, []int{1, 2, 3, 4}, []int{2, 3}, []int{1}, []int{1, 4}},
+		{[]int{1, 5}, []int{2, 3, 4}, []int{1, 2, 3, 4, 5}, nil, []int{1, 5}, []int{1, 2, 3, 4, 5}},
+	} {
+		s1, s2 := Of(tt.s1...), Of(tt.s2...)
+		check(t, Union(s1, s2), tt.union...)
+		check(t, Intersection(s1, s2), tt.inter...)
+		check(t, Difference(s1, s2), tt.diff...)
+		check(t, SymmetricDifference(s1, s2), tt.symmDiff...)
+		check(t, s1, tt.s1...)
+		check(t, s2, tt.s2...)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !Of(1, 2).Equal(Of(2, 1)) {
+		t.Error("Of(1, 2) != Of(2, 1)")
+	}
+	if Of(1, 2).Equal(Of(1, 3)) {
+		t.Error("Of(1, 2) == Of(1, 3)")
+	}
+	if Of(1, 2).Equal(Of(1)) {
+		t.Error("Of(1, 2) == Of(1)")
+	}
+}
+
+func TestNaN(t *testing.T) {
+	nan := math.NaN()
+	s := Of(1, nan, math.Inf(-1), -nan, 0)
+	if s.Len() != 4 {
+		t.Fatalf("got %s; want 4 elements", s)
+	}
+	if v, _ := s.Min(); !math.IsNaN(v) {
+		t.Errorf("Min: got %v; want NaN", v)
+	}
+	if !s.Contains(nan) {
+		t.Error("Contains(NaN): got false")
+	}
+	if got := slices.Collect(s.Range(math.Inf(-1), 1)); !slices.Equal(got, []float64{math.Inf(-1), 0}) {
+		t.Errorf("Range(-Inf, 1): got %v", got)
+	}
+	s.Remove(nan)
+	if s.Len() != 3 || s.Contains(nan) {
+		t.Errorf("after Remove(NaN): got %s", s)
+	}
+}
+
+func TestClearClone(t *testing.T) {
+	s := Of(1, 2, 3)
+	c := s.Clone()
+	s.Clear()
+	check(t, s)
+	check(t, c, 1, 2, 3)
+	c.Add(4)
+	s.Add(5)
+	check(t, c, 1, 2, 3, 4)
+	check(t, s, 5)
+}
+
+func TestRemoveIf(t *testing.T) {
+	var s Set[int]
+	for i := range 200 {
+		s.Add(i)
+	}
+	s.RemoveIf(func(v int) bool { return v%3 != 0 })
+	var want []int
+	for i := 0; i < 200; i += 3 {
+		want = append(want, i)
+	}
+	check(t, &s, want...)
+}
+
+// TestSetOpsSizes checks that the functions that combine sets, which build
+// their results in bulk, give valid trees at sizes around the node limits,
+// and that the results stay valid as they grow and shrink.
+func TestSetOpsSizes(t *testing.T) {
+	for _, n := range []int{1, minItems, maxItems, maxItems + 1, 2*maxItems + 1, (maxItems + 1) * (maxItems + 1), 5000} {
+		s1, s2 := new(Set[int]), new(Set[int])
+		var union, inter, diff, symmDiff []int
+		// Leave a gap after each element.
+		for v := range 3 * n {
+			in1, in2 := v%3 == 0, v%2 == 0
+			if in1 {
+				s1.Add(2 * v)
+			}
+			if in2 {
+				s2.Add(2 * v)
+			}
+			if in1 || in2 {
+				union = append(union, 2*v)
+			}
+			if in1 && in2 {
+				inter = append(inter, 2*v)
+			}
+			if in1 && !in2 {
+				diff = append(diff, 2*v)
+			}
+			if in1 != in2 {
+				symmDiff = append(symmDiff, 2*v)
+			}
+		}
+		for _, op := range []struct {
+			name    string
+			f       func(s1, s2 *Set[int]) *Set[int]
+			inPlace func(s, s2 *Set[int])
+			want    []int
+		}{
+			{"Union", Union[int], (*Set[int]).AddSet, union},
+			{"Intersection", Intersection[int], nil, inter},
+			{"Difference", Difference[int], (*Set[int]).RemoveSet, diff},
+			{"SymmetricDifference", SymmetricDifference[int], nil, symmDiff},
+		} {
+			t.Run(fmt.Sprintf("%s/n=%d", op.name, n), func(t *testing.T) {
+				s := op.f(s1, s2)
+				check(t, s, op.want...)
+				if op.inPlace != nil {
+					c := s1.Clone()
+					op.inPlace(c, s2)
+					check(t, c, op.want...)
+				}
+				// Insert into every gap, then delete most of the elements,
+				// forcing splits and then merges throughout the built tree.
+				want := slices.Clone(op.want)
+				for _, v := range op.want {
+					s.Add(v + 1)
+					want = append(want, v+1)
+				}
+				slices.Sort(want)
+				check(t, s, want...)
+				s.RemoveIf(func(v int) bool { return v%5 != 0 })
+				want = slices.DeleteFunc(want, func(v int) bool { return v%5 != 0 })
+				check(t, s, want...)
+			})
+		}
+	}
+}
+
+// check checks that s holds exactly want, which must be sorted,
+// and that its tree is a valid B-tree.
+func check(t *testing.T, s *Set[int], want ...int) {
+	t.Helper()
+	checkTree(t, &s.t, want)
+}
+
+func BenchmarkSortedIteration(b *testing.B) {
+	for _, n := range []int{100, 10_000} {
+		var s Set[int]
+		var m set.Set[int]
+		r := rand.New(rand.NewPCG(1, 2))
+		for range n {
+			v := r.Int()
+			s.Add(v)
+			m.Add(v)
+		}
+		b.Run(fmt.Sprintf("n=%d/impl=sortedset", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for range s.All() {
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/impl=map", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for range slices.Sorted(m.All()) {
+				}
+			}
+		})
+	}
+}
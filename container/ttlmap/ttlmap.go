@@ -0,0 +1,253 @@
+// Package ttlmap implements a map whose entries expire after a time-to-live.
+package ttlmap
+
+import (
+	"iter"
+	"time"
+
+	"github.com/cespare/next/container/ordmap"
+)
+
+// A Map is a map from keys to values in which each entry expires once its
+// time-to-live (TTL) has elapsed since it was last set.
+//
+// Expired entries are removed lazily: Get removes the entry it looks up if
+// that entry has expired, and Sweep removes all expired entries. Until then,
+// an expired entry still counts toward Len, but it is never returned by Get
+// or produced by All.
+//
+// The entries are kept ordered by expiry time, so Sweep takes time
+// proportional to the number of expired entries. Entries with the default
+// TTL are kept apart from the others, so setting an entry with the default
+// TTL takes O(1) time (as long as the clock does not go backward); setting an
+// entry with another TTL takes time proportional to the number of entries
+// with non-default TTLs that expire after it.
+//
+// A Map must be created with New.
+// A Map is not safe for concurrent use by multiple goroutines.
+// Note that Get may modify the map (it removes an expired entry).
+type Map[K comparable, V any] struct {
+	// Now optionally reports the current time.
+	// If Now is nil, time.Now is used.
+	// Now should be set before the map is used and not changed after.
+	Now func() time.Time
+	// OnExpire is an optional function that is called whenever an expired
+	// entry is removed from the map by Get or Sweep.
+	// OnExpire is called after the entry has been removed.
+	// OnExpire must not modify the map.
+	OnExpire func(K, V)
+
+	ttl time.Duration
+	// def holds the entries with the default TTL, and custom holds the
+	// others. Each is ordered by deadline and then by seq. A key is in at
+	// most one of them.
+	def    *ordmap.Map[K, entry[V]]
+	custom *ordmap.Map[K, entry[V]]
+	seq    uint64 // number of calls to SetTTL
+}
+
+type entry[V any] struct {
+	v        V
+	deadline time.Time
+	// seq orders entries with equal deadlines by when they were set.
+	seq uint64
+}
+
+func (e *entry[V]) expired(now time.Time) bool {
+	return !now.Before(e.deadline)
+}
+
+// before reports whether e expires before e1.
+func (e *entry[V]) before(e1 *entry[V]) bool {
+	if c := e.deadline.Compare(e1.deadline); c != 0 {
+		return c < 0
+	}
+	return e.seq < e1.seq
+}
+
+// New creates a Map in which entries expire after the given default TTL.
+// New panics if ttl is not positive.
+func New[K comparable, V any](ttl time.Duration) *Map[K, V] {
+	if ttl <= 0 {
+		panic("ttlmap: non-positive TTL")
+	}
+	return &Map[K, V]{
+		ttl:    ttl,
+		def:    new(ordmap.Map[K, entry[V]]),
+		custom: new(ordmap.Map[K, entry[V]]),
+	}
+}
+
+// TTL returns the default TTL the map was created with.
+func (m *Map[K, V]) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *Map[K, V]) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// Get returns the value stored in the map for a key,
+// or the zero value of V if no value is present.
+// The ok result indicates whether an unexpired entry was found.
+// If the entry for key has expired, Get removes it and calls OnExpire.
+func (m *Map[K, V]) Get(key K) (val V, ok bool) {
+	e, om, ok := m.peek(key)
+	if !ok {
+		return val, false
+	}
+	if e.expired(m.now()) {
+		om.Delete(key)
+		m.expired(key, e.v)
+		return val, false
+	}
+	return e.v, true
+}
+
+// Deadline returns the time at which the entry for key expires.
+// The ok result indicates whether an unexpired entry was found.
+// Unlike Get, Deadline does not remove an expired entry.
+func (m *Map[K, V]) Deadline(key K) (deadline time.Time, ok bool) {
+	e, _, ok := m.peek(key)
+	if !ok || e.expired(m.now()) {
+		return deadline, false
+	}
+	return e.deadline, true
+}
+
+// Set sets the value for a key, which expires after the default TTL.
+// If the key was already present, its value and expiry time are replaced
+// (OnExpire is not called for the old value).
+func (m *Map[K, V]) Set(key K, val V) {
+	m.SetTTL(key, val, m.ttl)
+}
+
+// SetTTL is like Set but the entry expires after the given ttl rather than
+// the default TTL. If ttl is not positive, the entry is already expired.
+func (m *Map[K, V]) SetTTL(key K, val V, ttl time.Duration) {
+	m.seq++
+	e := entry[V]{v: val, deadline: m.now().Add(ttl), seq: m.seq}
+	om, other := m.custom, m.def
+	if ttl == m.ttl {
+		om, other = other, om
+	}
+	other.Delete(key)
+	// Find the last entry that expires before the new one, searching from
+	// the back. For the default TTL, it is the last entry.
+	for k, e1 := range om.Backward() {
+		if k != key && e1.before(&e) {
+			om.SetAfter(key, e, k)
+			return
+		}
+	}
+	om.Set(key, e)
+	om.MoveToFront(key)
+}
+
+// Delete deletes the value for a key.
+// The ok result indicates whether the key was present in the map
+// (whether or not its entry had expired).
+// OnExpire is not called.
+func (m *Map[K, V]) Delete(key K) (ok bool) {
+	_, om, ok := m.peek(key)
+	if ok {
+		om.Delete(key)
+	}
+	return ok
+}
+
+// Clear removes all entries from the map without calling OnExpire.
+func (m *Map[K, V]) Clear() {
+	m.def.Clear()
+	m.custom.Clear()
+}
+
+// Len returns the number of entries in the map,
+// including expired entries that have not yet been removed.
+func (m *Map[K, V]) Len() int {
+	return m.def.Len() + m.custom.Len()
+}
+
+// Sweep removes all expired entries from the map, calling OnExpire for each
+// of them in the order they expired. It returns the number of entries removed.
+// Sweep takes time proportional to the number of expired entries.
+func (m *Map[K, V]) Sweep() int {
+	now := m.now()
+	n := 0
+	for {
+		om := m.first()
+		_, e, ok := om.First()
+		if !ok || !e.expired(now) {
+			return n
+		}
+		k, e, _ := om.PopFirst()
+		n++
+		m.expired(k, e.v)
+	}
+}
+
+// All returns an iterator over the unexpired key-value pairs in the map,
+// ordered from soonest to latest expiry.
+// Entries that expire during iteration are still produced;
+// All does not remove expired entries.
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		now := m.now()
+		// Merge the two lists. An entry that is read ahead may be deleted
+		// or set again by the loop body before it is reached, so check that
+		// it is still current before producing it.
+		next1, stop1 := iter.Pull2(m.def.All())
+		defer stop1()
+		next2, stop2 := iter.Pull2(m.custom.All())
+		defer stop2()
+		k1, e1, ok1 := next1()
+		k2, e2, ok2 := next2()
+		for ok1 || ok2 {
+			var k K
+			var e entry[V]
+			if ok1 && (!ok2 || e1.before(&e2)) {
+				k, e = k1, e1
+				k1, e1, ok1 = next1()
+			} else {
+				k, e = k2, e2
+				k2, e2, ok2 = next2()
+			}
+			if cur, _, ok := m.peek(k); !ok || cur.seq != e.seq || e.expired(now) {
+				continue
+			}
+			if !yield(k, e.v) {
+				return
+			}
+		}
+	}
+}
+
+// peek returns the entry for key and the list that holds it.
+func (m *Map[K, V]) peek(key K) (e entry[V], om *ordmap.Map[K, entry[V]], ok bool) {
+	if e, ok := m.def.Peek(key); ok {
+		return e, m.def, true
+	}
+	if e, ok := m.custom.Peek(key); ok {
+		return e, m.custom, true
+	}
+	return e, nil, false
+}
+
+// first returns the list holding the entry that expires first.
+func (m *Map[K, V]) first() *ordmap.Map[K, entry[V]] {
+	_, e1, ok1 := m.def.First()
+	_, e2, ok2 := m.custom.First()
+	if ok2 && (!ok1 || e2.before(&e1)) {
+		return m.custom
+	}
+	return m.def
+}
+
+func (m *Map[K, V]) expired(key K, val V) {
+	if m.OnExpire != nil {
+		m.OnExpire(key, val)
+	}
+}
@@ -0,0 +1,271 @@
+package ttlmap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+type expiry struct {
+	Key string
+	Val int
+}
+
+func newTestMap(ttl time.Duration) (*Map[string, int], *clock, *[]expiry) {
+	c := &clock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var expiries []expiry
+	m := New[string, int](ttl)
+	m.Now = c.now
+	m.OnExpire = func(k string, v int) {
+		expiries = append(expiries, expiry{k, v})
+	}
+	return m, c, &expiries
+}
+
+func TestMap(t *testing.T) {
+	m, c, expiries := newTestMap(10 * time.Second)
+
+	checkGet(t, m, "a", 0, false)
+	m.Set("a", 1)
+	c.advance(time.Second)
+	m.Set("b", 2)
+	c.advance(time.Second)
+	m.Set("c", 3)
+	checkKeys(t, m, "a", "b", "c")
+
+	// Replacing an entry resets its TTL.
+	m.Set("a", 10)
+	checkKeys(t, m, "b", "c", "a")
+	if d, ok := m.Deadline("a"); !ok || !d.Equal(c.t.Add(10*time.Second)) {
+		t.Fatalf(`Deadline("a"): got (%v, %t)`, d, ok)
+	}
+
+	// "b" expires exactly at its deadline.
+	c.advance(9*time.Second - 1)
+	checkGet(t, m, "b", 2, true)
+	c.advance(1)
+	if _, ok := m.Deadline("b"); ok {
+		t.Fatal(`Deadline("b") of expired entry: got ok`)
+	}
+	if got := m.Len(); got != 3 {
+		t.Fatalf("Len with unremoved expired entry: got %d; want 3", got)
+	}
+	checkKeys(t, m, "c", "a")
+	checkGet(t, m, "b", 0, false)
+	if got := m.Len(); got != 2 {
+		t.Fatalf("Len after Get removed expired entry: got %d; want 2", got)
+	}
+
+	if !m.Delete("c") {
+		t.Fatal(`Delete("c"): got false`)
+	}
+	if m.Delete("c") {
+		t.Fatal(`second Delete("c"): got true`)
+	}
+	c.advance(time.Hour)
+	checkGet(t, m, "a", 0, false)
+	if got := m.Len(); got != 0 {
+		t.Fatalf("Len: got %d; want 0", got)
+	}
+
+	want := []expiry{{"b", 2}, {"a", 10}}
+	if diff := cmp.Diff(*expiries, want); diff != "" {
+		t.Fatalf("expiries (-got, +want):\n%s", diff)
+	}
+}
+
+func TestSetTTL(t *testing.T) {
+	m, c, _ := newTestMap(time.Minute)
+	m.Set("a", 1)
+	m.SetTTL("b", 2, 10*time.Second)
+	m.SetTTL("c", 3, 2*time.Minute)
+	m.SetTTL("d", 4, 30*time.Second)
+	m.SetTTL("e", 5, 30*time.Second)
+	m.Set("f", 6)
+	checkKeys(t, m, "b", "d", "e", "a", "f", "c")
+
+	// Shorten and lengthen existing entries.
+	m.SetTTL("c", 30, time.Second)
+	m.SetTTL("b", 20, time.Hour)
+	checkKeys(t, m, "c", "d", "e", "a", "f", "b")
+
+	// A non-positive TTL creates an expired entry.
+	m.SetTTL("g", 7, 0)
+	checkGet(t, m, "g", 0, false)
+
+	c.advance(45 * time.Second)
+	checkKeys(t, m, "a", "f", "b")
+}
+
+func TestModifyDuringAll(t *testing.T) {
+	m, c, _ := newTestMap(time.Minute)
+	m.SetTTL("a", 1, time.Minute+time.Second)
+	m.Set("b", 2)
+	c.advance(time.Second)
+	m.SetTTL("c", 3, time.Minute-time.Second) // same deadline as "b", but set later
+	m.SetTTL("d", 4, time.Hour)
+	m.Set("e", 5)
+	checkKeys(t, m, "b", "c", "a", "e", "d")
+
+	// Entries deleted or set again before iteration reaches them are not
+	// produced, even if they have already been read from their list.
+	var got []string
+	for k := range m.All() {
+		got = append(got, k)
+		if k == "b" {
+			m.Delete("c")
+			m.Delete("a")
+			m.SetTTL("e", 50, time.Hour)
+		}
+	}
+	if want := []string{"b", "d"}; !slices.Equal(got, want) {
+		t.Fatalf("All with modification: got %v; want %v", got, want)
+	}
+	checkKeys(t, m, "b", "d", "e")
+}
+
+func TestSweep(t *testing.T) {
+	m, c, expiries := newTestMap(time.Minute)
+	if got := m.Sweep(); got != 0 {
+		t.Fatalf("Sweep of empty map: got %d", got)
+	}
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		m.Set(k, i)
+		c.advance(time.Second)
+	}
+	m.SetTTL("f", 5, time.Second)
+	if got := m.Sweep(); got != 0 {
+		t.Fatalf("Sweep with no expired entries: got %d", got)
+	}
+	c.advance(56 * time.Second)
+	if got := m.Sweep(); got != 3 {
+		t.Fatalf("Sweep: got %d; want 3", got)
+	}
+	if got := m.Len(); got != 3 {
+		t.Fatalf("Len after Sweep: got %d; want 3", got)
+	}
+	checkKeys(t, m, "c", "d", "e")
+	want := []expiry{{"f", 5}, {"a", 0}, {"b", 1}}
+	if diff := cmp.Diff(*expiries, want); diff != "" {
+		t.Fatalf("expiries (-got, +want):\n%s", diff)
+	}
+
+	m.Clear()
+	if got := m.Len(); got != 0 {
+		t.Fatalf("Len after Clear: got %d", got)
+	}
+	if len(*expiries) != 3 {
+		t.Fatalf("Clear called OnExpire")
+	}
+}
+
+func TestRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	c := &clock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	model := make(map[int]time.Time) // key -> deadline
+	var expired []int
+	m := New[int, int](100 * time.Millisecond)
+	m.Now = c.now
+	m.OnExpire = func(k, _ int) { expired = append(expired, k) }
+	for op := range 10000 {
+		k := r.IntN(200)
+		switch r.IntN(4) {
+		case 0:
+			m.Set(k, k)
+			model[k] = c.t.Add(m.TTL())
+		case 1:
+			ttl := time.Duration(r.IntN(300)) * time.Millisecond
+			m.SetTTL(k, k, ttl)
+			model[k] = c.t.Add(ttl)
+		case 2:
+			expired = expired[:0]
+			n := m.Sweep()
+			for _, k := range expired {
+				if c.t.Before(model[k]) {
+					t.Fatalf("op %d: Sweep removed unexpired key %d", op, k)
+				}
+				delete(model, k)
+			}
+			if n != len(expired) {
+				t.Fatalf("op %d: Sweep returned %d; expired %d entries", op, n, len(expired))
+			}
+			for k, d := range model {
+				if !c.t.Before(d) {
+					t.Fatalf("op %d: Sweep did not remove expired key %d", op, k)
+				}
+			}
+		case 3:
+			c.advance(time.Duration(r.IntN(20)) * time.Millisecond)
+		}
+		var want []int
+		for k, d := range model {
+			if c.t.Before(d) {
+				want = append(want, k)
+			}
+		}
+		slices.SortFunc(want, func(a, b int) int { return model[a].Compare(model[b]) })
+		var got []int
+		var prev time.Time
+		for k := range m.All() {
+			d, ok := m.Deadline(k)
+			if !ok || !d.Equal(model[k]) {
+				t.Fatalf("op %d: Deadline(%d): got (%v, %t); want %v", op, k, d, ok, model[k])
+			}
+			if d.Before(prev) {
+				t.Fatalf("op %d: All produced entries out of deadline order", op)
+			}
+			prev = d
+			got = append(got, k)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("op %d: All produced %d entries; want %d", op, len(got), len(want))
+		}
+		for i := range got {
+			if !model[got[i]].Equal(model[want[i]]) {
+				t.Fatalf("op %d: All produced %v; want %v", op, got, want)
+			}
+		}
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("New(%s) did not panic", ttl)
+				}
+			}()
+			New[string, int](ttl)
+		}()
+	}
+}
+
+func checkGet(t *testing.T, m *Map[string, int], key string, wantVal int, wantOK bool) {
+	t.Helper()
+	v, ok := m.Get(key)
+	if v != wantVal || ok != wantOK {
+		t.Fatalf("Get(%q): got (%d, %t); want (%d, %t)", key, v, ok, wantVal, wantOK)
+	}
+}
+
+func checkKeys(t *testing.T, m *Map[string, int], want ...string) {
+	t.Helper()
+	var got []string
+	for k := range m.All() {
+		got = append(got, k)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("keys (-got, +want):\n%s", diff)
+	}
+}
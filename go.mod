@@ -1,5 +1,8 @@
 module github.com/cespare/next
 
-go 1.23.0
+// Go 1.24 is needed for maphash.Comparable, which ordmap.Persistent uses to
+// hash keys of any comparable type, and maphash.WriteComparable, which
+// hashset.SliceHasher uses to hash elements.
+go 1.24.0
 
 require github.com/google/go-cmp v0.5.9